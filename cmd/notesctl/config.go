@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+const defaultServer = "http://localhost:8080"
+
+var errProfileNotFound = errors.New("profile not found")
+
+// Profile is a server and the credentials to call it with
+type Profile struct {
+	Server string `yaml:"server"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+// Config is the configuration file of notesctl
+type Config struct {
+	// the profile used when none is selected
+	Current  string             `yaml:"current,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+// defaultConfigPath returns NOTESCTL_CONFIG, or else config.yaml in the notesctl directory of the user
+// configuration directory.
+func defaultConfigPath() string {
+	if path := os.Getenv("NOTESCTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "notesctl.yaml"
+	}
+	return filepath.Join(dir, "notesctl", "config.yaml")
+}
+
+// loadConfig reads the configuration file. A missing file is an empty configuration.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]Profile{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	return cfg, nil
+}
+
+// save writes the configuration file, which only the user may read as it holds tokens. It is written
+// to a temporary file created with mode 0600 and renamed over the old one, so that a file created with
+// a wider mode is tightened and a failed write leaves the old configuration in place.
+func (c *Config) save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// resolve returns the server and token to use: those given, falling back on the named profile, or the
+// current one when name is empty, and on the local server.
+func (c *Config) resolve(name, server, token string) (Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	var p Profile
+	if name != "" {
+		var ok bool
+		if p, ok = c.Profiles[name]; !ok {
+			return Profile{}, fmt.Errorf("%w: %s", errProfileNotFound, name)
+		}
+	}
+	if server != "" {
+		p.Server = server
+	}
+	if token != "" {
+		p.Token = token
+	}
+	if p.Server == "" {
+		p.Server = defaultServer
+	}
+	return p, nil
+}
+
+func profileList(e *env, args []string) error {
+	fs := flag.NewFlagSet("profile list", flag.ContinueOnError)
+	if _, err := parseN(fs, e, args, 0, ""); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(e.configPath)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		Name    string `json:"name"`
+		Server  string `json:"server"`
+		Current bool   `json:"current"`
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([]row, len(names))
+	for i, name := range names {
+		// the tokens are left out
+		rows[i] = row{Name: name, Server: cfg.Profiles[name].Server, Current: name == cfg.Current}
+	}
+	return printList(e.out, rows, []string{"CURRENT", "NAME", "SERVER"}, func(r row) []string {
+		current := ""
+		if r.Current {
+			current = "*"
+		}
+		return []string{current, r.Name, r.Server}
+	})
+}
+
+func profileSet(e *env, args []string) error {
+	fs := flag.NewFlagSet("profile set", flag.ContinueOnError)
+	server := fs.String("server", "", "the URL of the server")
+	token := fs.String("token", "", "the bearer token sent to the server")
+	positional, err := parseN(fs, e, args, 1, "[-server url] [-token t] <name>")
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(e.configPath)
+	if err != nil {
+		return err
+	}
+	name := positional[0]
+	p := cfg.Profiles[name]
+	if *server != "" {
+		p.Server = *server
+	}
+	if *token != "" {
+		p.Token = *token
+	}
+	if p.Server == "" {
+		return usagef("profile %s needs a server", name)
+	}
+	cfg.Profiles[name] = p
+	// the first profile becomes the default
+	if cfg.Current == "" {
+		cfg.Current = name
+	}
+	return cfg.save(e.configPath)
+}
+
+func profileUse(e *env, args []string) error {
+	fs := flag.NewFlagSet("profile use", flag.ContinueOnError)
+	positional, err := parseN(fs, e, args, 1, "<name>")
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(e.configPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[positional[0]]; !ok {
+		return fmt.Errorf("%w: %s", errProfileNotFound, positional[0])
+	}
+	cfg.Current = positional[0]
+	return cfg.save(e.configPath)
+}
+
+func profileDelete(e *env, args []string) error {
+	fs := flag.NewFlagSet("profile delete", flag.ContinueOnError)
+	positional, err := parseN(fs, e, args, 1, "<name>")
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(e.configPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[positional[0]]; !ok {
+		return fmt.Errorf("%w: %s", errProfileNotFound, positional[0])
+	}
+	delete(cfg.Profiles, positional[0])
+	if cfg.Current == positional[0] {
+		cfg.Current = ""
+	}
+	return cfg.save(e.configPath)
+}
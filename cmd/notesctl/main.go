@@ -0,0 +1,245 @@
+// notesctl manages the notes of the API and runs its site diagnostics from the command line.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/client"
+)
+
+// Exit codes, so that scripts can tell the failures apart
+const (
+	exitOK = 0
+	// the request failed
+	exitError = 1
+	// the command line is invalid
+	exitUsage = 2
+	// the note, file or profile does not exist
+	exitNotFound = 3
+	// a note with the title exists, or the note changed since the version given
+	exitConflict = 4
+	// the API cannot be reached, is unavailable or is rate limiting
+	exitUnavailable = 5
+)
+
+const usage = `Usage: notesctl [flags] <command> [arguments]
+
+Commands:
+  notes list [-keywords text]           list the notes
+  notes search <keywords>               list the notes containing the keywords
+  notes get <id>                        show a note
+  notes create -title t [-description d]
+                                        create a note
+  notes update <id> [-title t] [-description d] [-version n]
+                                        change a note, provided it has not changed since the version
+                                        (by default the version it is read at)
+  notes delete <id>...                  delete notes
+  notes import [-format f] <file>       create the notes of a JSON, YAML or CSV file, or - for standard input.
+                                        Notes whose title exists are skipped
+  notes export [-format f] [file]       write every note as JSON, YAML or CSV, to standard output by default
+  site ping [-count n] [-timeout s] [-interval s] <hostname>
+                                        ping a host from the server
+  site downloads                        list the downloads catalogue
+  site upload <file>                    add a file to the downloads catalogue
+  site download [-out file] <id>        save a file of the downloads catalogue, under its name by default,
+                                        or to standard output with -out -
+  profile list                          list the profiles of the configuration file
+  profile set [-server url] [-token t] <name>
+                                        add or change a profile
+  profile use <name>                    make a profile the default
+  profile delete <name>                 remove a profile
+
+The server and token are taken from the flags, then from NOTESCTL_SERVER and NOTESCTL_TOKEN, then from the
+profile: the -profile flag, NOTESCTL_PROFILE or the default profile of the configuration file.
+
+Exit codes: 0 success, 1 failure, 2 invalid command line, 3 not found, 4 conflict, 5 server unavailable.
+
+Flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// env is what a command runs with
+type env struct {
+	ctx    context.Context
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	out    printer
+	// the configuration file and the profile selected
+	configPath string
+	profile    string
+	server     string
+	token      string
+	timeout    time.Duration
+}
+
+// client returns a client of the server selected by the flags, environment and profile.
+func (e *env) client() (*client.Client, error) {
+	cfg, err := loadConfig(e.configPath)
+	if err != nil {
+		return nil, err
+	}
+	p, err := cfg.resolve(e.profile, e.server, e.token)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(p.Server, client.WithToken(p.Token), client.WithUserAgent("notesctl"))
+}
+
+// usageError is an invalid command line
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// run runs the command line, returning the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("notesctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&e.configPath, "config", defaultConfigPath(), "the configuration file holding the profiles")
+	fs.StringVar(&e.profile, "profile", os.Getenv("NOTESCTL_PROFILE"), "the profile to use instead of the default one")
+	fs.StringVar(&e.server, "server", os.Getenv("NOTESCTL_SERVER"), "the URL of the server, such as http://localhost:8080")
+	fs.StringVar(&e.token, "token", os.Getenv("NOTESCTL_TOKEN"), "the bearer token sent to the server")
+	fs.DurationVar(&e.timeout, "timeout", 30*time.Second, "how long a command may take")
+	format := fs.String("o", "table", "the output format: table, json or yaml")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	switch *format {
+	case "table", "json", "yaml":
+		e.out = printer{format: *format, w: stdout}
+	default:
+		fmt.Fprintf(stderr, "notesctl: invalid output format %q: expecting table, json or yaml\n", *format)
+		return exitUsage
+	}
+
+	commands := map[string]map[string]func(e *env, args []string) error{
+		"notes": {
+			"list":   notesList,
+			"search": notesSearch,
+			"get":    notesGet,
+			"create": notesCreate,
+			"update": notesUpdate,
+			"delete": notesDelete,
+			"import": notesImport,
+			"export": notesExport,
+		},
+		"site": {
+			"ping":      sitePing,
+			"downloads": siteDownloads,
+			"upload":    siteUpload,
+			"download":  siteDownload,
+		},
+		"profile": {
+			"list":   profileList,
+			"set":    profileSet,
+			"use":    profileUse,
+			"delete": profileDelete,
+		},
+	}
+	rest := fs.Args()
+	if len(rest) < 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	cmd, ok := commands[rest[0]][rest[1]]
+	if !ok {
+		fmt.Fprintf(stderr, "notesctl: unknown command %q\n", strings.Join(rest[:2], " "))
+		return exitUsage
+	}
+
+	var cancel context.CancelFunc
+	e.ctx, cancel = context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if err := cmd(e, rest[2:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "notesctl: %s\n", err)
+		}
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode maps the failure of a command to an exit code.
+func exitCode(err error) int {
+	var (
+		usageErr usageError
+		netErr   net.Error
+	)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, client.ErrNotFound), errors.Is(err, os.ErrNotExist), errors.Is(err, errProfileNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	case errors.Is(err, client.ErrUnavailable), errors.Is(err, client.ErrRateLimited),
+		errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return exitUnavailable
+	}
+	return exitError
+}
+
+// parse parses the flags of a command, which may come before or after its arguments, returning the arguments.
+func parse(fs *flag.FlagSet, e *env, args []string) ([]string, error) {
+	fs.SetOutput(e.stderr)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err.Error()}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseN parses the flags of a command taking n arguments.
+func parseN(fs *flag.FlagSet, e *env, args []string, n int, names string) ([]string, error) {
+	positional, err := parse(fs, e, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positional) != n {
+		return nil, usagef("usage: notesctl %s %s", fs.Name(), names)
+	}
+	return positional, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/handler"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/pkg/client"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// notesctl runs notesctl against a server with an in-memory repository
+type notesctl struct {
+	t          *testing.T
+	server     string
+	configPath string
+}
+
+func newNotesctl(t *testing.T) *notesctl {
+	for _, name := range []string{"NOTESCTL_CONFIG", "NOTESCTL_PROFILE", "NOTESCTL_SERVER", "NOTESCTL_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	logger, _ := log.NewForTest()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	srv := httptest.NewServer(handler.BuildHandler(logger, &config.Config{DataDir: t.TempDir()}, repo, handler.Components{}))
+	t.Cleanup(srv.Close)
+	return &notesctl{t: t, server: srv.URL, configPath: filepath.Join(t.TempDir(), "notesctl", "config.yaml")}
+}
+
+// run runs a command line, returning its exit code and output.
+func (n *notesctl) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), append([]string{"-config", n.configPath}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// runOK runs a command line that must succeed, returning its output.
+func (n *notesctl) runOK(args ...string) string {
+	code, stdout, stderr := n.run("", args...)
+	require.Equal(n.t, exitOK, code, stderr)
+	return stdout
+}
+
+func TestNotesctl_Profiles(t *testing.T) {
+	n := newNotesctl(t)
+
+	code, _, stderr := n.run("", "profile", "use", "demo")
+	assert.Equal(t, exitNotFound, code)
+	assert.Contains(t, stderr, "profile not found: demo")
+
+	n.runOK("profile", "set", "broken", "-server", "http://127.0.0.1:1")
+	n.runOK("profile", "set", "demo", "-server", n.server, "-token", "s3cret")
+	info, err := os.Stat(n.configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "the tokens are kept private")
+	assert.Equal(t, "CURRENT  NAME    SERVER\n*        broken  http://127.0.0.1:1\n         demo    "+n.server+"\n", n.runOK("profile", "list"))
+
+	code, _, _ = n.run("", "notes", "list")
+	assert.Equal(t, exitUnavailable, code, "the first profile is the default")
+	n.runOK("profile", "use", "demo")
+	assert.Contains(t, n.runOK("notes", "list"), "TITLE")
+	code, _, _ = n.run("", "-profile", "broken", "notes", "list")
+	assert.Equal(t, exitUnavailable, code)
+	assert.Contains(t, n.runOK("-profile", "broken", "-server", n.server, "notes", "list"), "TITLE", "the flags win over the profile")
+
+	// a configuration file readable by others is made private when it is saved
+	require.NoError(t, os.Chmod(n.configPath, 0644))
+	n.runOK("profile", "use", "demo")
+	info, err = os.Stat(n.configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(n.configPath))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left behind")
+
+	n.runOK("profile", "delete", "demo")
+	var profiles []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(n.runOK("-o", "json", "profile", "list")), &profiles))
+	assert.Equal(t, []map[string]interface{}{{"name": "broken", "server": "http://127.0.0.1:1", "current": false}}, profiles)
+}
+
+func TestNotesctl_Notes(t *testing.T) {
+	n := newNotesctl(t)
+	n.runOK("profile", "set", "demo", "-server", n.server)
+
+	var created client.Note
+	require.NoError(t, json.Unmarshal([]byte(n.runOK("-o", "json", "notes", "create", "-title", "cobra", "-description", "cobra is a CLI package")), &created))
+	assert.Equal(t, "cobra", created.Title)
+	code, _, stderr := n.run("", "notes", "create", "-title", "cobra")
+	assert.Equal(t, exitConflict, code)
+	assert.Contains(t, stderr, "notesctl: ")
+
+	out := n.runOK("notes", "get", created.NoteID)
+	assert.Contains(t, out, "Title:        cobra\n")
+	assert.Contains(t, n.runOK("-o", "yaml", "notes", "get", created.NoteID), "title: cobra\n")
+	code, _, _ = n.run("", "notes", "get", "missing")
+	assert.Equal(t, exitNotFound, code)
+
+	out = n.runOK("notes", "update", created.NoteID, "-description", "commands and flags")
+	assert.Contains(t, out, "Description:  commands and flags\n")
+	assert.Contains(t, out, "Title:        cobra\n", "the title is kept")
+	code, _, _ = n.run("", "notes", "update", created.NoteID, "-title", "viper", "-version", "1")
+	assert.Equal(t, exitConflict, code, "the note changed since version 1")
+
+	out = n.runOK("notes", "search", "commands")
+	assert.Contains(t, out, created.NoteID)
+
+	require.NoError(t, json.Unmarshal([]byte(n.runOK("-o", "json", "notes", "get", created.NoteID)), &created))
+	n.runOK("notes", "delete", created.NoteID)
+	code, _, _ = n.run("", "notes", "delete", created.NoteID)
+	assert.Equal(t, exitNotFound, code)
+}
+
+func TestNotesctl_ImportExport(t *testing.T) {
+	n := newNotesctl(t)
+	n.runOK("profile", "set", "demo", "-server", n.server)
+	dir := t.TempDir()
+
+	exported := filepath.Join(dir, "notes.yaml")
+	n.runOK("notes", "export", exported)
+	b, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "- noteid: ")
+
+	out := n.runOK("notes", "import", exported)
+	assert.Equal(t, 2, strings.Count(out, "skipped"), "the notes exist")
+
+	code, stdout, stderr := n.run("title,description\ncobra,a CLI package\npflag,a flags package\n", "-o", "json", "notes", "import", "-format", "csv", "-")
+	require.Equal(t, exitOK, code, stderr)
+	var results []importResult
+	require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, "created", results[0].Status)
+	assert.NotEmpty(t, results[1].NoteID)
+
+	out = n.runOK("notes", "export", "-format", "csv")
+	assert.True(t, strings.HasPrefix(out, "noteid,title,description,createdon,version\n"))
+	assert.Contains(t, out, ",pflag,a flags package,")
+	assert.Equal(t, 5, strings.Count(out, "\n"))
+
+	var notes []client.Note
+	require.NoError(t, json.Unmarshal([]byte(n.runOK("notes", "export")), &notes))
+	assert.Len(t, notes, 4)
+
+	code, _, _ = n.run("", "notes", "import", filepath.Join(dir, "missing.json"))
+	assert.Equal(t, exitNotFound, code)
+	code, _, _ = n.run("", "notes", "import", "-format", "xml", exported)
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestNotesctl_Downloads(t *testing.T) {
+	n := newNotesctl(t)
+	n.runOK("profile", "set", "demo", "-server", n.server)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.txt")
+	require.NoError(t, os.WriteFile(file, []byte("all systems go"), 0600))
+
+	var uploaded []client.Download
+	require.NoError(t, json.Unmarshal([]byte(n.runOK("-o", "json", "site", "upload", file)), &uploaded))
+	require.Len(t, uploaded, 1)
+	assert.Equal(t, "report.txt", uploaded[0].Name)
+	assert.Contains(t, n.runOK("site", "downloads"), "report.txt")
+
+	assert.Equal(t, "all systems go", n.runOK("site", "download", "-out", "-", uploaded[0].ID))
+	saved := filepath.Join(dir, "saved.txt")
+	n.runOK("site", "download", uploaded[0].ID, "-out", saved)
+	b, err := os.ReadFile(saved)
+	require.NoError(t, err)
+	assert.Equal(t, "all systems go", string(b))
+
+	code, _, _ := n.run("", "site", "download", "-out", saved, "missing")
+	assert.Equal(t, exitNotFound, code)
+	b, err = os.ReadFile(saved)
+	require.NoError(t, err)
+	assert.Equal(t, "all systems go", string(b), "a failed download leaves the file as it was")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "and nothing behind")
+}
+
+func TestNotesctl_Usage(t *testing.T) {
+	n := newNotesctl(t)
+	for _, args := range [][]string{
+		{},
+		{"notes"},
+		{"notes", "frobnicate"},
+		{"-o", "xml", "notes", "list"},
+		{"notes", "get"},
+		{"notes", "get", "-bogus", "1"},
+		{"notes", "create"},
+		{"site", "ping"},
+	} {
+		code, _, stderr := n.run("", args...)
+		assert.Equal(t, exitUsage, code, args)
+		assert.NotEmpty(t, stderr, args)
+	}
+	code, _, stderr := n.run("", "-h")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "Exit codes:")
+	code, _, _ = n.run("", "notes", "list", "-h")
+	assert.Equal(t, exitOK, code)
+}
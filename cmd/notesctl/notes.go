@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/client"
+)
+
+// the columns of notes in CSV files: those of the version 1 CSV representation of the API, and the version
+var csvHeader = []string{"noteid", "title", "description", "createdon", "version"}
+
+var noteHeader = []string{"ID", "TITLE", "DESCRIPTION", "VERSION"}
+
+func noteRow(n client.Note) []string {
+	return []string{n.NoteID, n.Title, n.Description, strconv.FormatUint(n.Version, 10)}
+}
+
+func noteFields(n client.Note) [][2]string {
+	return [][2]string{
+		{"ID", n.NoteID},
+		{"Title", n.Title},
+		{"Description", n.Description},
+		{"Created", n.CreatedOn.Format(time.RFC3339)},
+		{"Updated", n.UpdatedOn.Format(time.RFC3339)},
+		{"Version", strconv.FormatUint(n.Version, 10)},
+	}
+}
+
+func notesList(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes list", flag.ContinueOnError)
+	keywords := fs.String("keywords", "", "list only the notes containing the keywords")
+	if _, err := parseN(fs, e, args, 0, "[-keywords text]"); err != nil {
+		return err
+	}
+	return listNotes(e, *keywords)
+}
+
+func notesSearch(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes search", flag.ContinueOnError)
+	positional, err := parse(fs, e, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return usagef("usage: notesctl notes search <keywords>")
+	}
+	return listNotes(e, strings.Join(positional, " "))
+}
+
+func listNotes(e *env, keywords string) error {
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	notes, err := c.ListNotes(e.ctx, keywords)
+	if err != nil {
+		return err
+	}
+	return printList(e.out, notes, noteHeader, noteRow)
+}
+
+func notesGet(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes get", flag.ContinueOnError)
+	positional, err := parseN(fs, e, args, 1, "<id>")
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	n, err := c.GetNote(e.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return printOne(e.out, n, noteFields)
+}
+
+func notesCreate(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes create", flag.ContinueOnError)
+	title := fs.String("title", "", "the title of the note, which must be unique")
+	description := fs.String("description", "", "the description of the note")
+	if _, err := parseN(fs, e, args, 0, "-title t [-description d]"); err != nil {
+		return err
+	}
+	if *title == "" {
+		return usagef("usage: notesctl notes create -title t [-description d]")
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	n, err := c.CreateNote(e.ctx, client.Note{Title: *title, Description: *description})
+	if err != nil {
+		return err
+	}
+	return printOne(e.out, n, noteFields)
+}
+
+func notesUpdate(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes update", flag.ContinueOnError)
+	title := fs.String("title", "", "the new title of the note")
+	description := fs.String("description", "", "the new description of the note")
+	version := fs.Uint64("version", 0, "the version the note must still be at, by default the version it is read at")
+	positional, err := parseN(fs, e, args, 1, "<id> [-title t] [-description d] [-version n]")
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	// the fields left out keep their value
+	n, err := c.GetNote(e.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			n.Title = *title
+		case "description":
+			n.Description = *description
+		case "version":
+			n.Version = *version
+		}
+	})
+	if n, err = c.UpdateNote(e.ctx, n); err != nil {
+		return err
+	}
+	return printOne(e.out, n, noteFields)
+}
+
+func notesDelete(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes delete", flag.ContinueOnError)
+	positional, err := parse(fs, e, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return usagef("usage: notesctl notes delete <id>...")
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	for _, id := range positional {
+		if err := c.DeleteNote(e.ctx, id); err != nil {
+			return fmt.Errorf("note %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// importResult is the outcome of importing a note
+type importResult struct {
+	Title string `json:"title"`
+	// created or skipped
+	Status string `json:"status"`
+	NoteID string `json:"noteid,omitempty"`
+}
+
+func notesImport(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes import", flag.ContinueOnError)
+	format := fs.String("format", "", "the format of the file: json, yaml or csv, by default taken from its extension")
+	positional, err := parseN(fs, e, args, 1, "[-format f] <file>")
+	if err != nil {
+		return err
+	}
+	if *format, err = fileFormat(*format, positional[0]); err != nil {
+		return err
+	}
+	var r io.Reader = e.stdin
+	if positional[0] != "-" {
+		f, err := os.Open(positional[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	notes, err := readNotes(r, *format)
+	if err != nil {
+		return fmt.Errorf("invalid file %s: %w", positional[0], err)
+	}
+
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	results := make([]importResult, 0, len(notes))
+	for _, n := range notes {
+		created, err := c.CreateNote(e.ctx, n)
+		switch {
+		case errors.Is(err, client.ErrConflict):
+			results = append(results, importResult{Title: n.Title, Status: "skipped"})
+		case err != nil:
+			return fmt.Errorf("note %q: %w", n.Title, err)
+		default:
+			results = append(results, importResult{Title: n.Title, Status: "created", NoteID: created.NoteID})
+		}
+	}
+	return printList(e.out, results, []string{"TITLE", "STATUS", "ID"}, func(r importResult) []string {
+		return []string{r.Title, r.Status, r.NoteID}
+	})
+}
+
+func notesExport(e *env, args []string) error {
+	fs := flag.NewFlagSet("notes export", flag.ContinueOnError)
+	format := fs.String("format", "", "the format of the file: json, yaml or csv, by default taken from its extension")
+	positional, err := parse(fs, e, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return usagef("usage: notesctl notes export [-format f] [file]")
+	}
+	path := "-"
+	if len(positional) == 1 {
+		path = positional[0]
+	}
+	if *format, err = fileFormat(*format, path); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	notes, err := c.ListNotes(e.ctx, "")
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return writeNotes(e.stdout, *format, notes)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeNotes(f, *format, notes); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fileFormat returns the format given, or else the one of the extension of the file, defaulting to JSON.
+func fileFormat(format, path string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			format = "yaml"
+		case ".csv":
+			format = "csv"
+		default:
+			format = "json"
+		}
+	}
+	switch format {
+	case "json", "yaml", "csv":
+		return format, nil
+	}
+	return "", usagef("invalid file format %q: expecting json, yaml or csv", format)
+}
+
+// readNotes decodes a list of notes, or a single note.
+func readNotes(r io.Reader, format string) ([]client.Note, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var notes []client.Note
+	switch format {
+	case "csv":
+		return readCSV(b)
+	case "yaml":
+		if err := yaml.Unmarshal(b, &notes); err != nil {
+			var n client.Note
+			if yaml.Unmarshal(b, &n) != nil {
+				return nil, err
+			}
+			notes = []client.Note{n}
+		}
+	default:
+		if err := json.Unmarshal(b, &notes); err != nil {
+			var n client.Note
+			if json.Unmarshal(b, &n) != nil {
+				return nil, err
+			}
+			notes = []client.Note{n}
+		}
+	}
+	return notes, nil
+}
+
+// readCSV decodes the notes of a CSV file whose header names the columns.
+func readCSV(b []byte) ([]client.Note, error) {
+	records, err := csv.NewReader(strings.NewReader(string(b))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("the CSV header has no title column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	notes := make([]client.Note, 0, len(records)-1)
+	for _, record := range records[1:] {
+		notes = append(notes, client.Note{Title: field(record, "title"), Description: field(record, "description")})
+	}
+	return notes, nil
+}
+
+func writeNotes(w io.Writer, format string, notes []client.Note) error {
+	if notes == nil {
+		notes = []client.Note{}
+	}
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, n := range notes {
+			created := ""
+			if !n.CreatedOn.IsZero() {
+				created = n.CreatedOn.Format(time.RFC3339)
+			}
+			cw.Write([]string{n.NoteID, n.Title, n.Description, created, strconv.FormatUint(n.Version, 10)})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "yaml":
+		return printer{format: "yaml", w: w}.encode(notes)
+	}
+	return printer{format: "json", w: w}.encode(notes)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// printer writes the results of the commands as a table, JSON or YAML
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+// printList prints items, one row of the table each.
+func printList[T any](p printer, items []T, header []string, row func(T) []string) error {
+	if p.format != "table" {
+		if items == nil {
+			items = []T{}
+		}
+		return p.encode(items)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(tw, strings.Join(clean(row(item)), "\t"))
+	}
+	return tw.Flush()
+}
+
+// printOne prints an item, with a line of the table for each of its fields.
+func printOne[T any](p printer, item T, fields func(T) [][2]string) error {
+	if p.format != "table" {
+		return p.encode(item)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, f := range fields(item) {
+		fmt.Fprintf(tw, "%s:\t%s\n", f[0], strings.Join(clean([]string{f[1]}), ""))
+	}
+	return tw.Flush()
+}
+
+// encode writes v as JSON or YAML. The YAML is converted from the JSON so that both have the same field names.
+func (p printer) encode(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if p.format == "json" {
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	}
+	// JSON is YAML; decoding it as the value of a mapping keeps the order of the fields
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(append(append([]byte(`{"v": `), b...), '}'), &doc); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(doc[0].Value)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(out)
+	return err
+}
+
+// clean keeps the cells of a table on one line.
+func clean(cells []string) []string {
+	for i, c := range cells {
+		cells[i] = strings.Join(strings.Fields(c), " ")
+	}
+	return cells
+}
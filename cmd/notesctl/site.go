@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/client"
+)
+
+func sitePing(e *env, args []string) error {
+	fs := flag.NewFlagSet("site ping", flag.ContinueOnError)
+	count := fs.Int("count", 0, "the number of echo requests to send, 4 by default")
+	timeout := fs.Int("timeout", 0, "the seconds to wait for each reply, 5 by default")
+	interval := fs.Float64("interval", 0, "the seconds between echo requests, 1 by default")
+	positional, err := parseN(fs, e, args, 1, "[-count n] [-timeout s] [-interval s] <hostname>")
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	result, err := c.Ping(e.ctx, client.Site{Hostname: positional[0], Count: *count, Timeout: *timeout, Interval: *interval})
+	if err != nil {
+		return err
+	}
+	return printOne(e.out, result, func(r client.PingResult) [][2]string {
+		fields := [][2]string{
+			{"Hostname", r.Hostname},
+			{"Address", r.Address},
+			{"Sent", strconv.Itoa(r.PacketsSent)},
+			{"Received", strconv.Itoa(r.PacketsReceived)},
+			{"Loss", strconv.FormatFloat(r.PacketLoss, 'f', -1, 64) + "%"},
+		}
+		if r.RTT != nil {
+			fields = append(fields, [2]string{"RTT", fmt.Sprintf("min %.3f ms, avg %.3f ms, max %.3f ms", r.RTT.Min, r.RTT.Avg, r.RTT.Max)})
+		}
+		return fields
+	})
+}
+
+var downloadHeader = []string{"ID", "NAME", "SIZE", "TYPE", "UPLOADED"}
+
+func downloadRow(d client.Download) []string {
+	return []string{d.ID, d.Name, strconv.FormatInt(d.Size, 10), d.ContentType, d.Uploaded.Format(time.RFC3339)}
+}
+
+func siteDownloads(e *env, args []string) error {
+	fs := flag.NewFlagSet("site downloads", flag.ContinueOnError)
+	if _, err := parseN(fs, e, args, 0, ""); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	downloads, err := c.ListDownloads(e.ctx)
+	if err != nil {
+		return err
+	}
+	return printList(e.out, downloads, downloadHeader, downloadRow)
+}
+
+func siteUpload(e *env, args []string) error {
+	fs := flag.NewFlagSet("site upload", flag.ContinueOnError)
+	positional, err := parseN(fs, e, args, 1, "<file>")
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	d, err := c.UploadFile(e.ctx, filepath.Base(positional[0]), f)
+	if err != nil {
+		return err
+	}
+	return printList(e.out, []client.Download{d}, downloadHeader, downloadRow)
+}
+
+func siteDownload(e *env, args []string) error {
+	fs := flag.NewFlagSet("site download", flag.ContinueOnError)
+	out := fs.String("out", "", "the file to save to, - for standard output, by default the name of the download")
+	positional, err := parseN(fs, e, args, 1, "[-out file] <id>")
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		return c.DownloadFile(e.ctx, positional[0], e.stdout)
+	}
+	path := *out
+	if path == "" {
+		d, err := c.GetDownload(e.ctx, positional[0])
+		if err != nil {
+			return err
+		}
+		// only the base name, so that a download cannot be saved outside the working directory
+		path = filepath.Base(d.Name)
+	}
+	// the download is written next to the file and renamed over it once complete, so that a failed download
+	// leaves the file as it was
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	err = c.DownloadFile(e.ctx, positional[0], f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	fmt.Fprintln(e.stderr, "saved", path)
+	return nil
+}
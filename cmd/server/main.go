@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/rs/cors"
     _ "github.com/mattn/go-sqlite3"
@@ -10,9 +13,17 @@ import (
 	"github.com/fortify-presales/insecure-go-api/pkg/log"
 
 	_ "github.com/fortify-presales/insecure-go-api/docs" // docs is generated by Swag CLI, you have to import it.
+	"github.com/fortify-presales/insecure-go-api/internal/admin"
 	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/health"
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/internal/link"
 	//"github.com/fortify-presales/insecure-go-api/internal/repository/inmem"
 	"github.com/fortify-presales/insecure-go-api/internal/middleware"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/internal/site"
+	"github.com/fortify-presales/insecure-go-api/internal/webhook"
 
 	s "github.com/fortify-presales/insecure-go-api/internal/server"
 	h "github.com/fortify-presales/insecure-go-api/internal/handler"
@@ -54,6 +65,34 @@ func main() {
 		logger.Errorf("failed to load application configuration: %s", err)
 		os.Exit(-1)
 	}
+	// Re-create root logger using the logging configuration, masking the configured secrets
+	redactor := log.NewRedactor(cfg.Log.Redact.Fields...)
+	redactor.AddSecretsFrom(cfg)
+	logOptions := []log.Option{log.WithRedactor(redactor)}
+	// Keep recent log entries in memory for the admin API
+	var ring *log.Ring
+	if cfg.Log.RingSize > 0 {
+		ring = log.NewRing(cfg.Log.RingSize)
+		logOptions = append(logOptions, log.WithRing(ring))
+	}
+	configured, levels, err := log.NewWithConfig(cfg.Log, logOptions...)
+	if err != nil {
+		logger.Errorf("failed to configure logger: %s", err)
+		os.Exit(-1)
+	}
+	logger = configured.With(nil, "version", Version)
+	// Flush buffered log entries and close the log outputs on shutdown
+	defer logger.Close()
+	// Route log/slog output, including that of libraries, through the root logger
+	slog.SetDefault(slog.New(log.NewSlogHandler(logger)))
+	// SIGUSR1 toggles debug logging
+	defer log.ToggleDebugOnSignal(levels, logger)()
+	// Create the data directory holding the database, command history and downloads
+	if err := cfg.PrepareDataDir(); err != nil {
+		logger.Errorf("failed to prepare data directory: %s", err)
+		os.Exit(-1)
+	}
+	logger.Infof("Using data directory %s", cfg.DataDir)
 
    
 	// Initialize storage
@@ -64,18 +103,92 @@ func main() {
 	//}
 	//repo.Populate() // Populate the in-memory database
 
-	repo := r.BuildRepository(logger, cfg)
+	db := r.OpenDatabase(logger, cfg.DataPath(cfg.Database))
+	defer db.Close()
+	// Record note changes in the webhook outbox in the same transaction
+	webhookStore := r.BuildWebhookStore(logger, db)
+	// Keep recent note changes for the note change stream
+	noteChanges := note.NewFeed(cfg.NoteEventBacklog)
+	repo := r.BuildRepository(logger, cfg, db, note.WithOutbox(webhookStore), note.OnChange(noteChanges.Publish))
 	if repo == nil {
 		logger.Errorf("Failed to initialize repository")
 		os.Exit(-1)
 	}
+	// Deliver note change events to the webhooks
+	webhooks := webhook.NewManager(logger.Named("webhook"), webhookStore, nil)
+	webhooks.Start()
+	defer webhooks.Close()
+	// Bound the commands run by the site API
+	commands := site.NewCommandRunner(site.CommandRunnerConfig{
+		Timeout:     time.Duration(cfg.CommandTimeout) * time.Second,
+		MaxOutput:   cfg.CommandMaxOutput << 10,
+		Concurrency: cfg.CommandConcurrency,
+	})
+	// Run long site diagnostics in the background
+	jobs := job.NewManager(logger.Named("job"), r.BuildJobStore(logger, db), cfg.JobWorkers, cfg.JobQueueSize, site.Runners(commands))
+	defer jobs.Close()
+	// Record the commands run by the site API
+	history, err := site.NewHistory(cfg.DataPath(cfg.CommandHistory))
+	if err != nil {
+		logger.Errorf("failed to open command history: %s", err)
+		os.Exit(-1)
+	}
+	defer history.Close()
+	// Check the registered site monitors on their interval
+	notifiers := []monitor.Notifier{monitor.LogNotifier{Logger: logger.Named("monitor")}}
+	if cfg.MonitorWebhookURL != "" {
+		notifiers = append(notifiers, monitor.NewWebhookNotifier(cfg.MonitorWebhookURL))
+	}
+	monitors := monitor.NewManager(logger.Named("monitor"), r.BuildMonitorStore(logger, db), site.Probes(commands), notifiers...)
+	if err := monitors.Start(context.Background()); err != nil {
+		logger.Errorf("failed to start monitors: %s", err)
+		os.Exit(-1)
+	}
+	defer monitors.Close()
+	// Sign download links when a key is configured
+	var links *link.Manager
+	if cfg.DownloadSigningKey != "" {
+		links = link.NewManager(logger.Named("link"), []byte(cfg.DownloadSigningKey), r.BuildLinkStore(logger, db))
+	}
 	// Initialize middleware stack
+	limiter := middleware.NewRateLimit(1, 200)
 	stack := middleware.MiddlewareStack(
-		middleware.RateLimiter(200),
+		middleware.RequestID(),
+		limiter.Middleware(),
 		middleware.PanicRecovery(logger),
 	)
 	// Initialize CORS
-	serverMux := cors.Default().Handler(h.BuildHandler(logger, cfg, repo))
+	serverMux := cors.Default().Handler(h.BuildHandler(logger, cfg, repo, h.Components{
+		Site: site.Services{
+			Jobs:      jobs,
+			History:   history,
+			Monitors:  monitors,
+			Commands:  commands,
+			Downloads: site.NewDownloads(cfg.DataPath(cfg.DownloadsDir), int64(cfg.MaxUploadSize)<<20),
+			Links:     links,
+		},
+		Webhooks:    webhooks,
+		NoteChanges: noteChanges,
+		Readiness: health.Checks{
+			"data_dir":  health.Writable(cfg.DataDir),
+			"downloads": health.Writable(cfg.DataPath(cfg.DownloadsDir)),
+			"database":  db.PingContext,
+		},
+	}))
+
+	// Serve the admin API on its own port
+	adminHandler := admin.MakeHTTPHandler(logger.Named("admin"), cfg, admin.Sources{
+		Version:     Version,
+		Repository:  repo,
+		Levels:      levels,
+		Ring:        ring,
+		RateLimiter: limiter,
+	})
+	if adminSrv, err := admin.Start(logger, cfg, adminHandler); err != nil {
+		logger.Warnf("Admin API not started: %s", err)
+	} else {
+		defer adminSrv.Close()
+	}
 
 	srv := s.RunServer(8080, stack(serverMux), logger)
 	if srv == nil {	
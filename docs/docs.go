@@ -25,19 +25,25 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/notes": {
+        "/../v2/notes": {
             "get": {
-                "description": "Get all Notes",
+                "description": "Get all Notes, or the Notes whose title or description contains the keywords",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "tags": [
-                    "notes"
+                    "notes-v2"
                 ],
-                "summary": "Get Notes",
+                "summary": "Get Notes (v2)",
                 "parameters": [
                     {
                         "type": "string",
@@ -53,18 +59,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/note.Note"
+                                "$ref": "#/definitions/note.NoteV2"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.APIError"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "406": {
+                        "description": "Not Acceptable",
                         "schema": {
                             "$ref": "#/definitions/model.APIError"
                         }
@@ -76,21 +76,25 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/notes/": {
+            },
             "post": {
-                "description": "Create a new Note",
+                "description": "Create a new Note, returning it with its location",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "tags": [
-                    "notes"
+                    "notes-v2"
                 ],
-                "summary": "Create Note",
+                "summary": "Create Note (v2)",
                 "parameters": [
                     {
                         "description": "Note",
@@ -98,15 +102,21 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/note.Note"
+                            "$ref": "#/definitions/note.NoteInputV2"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/note.Note"
+                            "$ref": "#/definitions/note.NoteV2"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "the URL of the Note"
+                            }
                         }
                     },
                     "400": {
@@ -115,8 +125,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.APIError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Another Note has the title",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
                         "schema": {
                             "$ref": "#/definitions/model.APIError"
                         }
@@ -130,19 +152,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/notes/{id}": {
+        "/../v2/notes/{id}": {
             "get": {
                 "description": "Get a Note",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "tags": [
-                    "notes"
+                    "notes-v2"
                 ],
-                "summary": "Get Note",
+                "summary": "Get Note (v2)",
                 "parameters": [
                     {
                         "type": "string",
@@ -156,17 +184,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/note.Note"
+                            "$ref": "#/definitions/note.NoteV2"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Could not find Note Id",
                         "schema": {
                             "$ref": "#/definitions/model.APIError"
                         }
                     },
-                    "404": {
-                        "description": "Could not find Note Id",
+                    "406": {
+                        "description": "Not Acceptable",
                         "schema": {
                             "$ref": "#/definitions/model.APIError"
                         }
@@ -180,17 +208,23 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update an existing Note",
+                "description": "Update an existing Note\nWhen the body has a version, the update fails with 409 Conflict if the Note has changed since",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "tags": [
-                    "notes"
+                    "notes-v2"
                 ],
-                "summary": "Update Note",
+                "summary": "Update Note (v2)",
                 "parameters": [
                     {
                         "type": "string",
@@ -205,7 +239,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/note.Note"
+                            "$ref": "#/definitions/note.NoteInputV2"
                         }
                     }
                 ],
@@ -213,7 +247,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/note.Note"
+                            "$ref": "#/definitions/note.NoteV2"
                         }
                     },
                     "400": {
@@ -228,6 +262,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.APIError"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "The Note has changed since the version",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -239,15 +291,21 @@ const docTemplate = `{
             "delete": {
                 "description": "Delete a Note",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "tags": [
-                    "notes"
+                    "notes-v2"
                 ],
-                "summary": "Delete Note",
+                "summary": "Delete Note (v2)",
                 "parameters": [
                     {
                         "type": "string",
@@ -257,11 +315,67 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Could not find Note Id",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes": {
+            "get": {
+                "description": "Get all Notes",
+                "consumes": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Get Notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "alphadex",
+                        "description": "search by keywords",
+                        "name": "keywords",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.APIMessage"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/note.Note"
+                            }
                         }
                     },
                     "400": {
@@ -271,7 +385,13 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Could not find Note Id",
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
                         "schema": {
                             "$ref": "#/definitions/model.APIError"
                         }
@@ -285,34 +405,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/site/download/{id}": {
-            "get": {
-                "description": "Download a file by ID",
+        "/notes/": {
+            "post": {
+                "description": "Create a new Note",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
                 ],
                 "tags": [
-                    "site"
+                    "notes"
                 ],
-                "summary": "Download File",
+                "summary": "Create Note",
                 "parameters": [
                     {
-                        "type": "string",
-                        "example": "\"12345\"",
-                        "description": "id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Note",
+                        "name": "Note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/note.Note"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "string",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/note.Note"
                         }
                     },
                     "400": {
@@ -321,6 +448,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.APIError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -330,9 +475,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/site/ping": {
+        "/notes/changes": {
             "get": {
-                "description": "Ping a Site using URL query parameter",
+                "description": "Get the notes created, updated and deleted since a sync token, and the token to pass next time.\nWithout a token every note is returned. Request again straight away while more is true",
                 "consumes": [
                     "application/json"
                 ],
@@ -340,24 +485,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "site"
+                    "notes"
                 ],
-                "summary": "Ping Site by Query",
+                "summary": "Get Note Changes",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "\"localhost\"",
-                        "description": "hostname",
-                        "name": "hostname",
-                        "in": "query",
-                        "required": true
+                        "description": "sync token returned by the previous request",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "changes to return, at most 1000",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "string",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/note.Changes"
                         }
                     },
                     "400": {
@@ -373,9 +523,63 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/notes/events": {
+            "get": {
+                "description": "Stream note changes as Server-Sent Events named created, updated and deleted, with the change as data\nA client reconnecting with the Last-Event-ID header (or lastEventId parameter) resumes after that change.\nA reset event is sent instead when the changes since then are no longer kept, after which the notes should be fetched again",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Stream Note Changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only changes to notes whose title or description contains the keywords",
+                        "name": "keywords",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "resume after this event ID",
+                        "name": "lastEventId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "resume after this event ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/note.Change"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/notes/sync": {
             "post": {
-                "description": "Ping a Site using JSON Body\nJSON Body should contain a \"hostname\" field\nExample: {\"hostname\": \"localhost\"}\nThis is a JSON Injection vulnerability example",
+                "description": "Apply the changes an offline client made to the versions of the notes it last saw.\nA change to a note that was changed since is not applied and is reported as a conflict together with the note as it is.\nExample: {\"changes\": [{\"noteid\": \"1b4e28ba-2fa1-11d2-883f-0016d3cca427\", \"base_version\": 3, \"title\": \"zap\", \"description\": \"zap is a logging package\"}]}",
                 "consumes": [
                     "application/json"
                 ],
@@ -383,25 +587,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "site"
+                    "notes"
                 ],
-                "summary": "Ping Site by Body",
+                "summary": "Sync Note Changes",
                 "parameters": [
                     {
-                        "description": "Site",
-                        "name": "Site",
+                        "description": "client changes",
+                        "name": "SyncRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/site.Site"
+                            "$ref": "#/definitions/note.SyncRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "string",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/note.SyncResponse"
                         }
                     },
                     "400": {
@@ -418,41 +622,2722 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.APIError": {
-            "type": "object",
-            "properties": {
-                "errorCode": {
-                    "type": "integer"
-                },
-                "errorMessage": {
-                    "type": "string"
-                }
-            }
-        },
-        "model.APIMessage": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                }
-            }
         },
-        "note.Note": {
+        "/notes/{id}": {
+            "get": {
+                "description": "Get a Note",
+                "consumes": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Get Note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/note.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find Note Id",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing Note",
+                "consumes": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Update Note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Note",
+                        "name": "Note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/note.Note"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/note.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find Note Id",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a Note",
+                "consumes": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/xml",
+                    "application/yaml",
+                    "text/csv"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Delete Note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find Note Id",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/dns": {
+            "get": {
+                "description": "Look up the DNS records of a hostname, optionally querying a specific DNS server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "DNS Lookup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"localhost\"",
+                        "description": "hostname",
+                        "name": "hostname",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "record types: A, AAAA, CNAME, MX, TXT",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"8.8.8.8:53\"",
+                        "description": "DNS server address with optional port",
+                        "name": "resolver",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "seconds to wait (1-30)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.DNSResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/download/{id}": {
+            "get": {
+                "description": "Download a file by ID\nRange requests and conditional requests using If-Modified-Since or If-None-Match are supported\nLinks created with POST /site/downloads/{id}/links carry a signature that is checked before the file is served\nWhen signed links are enabled, files are only served through a valid link\nA single use link serves a few range requests after the start of the file to the client that used it, so that the download can be resumed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Download File",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"12345\"",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signed link id",
+                        "name": "link",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "signed link expiry, in seconds since the epoch",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1 if the signed link can be used once only",
+                        "name": "once",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the address the signed link is bound to",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signed link signature",
+                        "name": "sig",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "416": {
+                        "description": "Requested Range Not Satisfiable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/downloads": {
+            "get": {
+                "description": "List the files that can be downloaded, most recently uploaded first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "List Downloads",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/site.Download"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a file to the downloads catalogue using a multipart form with a \"file\" field",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Upload File",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/site.Download"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/downloads/{id}": {
+            "get": {
+                "description": "Get the size, content type, checksum and upload time of a file",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Get Download",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.Download"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/downloads/{id}/links": {
+            "post": {
+                "description": "Create a signed link to a file that expires, and may be used once only or from one address only\nExample: {\"expires_in\": 600, \"single_use\": true}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Create Download Link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"12345\"",
+                        "description": "file id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Request",
+                        "name": "Request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/link.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/link.Link"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/history": {
+            "get": {
+                "description": "List the commands run by the site API, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Get Command History",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"ping\"",
+                        "description": "command name",
+                        "name": "command",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "text contained in one of the arguments, such as a hostname",
+                        "name": "hostname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client address or address prefix",
+                        "name": "client",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "request ID",
+                        "name": "request_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "exit code",
+                        "name": "exit_code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time of the oldest entry",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time after the newest entry",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "number of matching entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "maximum number of entries (1-500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.HistoryPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/http": {
+            "get": {
+                "description": "Send a request to a URL and report the status, headers, redirect chain and timing breakdown",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Probe HTTP",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"http://localhost:8080/swagger/index.html\"",
+                        "description": "absolute http or https URL",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "GET",
+                        "description": "GET or HEAD",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "redirects to follow (0-10)",
+                        "name": "max_redirects",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "seconds to wait (1-30)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.HTTPResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/jobs": {
+            "post": {
+                "description": "Run a site diagnostic in the background\nExample: {\"type\": \"ping\", \"params\": {\"hostname\": \"localhost\", \"count\": 10}}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Submit Job",
+                "parameters": [
+                    {
+                        "description": "JobRequest",
+                        "name": "JobRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/site.JobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/job.Job"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the job"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/jobs/{id}": {
+            "get": {
+                "description": "Get the status, progress and result of a site diagnostic job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Get Job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/job.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancel a queued or running site diagnostic job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Cancel Job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the job was cancelled before it started",
+                        "schema": {
+                            "$ref": "#/definitions/job.Job"
+                        }
+                    },
+                    "202": {
+                        "description": "the running job is being cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/job.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/links/{id}": {
+            "get": {
+                "description": "Get a signed download link with the audit record of each attempt to use it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Get Download Link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "link id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/link.Audit"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop a signed download link from being used",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Revoke Download Link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "link id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/monitors": {
+            "get": {
+                "description": "List the monitors with their current state",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "List Monitors",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/monitor.Monitor"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Check a host or URL on an interval with one of the site diagnostics\nExample: {\"name\": \"API\", \"type\": \"http\", \"params\": {\"url\": \"http://localhost:8080/swagger/index.html\"}, \"interval\": 60}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Create Monitor",
+                "parameters": [
+                    {
+                        "description": "MonitorRequest",
+                        "name": "MonitorRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/site.MonitorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/monitor.Monitor"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the monitor"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/monitors/{id}": {
+            "get": {
+                "description": "Get a monitor with its current state",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Get Monitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/monitor.Monitor"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop checking a monitor and delete its history",
+                "tags": [
+                    "site"
+                ],
+                "summary": "Delete Monitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/monitors/{id}/history": {
+            "get": {
+                "description": "Summarize the uptime percentage, latency percentiles and incidents of a monitor over a period",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Get Monitor History",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 start of the period (default 24 hours before the end)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 end of the period (default now)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include the checks made in the period",
+                        "name": "entries",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/monitor.History"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/ping": {
+            "get": {
+                "description": "Ping a Site using URL query parameters\nSend \"Accept: text/event-stream\" or set \"stream=true\" to receive each line of output as it is printed,\nfollowed by a \"summary\" event with the parsed statistics",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/event-stream",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Ping Site by Query",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"localhost\"",
+                        "description": "hostname",
+                        "name": "hostname",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "number of echo requests (1-20)",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "seconds to wait for each reply (1-30)",
+                        "name": "timeout",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "default": 1,
+                        "description": "seconds between echo requests (0.2-10)",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "stream the output as newline-delimited JSON",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.PingResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Ping a Site using JSON Body\nJSON Body should contain a \"hostname\" field and may contain \"count\", \"timeout\" and \"interval\"\nExample: {\"hostname\": \"localhost\", \"count\": 4}\nThis is a JSON Injection vulnerability example",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/event-stream",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Ping Site by Body",
+                "parameters": [
+                    {
+                        "description": "Site",
+                        "name": "Site",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/site.Site"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "stream the output as newline-delimited JSON",
+                        "name": "stream",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.PingResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/port": {
+            "get": {
+                "description": "Check whether a TCP port accepts connections and time the connection",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Check Port",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"localhost\"",
+                        "description": "hostname",
+                        "name": "hostname",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 8080,
+                        "description": "port",
+                        "name": "port",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "seconds to wait (1-30)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.PortResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/site/tls": {
+            "get": {
+                "description": "Describe the TLS protocol, certificate chain, expiry and subject alternative names of a server",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "site"
+                ],
+                "summary": "Check TLS",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "\"example.com\"",
+                        "description": "hostname",
+                        "name": "hostname",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 443,
+                        "description": "port",
+                        "name": "port",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name sent in the handshake and verified (default the hostname)",
+                        "name": "server_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "seconds to wait (1-30)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/site.TLSResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "List the webhooks, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List Webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Subscription"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe a URL to note.created, note.updated and note.deleted events\nEach delivery is signed with the webhook secret in the X-Webhook-Signature header\nExample: {\"url\": \"https://example.com/hooks/notes\", \"events\": [\"note.created\"], \"active\": true}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create Webhook",
+                "parameters": [
+                    {
+                        "description": "Subscription",
+                        "name": "Subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Get a webhook",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the URL, events and active flag of a webhook, and its secret if one is given",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Update Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Subscription",
+                        "name": "Subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a webhook and its delivery log",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the latest deliveries to a webhook with the outcome of their attempts, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List Webhook Deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Delivery"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{delivery}/redeliver": {
+            "post": {
+                "description": "Queue a new delivery of the event of an earlier delivery",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver Webhook Event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "delivery id",
+                        "name": "delivery",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Delivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "job.Job": {
+            "type": "object",
+            "properties": {
+                "created_on": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_on": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0b1e8f4e-5f3a-4d3c-9c43-6a1f2d9e7b10"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "progress": {
+                    "description": "percentage of the work done",
+                    "type": "integer",
+                    "example": 50
+                },
+                "result": {
+                    "type": "object"
+                },
+                "started_on": {
+                    "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/job.Status"
+                        }
+                    ],
+                    "example": "running"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "ping"
+                }
+            }
+        },
+        "job.Status": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "succeeded",
+                "failed",
+                "canceled"
+            ],
+            "x-enum-varnames": [
+                "StatusQueued",
+                "StatusRunning",
+                "StatusSucceeded",
+                "StatusFailed",
+                "StatusCanceled"
+            ]
+        },
+        "link.Audit": {
+            "type": "object",
+            "properties": {
+                "created_by": {
+                    "description": "the remote address of the client that created the link",
+                    "type": "string",
+                    "example": "127.0.0.1:51234"
+                },
+                "created_on": {
+                    "type": "string"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "12345"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f2c1a9e-7b4d-4e8a-9c61-0d5e2b7f8a13"
+                },
+                "ip": {
+                    "description": "the only client address the link can be redeemed from, if any",
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "redemptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/link.Redemption"
+                    }
+                },
+                "revoked_on": {
+                    "type": "string"
+                },
+                "single_use": {
+                    "description": "the link can be redeemed once only",
+                    "type": "boolean"
+                },
+                "url": {
+                    "description": "the signed URL path and query, only returned when the link is created",
+                    "type": "string",
+                    "example": "/api/v1/site/download/12345?expires=1700000000\u0026link=3f2c1a9e-7b4d-4e8a-9c61-0d5e2b7f8a13\u0026sig=..."
+                },
+                "used_by": {
+                    "description": "the remote address of the client that used a single use link",
+                    "type": "string",
+                    "example": "203.0.113.7:51234"
+                },
+                "used_on": {
+                    "type": "string"
+                }
+            }
+        },
+        "link.Link": {
+            "type": "object",
+            "properties": {
+                "created_by": {
+                    "description": "the remote address of the client that created the link",
+                    "type": "string",
+                    "example": "127.0.0.1:51234"
+                },
+                "created_on": {
+                    "type": "string"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "12345"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f2c1a9e-7b4d-4e8a-9c61-0d5e2b7f8a13"
+                },
+                "ip": {
+                    "description": "the only client address the link can be redeemed from, if any",
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "revoked_on": {
+                    "type": "string"
+                },
+                "single_use": {
+                    "description": "the link can be redeemed once only",
+                    "type": "boolean"
+                },
+                "url": {
+                    "description": "the signed URL path and query, only returned when the link is created",
+                    "type": "string",
+                    "example": "/api/v1/site/download/12345?expires=1700000000\u0026link=3f2c1a9e-7b4d-4e8a-9c61-0d5e2b7f8a13\u0026sig=..."
+                },
+                "used_by": {
+                    "description": "the remote address of the client that used a single use link",
+                    "type": "string",
+                    "example": "203.0.113.7:51234"
+                },
+                "used_on": {
+                    "type": "string"
+                }
+            }
+        },
+        "link.Redemption": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "description": "the remote address of the client that used the link",
+                    "type": "string",
+                    "example": "127.0.0.1:51234"
+                },
+                "error": {
+                    "description": "why the link was refused, empty if the file was served",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "link_id": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "link.Request": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "seconds until the link expires (1-604800, default 3600)",
+                    "type": "integer",
+                    "example": 3600
+                },
+                "ip": {
+                    "description": "binds the link to a client address",
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "single_use": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.APIError": {
+            "type": "object",
+            "properties": {
+                "errorCode": {
+                    "type": "integer"
+                },
+                "errorMessage": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.APIMessage": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "monitor.Check": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "latency measured by the probe in milliseconds",
+                    "type": "number"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "up": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "monitor.History": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "number of checks in the period",
+                    "type": "integer"
+                },
+                "entries": {
+                    "description": "the checks in the period, oldest first, when requested",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/monitor.Check"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "incidents": {
+                    "description": "periods during which the monitor was down, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/monitor.Incident"
+                    }
+                },
+                "latency": {
+                    "description": "latency of the checks that were up, omitted if there were none",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/monitor.LatencyStats"
+                        }
+                    ]
+                },
+                "monitor_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "up": {
+                    "type": "integer"
+                },
+                "uptime_percent": {
+                    "description": "percentage of checks that were up, or 100 if there were no checks",
+                    "type": "number"
+                }
+            }
+        },
+        "monitor.Incident": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "number of checks that were down",
+                    "type": "integer"
+                },
+                "duration_seconds": {
+                    "description": "seconds from the start to the end, or to the end of the period if ongoing",
+                    "type": "number"
+                },
+                "end": {
+                    "description": "the time of the first check that was up again, omitted while the incident is ongoing",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "the error reported by the first check that was down",
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "monitor.LatencyStats": {
+            "type": "object",
+            "properties": {
+                "max_ms": {
+                    "type": "number"
+                },
+                "min_ms": {
+                    "type": "number"
+                },
+                "p50_ms": {
+                    "type": "number"
+                },
+                "p90_ms": {
+                    "type": "number"
+                },
+                "p95_ms": {
+                    "type": "number"
+                },
+                "p99_ms": {
+                    "type": "number"
+                }
+            }
+        },
+        "monitor.Monitor": {
+            "type": "object",
+            "properties": {
+                "created_on": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0b1e8f4e-5f3a-4d3c-9c43-6a1f2d9e7b10"
+                },
+                "interval": {
+                    "description": "seconds between checks (5-86400, default 60)",
+                    "type": "integer",
+                    "example": 60
+                },
+                "last_checked_on": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Swagger UI"
+                },
+                "params": {
+                    "description": "the probe parameters, as for a job of the same type",
+                    "type": "object"
+                },
+                "state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/monitor.State"
+                        }
+                    ],
+                    "example": "up"
+                },
+                "state_changed_on": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "the probe used: \"ping\", \"dns\", \"port\", \"tls\" or \"http\"",
+                    "type": "string",
+                    "example": "http"
+                }
+            }
+        },
+        "monitor.State": {
+            "type": "string",
+            "enum": [
+                "unknown",
+                "up",
+                "down"
+            ],
+            "x-enum-varnames": [
+                "StateUnknown",
+                "StateUp",
+                "StateDown"
+            ]
+        },
+        "note.Change": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "description": "the note after the change, or before it was deleted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/note.Note"
+                        }
+                    ]
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "created, updated or deleted",
+                    "type": "string",
+                    "example": "created"
+                }
+            }
+        },
+        "note.Changes": {
+            "type": "object",
+            "properties": {
+                "more": {
+                    "description": "more changes follow: request them with the token straight away",
+                    "type": "boolean"
+                },
+                "reset": {
+                    "description": "the token was issued for another change sequence, for example before the database was re-created.\nDiscard the local notes and apply the changes from scratch",
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "pass as since to get the changes that follow",
+                    "type": "string"
+                },
+                "tombstones": {
+                    "description": "the notes deleted since the token, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/note.Tombstone"
+                    }
+                },
+                "upserts": {
+                    "description": "the notes created or updated since the token, oldest change first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/note.SyncNote"
+                    }
+                }
+            }
+        },
+        "note.Note": {
+            "type": "object",
+            "properties": {
+                "createdon": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "noteid": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "note.NoteInputV2": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "the version the update was made to. The update fails with 409 Conflict if the note has changed since.\nUnconditional when 0",
+                    "type": "integer"
+                }
+            }
+        },
+        "note.NoteLinksV2": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "description": "the notes collection",
+                    "type": "string"
+                },
+                "self": {
+                    "type": "string"
+                }
+            }
+        },
+        "note.NoteV2": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "links": {
+                    "$ref": "#/definitions/note.NoteLinksV2"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "changes with every update. Send it back with an update to make it conditional",
+                    "type": "integer"
+                }
+            }
+        },
+        "note.SyncChange": {
+            "type": "object",
+            "properties": {
+                "base_version": {
+                    "description": "the version of the note the change was made to, 0 for a new note",
+                    "type": "integer"
+                },
+                "deleted": {
+                    "description": "delete rather than update the note",
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "noteid": {
+                    "description": "the note changed. Creates a note when the note is not known and BaseVersion is 0,\nwith a new ID when empty",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "note.SyncNote": {
+            "type": "object",
+            "properties": {
+                "createdon": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "noteid": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updatedon": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "the position of the latest change to the note in the change sequence. Pass it as the base\nversion of a change to the note",
+                    "type": "integer"
+                }
+            }
+        },
+        "note.SyncRequest": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/note.SyncChange"
+                    }
+                }
+            }
+        },
+        "note.SyncResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/note.SyncResult"
+                    }
+                }
+            }
+        },
+        "note.SyncResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "note": {
+                    "description": "the note after the change was applied, or as it is when the change conflicts with it.\nMissing when the note is deleted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/note.SyncNote"
+                        }
+                    ]
+                },
+                "noteid": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "applied, conflict, rejected or failed",
+                    "type": "string",
+                    "example": "applied"
+                }
+            }
+        },
+        "note.Tombstone": {
+            "type": "object",
+            "properties": {
+                "deletedon": {
+                    "type": "string"
+                },
+                "noteid": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "the position of the deletion in the repository change sequence",
+                    "type": "integer"
+                }
+            }
+        },
+        "site.Certificate": {
+            "type": "object",
+            "properties": {
+                "dns_names": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "expires_in_days": {
+                    "type": "integer"
+                },
+                "ip_addresses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_ca": {
+                    "type": "boolean"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "not_after": {
+                    "type": "string"
+                },
+                "not_before": {
+                    "type": "string"
+                },
+                "serial_number": {
+                    "type": "string"
+                },
+                "signature_algorithm": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "site.DNSResult": {
             "type": "object",
             "properties": {
-                "createdon": {
+                "a": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "aaaa": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cname": {
                     "type": "string"
                 },
-                "description": {
+                "duration_ms": {
+                    "description": "time taken in milliseconds",
+                    "type": "number"
+                },
+                "errors": {
+                    "description": "lookup errors by record type",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "hostname": {
                     "type": "string"
                 },
-                "noteid": {
+                "mx": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/site.MXRecord"
+                    }
+                },
+                "resolver": {
+                    "description": "the DNS server that was queried, or \"system\"",
                     "type": "string"
                 },
-                "title": {
+                "txt": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "site.Download": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+                },
+                "name": {
+                    "description": "the name the file was uploaded with, offered to clients when it is downloaded",
+                    "type": "string",
+                    "example": "report.pdf"
+                },
+                "sha256": {
+                    "description": "hex encoded SHA-256 of the contents",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "uploaded": {
+                    "type": "string"
+                }
+            }
+        },
+        "site.HTTPResult": {
+            "type": "object",
+            "properties": {
+                "body_bytes": {
+                    "description": "number of body bytes read, up to 1 MiB",
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "description": "time taken by all requests in milliseconds",
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "final_url": {
+                    "description": "the URL of the last response, after following redirects",
+                    "type": "string"
+                },
+                "headers": {
+                    "type": "object"
+                },
+                "proto": {
+                    "type": "string"
+                },
+                "redirects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/site.Redirect"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "timing": {
+                    "description": "timing of the last request",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/site.HTTPTiming"
+                        }
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "site.HTTPTiming": {
+            "type": "object",
+            "properties": {
+                "connect_ms": {
+                    "type": "number"
+                },
+                "dns_ms": {
+                    "type": "number"
+                },
+                "first_byte_ms": {
+                    "type": "number"
+                },
+                "tls_ms": {
+                    "type": "number"
+                },
+                "total_ms": {
+                    "type": "number"
+                }
+            }
+        },
+        "site.HistoryEntry": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "client": {
+                    "description": "the remote address of the client that requested the command",
+                    "type": "string",
+                    "example": "127.0.0.1:51234"
+                },
+                "command": {
+                    "type": "string",
+                    "example": "ping"
+                },
+                "duration_ms": {
+                    "description": "run time in milliseconds",
+                    "type": "number"
+                },
+                "exit_code": {
+                    "description": "-1 if the command could not be started or was killed",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "sequence number, increasing with every command run",
+                    "type": "integer",
+                    "example": 1
+                },
+                "output": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "signal": {
+                    "description": "the signal that killed the command, if any",
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "timed_out": {
+                    "type": "boolean"
+                },
+                "truncated": {
+                    "description": "whether output beyond the capture limit was discarded",
+                    "type": "boolean"
+                }
+            }
+        },
+        "site.HistoryPage": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/site.HistoryEntry"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "number of entries matching the filter",
+                    "type": "integer"
+                }
+            }
+        },
+        "site.JobRequest": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "type": "object"
+                },
+                "type": {
+                    "description": "one of \"ping\", \"dns\", \"port\", \"tls\" or \"http\"",
+                    "type": "string",
+                    "example": "ping"
+                }
+            }
+        },
+        "site.MXRecord": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "type": "string"
+                },
+                "pref": {
+                    "type": "integer"
+                }
+            }
+        },
+        "site.MonitorRequest": {
+            "type": "object",
+            "properties": {
+                "interval": {
+                    "description": "seconds between checks (5-86400, default 60)",
+                    "type": "integer",
+                    "example": 60
+                },
+                "name": {
+                    "type": "string",
+                    "example": "API"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "type": {
+                    "description": "one of \"ping\", \"dns\", \"port\", \"tls\" or \"http\"",
+                    "type": "string",
+                    "example": "http"
+                }
+            }
+        },
+        "site.PingResult": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "the address that was pinged, if ping resolved the hostname",
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "packet_loss": {
+                    "description": "percentage of echo requests that were not answered",
+                    "type": "number"
+                },
+                "packets_received": {
+                    "type": "integer"
+                },
+                "packets_sent": {
+                    "type": "integer"
+                },
+                "rtt": {
+                    "description": "round-trip times, omitted when no replies were received",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/site.RTT"
+                        }
+                    ]
+                }
+            }
+        },
+        "site.PortResult": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "the address that accepted the connection",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "time taken to connect in milliseconds",
+                    "type": "number"
+                },
+                "open": {
+                    "type": "boolean"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "site.RTT": {
+            "type": "object",
+            "properties": {
+                "avg_ms": {
+                    "type": "number"
+                },
+                "max_ms": {
+                    "type": "number"
+                },
+                "mdev_ms": {
+                    "type": "number"
+                },
+                "min_ms": {
+                    "type": "number"
+                }
+            }
+        },
+        "site.Redirect": {
+            "type": "object",
+            "properties": {
+                "location": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "url": {
                     "type": "string"
                 }
             }
@@ -460,8 +3345,171 @@ const docTemplate = `{
         "site.Site": {
             "type": "object",
             "properties": {
+                "count": {
+                    "description": "number of echo requests to send (1-20, default 4)",
+                    "type": "integer",
+                    "example": 4
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "interval": {
+                    "description": "seconds between echo requests (0.2-10, default 1)",
+                    "type": "number",
+                    "example": 1
+                },
+                "timeout": {
+                    "description": "seconds to wait for each reply (1-30, default 5)",
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "site.TLSResult": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "alpn": {
+                    "description": "negotiated application protocol, e.g. \"h2\"",
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/site.Certificate"
+                    }
+                },
+                "cipher_suite": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "description": "time taken to connect and complete the handshake in milliseconds",
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
                 "hostname": {
                     "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "server_name": {
+                    "type": "string"
+                },
+                "verified": {
+                    "description": "whether the chain is trusted and valid for the server name",
+                    "type": "boolean"
+                },
+                "verify_error": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "negotiated protocol version, e.g. \"TLS 1.3\"",
+                    "type": "string"
+                }
+            }
+        },
+        "webhook.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_on": {
+                    "type": "string"
+                },
+                "delivered_on": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string",
+                    "example": "note.created"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_attempt": {
+                    "type": "string"
+                },
+                "next_attempt": {
+                    "description": "when the delivery is next attempted, while pending",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "response_status": {
+                    "description": "the status code returned by the last attempt, if it got a response",
+                    "type": "integer"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/webhook.Status"
+                        }
+                    ],
+                    "example": "delivered"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhook.Status": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "delivered",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "StatusPending",
+                "StatusDelivered",
+                "StatusFailed"
+            ]
+        },
+        "webhook.Subscription": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "deliveries are only made to active webhooks",
+                    "type": "boolean"
+                },
+                "created_on": {
+                    "type": "string"
+                },
+                "events": {
+                    "description": "the event types delivered, all of them when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "note.created",
+                        "note.deleted"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "8c4b0f2e-1d7a-4a55-b7f3-3e9d2c6a1b04"
+                },
+                "secret": {
+                    "description": "the key of the HMAC-SHA256 signature of each delivery. Generated when not given,\nand only returned when the webhook is created",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/notes"
                 }
             }
         }
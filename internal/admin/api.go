@@ -0,0 +1,201 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/middleware"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// Sources holds the components inspected and controlled through the admin API.
+// Endpoints for nil components are not registered.
+type Sources struct {
+	Version     string
+	Repository  note.Repository
+	Levels      *log.Levels
+	Ring        *log.Ring
+	RateLimiter *middleware.RateLimit
+}
+
+// AdminHandler organizes the HTTP handler functions of the admin API
+type AdminHandler struct {
+	logger  log.Logger
+	cfg     *config.Config
+	sources Sources
+	started time.Time
+}
+
+// MakeHTTPHandler builds the admin API handler. Every request must present the configured admin token,
+// either as a bearer token in the Authorization header or in the X-Admin-Token header.
+func MakeHTTPHandler(logger log.Logger, cfg *config.Config, sources Sources) http.Handler {
+
+	// Initialize handlers
+	adminHandler := &AdminHandler{
+		logger:  logger,
+		cfg:     cfg,
+		sources: sources,
+		started: time.Now(),
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /admin/info", adminHandler.GetInfo)
+	router.HandleFunc("GET /admin/config", adminHandler.GetConfig)
+	router.HandleFunc("GET /admin/runtime", adminHandler.GetRuntime)
+	if sources.Repository != nil {
+		router.HandleFunc("GET /admin/repository", adminHandler.GetRepositoryStats)
+	}
+	if sources.RateLimiter != nil {
+		router.HandleFunc("GET /admin/ratelimit", adminHandler.GetRateLimit)
+		router.HandleFunc("PUT /admin/ratelimit", adminHandler.PutRateLimit)
+	}
+	if sources.Levels != nil {
+		router.Handle("/admin/log/level", sources.Levels)
+	}
+	if sources.Ring != nil {
+		router.Handle("GET /admin/logs", sources.Ring)
+		router.HandleFunc("GET /admin/logs/stream", sources.Ring.ServeStream)
+	}
+
+	router.HandleFunc("/debug/pprof/", pprof.Index)
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return adminHandler.authenticate(router)
+}
+
+// authenticate rejects requests that don't carry the admin token.
+func (h *AdminHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Admin-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if h.cfg.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.AdminToken)) != 1 {
+			h.logger.Warnf("Rejected unauthenticated admin request from %s to %s", r.RemoteAddr, r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "invalid or missing admin token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Info describes the running build.
+type Info struct {
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	Module    string            `json:"module,omitempty"`
+	Settings  map[string]string `json:"build_settings,omitempty"`
+	Started   time.Time         `json:"started"`
+	Uptime    string            `json:"uptime"`
+}
+
+// GetInfo returns the version and build information of the running server.
+func (h *AdminHandler) GetInfo(w http.ResponseWriter, r *http.Request) {
+	info := Info{
+		Version:   h.sources.Version,
+		GoVersion: runtime.Version(),
+		Started:   h.started,
+		Uptime:    time.Since(h.started).Round(time.Second).String(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Module = bi.Main.Path
+		info.Settings = make(map[string]string)
+		for _, s := range bi.Settings {
+			if strings.HasPrefix(s.Key, "vcs.") || s.Key == "GOOS" || s.Key == "GOARCH" || s.Key == "CGO_ENABLED" {
+				info.Settings[s.Key] = s.Value
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// GetConfig returns the effective configuration with secrets masked.
+func (h *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.cfg.Redacted())
+}
+
+// RuntimeStats describes the goroutines and memory of the running server.
+type RuntimeStats struct {
+	Goroutines   int    `json:"goroutines"`
+	CPUs         int    `json:"cpus"`
+	GOMAXPROCS   int    `json:"gomaxprocs"`
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`
+	HeapInuse    uint64 `json:"heap_inuse_bytes"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`
+	Sys          uint64 `json:"sys_bytes"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"gc_pause_total_ns"`
+}
+
+// GetRuntime returns goroutine and memory statistics.
+func (h *AdminHandler) GetRuntime(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	writeJSON(w, http.StatusOK, RuntimeStats{
+		Goroutines:   runtime.NumGoroutine(),
+		CPUs:         runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapObjects:  m.HeapObjects,
+		TotalAlloc:   m.TotalAlloc,
+		Sys:          m.Sys,
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+	})
+}
+
+// GetRepositoryStats returns the note repository statistics.
+func (h *AdminHandler) GetRepositoryStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.sources.Repository.Stats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// GetRateLimit returns the state of the API rate limiter.
+func (h *AdminHandler) GetRateLimit(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.sources.RateLimiter.State())
+}
+
+// PutRateLimit changes the limit and burst of the API rate limiter, e.g. {"limit": 5, "burst": 100}.
+func (h *AdminHandler) PutRateLimit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Limit float64 `json:"limit"`
+		Burst int     `json:"burst"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Limit <= 0 || req.Burst <= 0 {
+		http.Error(w, "limit and burst must be positive", http.StatusBadRequest)
+		return
+	}
+	h.sources.RateLimiter.Set(rate.Limit(req.Limit), req.Burst)
+	h.logger.Infof("Rate limit changed to %g requests per second with bursts of %d", req.Limit, req.Burst)
+	writeJSON(w, http.StatusOK, h.sources.RateLimiter.State())
+}
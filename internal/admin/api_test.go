@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/middleware"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func newTestHandler(t *testing.T) http.Handler {
+	logger, _ := log.NewForTest()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	cfg := &config.Config{
+		ServerPort: 8080,
+		AdminPort:  8081,
+		AdminToken: "admin-secret-token",
+		DSN:        "postgres://app:hunter2@db/app",
+	}
+	return MakeHTTPHandler(logger, cfg, Sources{
+		Version:     "1.2.3",
+		Repository:  repo,
+		Levels:      log.NewLevels(zapcore.InfoLevel),
+		RateLimiter: middleware.NewRateLimit(1, 10),
+	})
+}
+
+func serve(handler http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestAdminHandler_Authentication(t *testing.T) {
+	handler := newTestHandler(t)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/admin/info", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/admin/info", "wrong", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/debug/pprof/", "", "").Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/info", nil)
+	req.Header.Set("X-Admin-Token", "admin-secret-token")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"version":"1.2.3"`)
+}
+
+func TestAdminHandler_Endpoints(t *testing.T) {
+	handler := newTestHandler(t)
+	token := "admin-secret-token"
+
+	res := serve(handler, http.MethodGet, "/admin/config", token, "")
+	require.Equal(t, http.StatusOK, res.Code)
+	var cfg map[string]interface{}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &cfg))
+	assert.Equal(t, log.Mask, cfg["dsn"])
+	assert.Equal(t, log.Mask, cfg["admin_token"])
+	assert.Equal(t, float64(8081), cfg["admin_port"])
+
+	res = serve(handler, http.MethodGet, "/admin/repository", token, "")
+	assert.JSONEq(t, `{"notes":2}`, res.Body.String())
+
+	res = serve(handler, http.MethodGet, "/admin/runtime", token, "")
+	assert.Contains(t, res.Body.String(), `"goroutines"`)
+
+	res = serve(handler, http.MethodPut, "/admin/ratelimit", token, `{"limit":5,"burst":20}`)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"burst":20`)
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodPut, "/admin/ratelimit", token, `{"limit":0}`).Code)
+
+	res = serve(handler, http.MethodPut, "/admin/log/level", token, `{"level":"debug"}`)
+	assert.JSONEq(t, `{"level":"debug"}`, res.Body.String())
+
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/debug/pprof/", token, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(handler, http.MethodGet, "/admin/logs", token, "").Code)
+}
+
+func TestStart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	_, err := Start(logger, &config.Config{ServerPort: 8080, AdminPort: 8081}, http.NotFoundHandler())
+	assert.ErrorIs(t, err, ErrDisabled)
+	_, err = Start(logger, &config.Config{ServerPort: 8080, AdminPort: 8080, AdminToken: "t"}, http.NotFoundHandler())
+	assert.Error(t, err)
+
+	srv, err := Start(logger, &config.Config{ServerPort: 8080, AdminHost: "127.0.0.1", AdminPort: 0, AdminToken: "t"}, http.NotFoundHandler())
+	require.NoError(t, err)
+	assert.NoError(t, srv.Close())
+}
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// ErrDisabled is returned by Start when no admin token is configured.
+var ErrDisabled = errors.New("admin API disabled: no admin token configured")
+
+// Start serves the admin API on its own listener, bound to the configured admin host and port, and
+// returns the server so that the caller can shut it down. The admin port must differ from the server port.
+func Start(logger log.Logger, cfg *config.Config, handler http.Handler) (*http.Server, error) {
+	if cfg.AdminToken == "" {
+		return nil, ErrDisabled
+	}
+	if cfg.AdminPort == cfg.ServerPort {
+		return nil, fmt.Errorf("admin port %d must differ from the server port", cfg.AdminPort)
+	}
+	addr := net.JoinHostPort(cfg.AdminHost, strconv.Itoa(cfg.AdminPort))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logger.Infof("Admin API listening on %s", ln.Addr())
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("admin API stopped: %s", err)
+		}
+	}()
+	return srv, nil
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/fortify-presales/insecure-go-api/pkg/log"
 	"github.com/qiangxue/go-env"
@@ -9,20 +13,80 @@ import (
 )
 
 const (
-	defaultServerPort         = 8080
-	defaultJWTExpirationHours = 72
+	defaultServerPort          = 8080
+	defaultAdminHost           = "127.0.0.1"
+	defaultAdminPort           = 8081
+	defaultJWTExpirationHours  = 72
+	defaultQueryTimeoutSeconds = 5
+	defaultJobWorkers          = 4
+	defaultJobQueueSize        = 100
+	defaultDataDir             = "."
+	defaultDatabase            = "sqlite.db"
+	defaultCommandHistory      = "command_history.jsonl"
+	defaultCommandTimeout      = 60
+	defaultCommandMaxOutputKB  = 64
+	defaultCommandConcurrency  = 4
+	defaultDownloadsDir        = "downloads"
+	defaultMaxUploadSizeMB     = 10
+	defaultNoteEventBacklog    = 256
 )
 
 // Config represents an application configuration.
 type Config struct {
 	// the server port. Defaults to 8080
 	ServerPort int `yaml:"server_port" env:"SERVER_PORT"`
+	// the interface the admin API listens on. Defaults to 127.0.0.1 so that it is only reachable locally
+	AdminHost string `yaml:"admin_host" env:"ADMIN_HOST"`
+	// the admin API port, which must differ from the server port. Defaults to 8081
+	AdminPort int `yaml:"admin_port" env:"ADMIN_PORT"`
+	// the token required to call the admin API. The admin API is disabled when empty
+	AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN,secret"`
 	// the data source name (DSN) for connecting to the database. required.
 	DSN string `yaml:"dsn" env:"DSN,secret"`
 	// JWT signing key. required.
 	JWTSigningKey string `yaml:"jwt_signing_key" env:"JWT_SIGNING_KEY,secret"`
 	// JWT expiration in hours. Defaults to 72 hours (3 days)
 	JWTExpiration int `yaml:"jwt_expiration" env:"JWT_EXPIRATION"`
+	// database query timeout in seconds. Defaults to 5 seconds
+	QueryTimeout int `yaml:"query_timeout" env:"QUERY_TIMEOUT"`
+	// the directory owning all on-disk state. Relative paths in the configuration are resolved against it.
+	// Defaults to the working directory
+	DataDir string `yaml:"data_dir" env:"DATA_DIR"`
+	// the SQLite database file, re-created on startup. Defaults to sqlite.db
+	Database string `yaml:"database" env:"DATABASE"`
+	// number of site diagnostic jobs run at the same time. Defaults to 4
+	JobWorkers int `yaml:"job_workers" env:"JOB_WORKERS"`
+	// number of site diagnostic jobs waiting for a worker before new jobs are rejected. Defaults to 100
+	JobQueueSize int `yaml:"job_queue_size" env:"JOB_QUEUE_SIZE"`
+	// the file the commands run by the site API are appended to. Defaults to command_history.jsonl
+	CommandHistory string `yaml:"command_history" env:"COMMAND_HISTORY"`
+	// the longest a command run by the site API may take, in seconds. Defaults to 60 seconds
+	CommandTimeout int `yaml:"command_timeout" env:"COMMAND_TIMEOUT"`
+	// the output captured from each command, in KB. Defaults to 64 KB
+	CommandMaxOutput int `yaml:"command_max_output" env:"COMMAND_MAX_OUTPUT"`
+	// the number of commands the site API runs at the same time. Defaults to 4
+	CommandConcurrency int `yaml:"command_concurrency" env:"COMMAND_CONCURRENCY"`
+	// the directory holding the downloads catalogue. Defaults to downloads
+	DownloadsDir string `yaml:"downloads_dir" env:"DOWNLOADS_DIR"`
+	// the largest file that can be uploaded to the downloads catalogue, in MB. Defaults to 10 MB
+	MaxUploadSize int `yaml:"max_upload_size" env:"MAX_UPLOAD_SIZE"`
+	// the key signing download links. When set, files can only be downloaded through signed links; signed links are disabled when empty
+	DownloadSigningKey string `yaml:"download_signing_key" env:"DOWNLOAD_SIGNING_KEY,secret"`
+	// the number of note changes kept for clients resuming the note change stream. Defaults to 256
+	NoteEventBacklog int `yaml:"note_event_backlog" env:"NOTE_EVENT_BACKLOG"`
+	// when version 1 of the notes API was deprecated, announced in the Deprecation header of its responses.
+	// An RFC 3339 date or time, such as 2026-06-30. Not announced when empty
+	NotesV1Deprecation string `yaml:"notes_v1_deprecation" env:"NOTES_V1_DEPRECATION"`
+	// when version 1 of the notes API will be removed, announced in the Sunset header of its responses.
+	// An RFC 3339 date or time. Not announced when empty
+	NotesV1Sunset string `yaml:"notes_v1_sunset" env:"NOTES_V1_SUNSET"`
+	// check the responses of the API against its OpenAPI document, logging the differences. Requests are always
+	// checked. Meant for testing: each response is kept until it has been checked. Defaults to false
+	ValidateResponses bool `yaml:"validate_responses" env:"VALIDATE_RESPONSES"`
+	// the URL monitor state changes are posted to as JSON. State changes are only logged when empty
+	MonitorWebhookURL string `yaml:"monitor_webhook_url" env:"MONITOR_WEBHOOK_URL"`
+	// logging configuration: level, encoding, per-package levels and sampling. Defaults to info level JSON logs
+	Log log.Config `yaml:"log" env:"LOG"`
 }
 
 // Validate validates the application configuration.
@@ -37,8 +101,22 @@ type Config struct {
 func Load(file string, logger log.Logger) (*Config, error) {
 	// default config
 	c := Config{
-		ServerPort:    defaultServerPort,
-		JWTExpiration: defaultJWTExpirationHours,
+		ServerPort:         defaultServerPort,
+		AdminHost:          defaultAdminHost,
+		AdminPort:          defaultAdminPort,
+		JWTExpiration:      defaultJWTExpirationHours,
+		QueryTimeout:       defaultQueryTimeoutSeconds,
+		JobWorkers:         defaultJobWorkers,
+		JobQueueSize:       defaultJobQueueSize,
+		DataDir:            defaultDataDir,
+		Database:           defaultDatabase,
+		CommandHistory:     defaultCommandHistory,
+		CommandTimeout:     defaultCommandTimeout,
+		CommandMaxOutput:   defaultCommandMaxOutputKB,
+		CommandConcurrency: defaultCommandConcurrency,
+		DownloadsDir:       defaultDownloadsDir,
+		MaxUploadSize:      defaultMaxUploadSizeMB,
+		NoteEventBacklog:   defaultNoteEventBacklog,
 	}
 
 	// load from YAML config file
@@ -62,3 +140,92 @@ func Load(file string, logger log.Logger) (*Config, error) {
 
 	return &c, err
 }
+
+// dataDirPerm is the mode of the directories created for the data directory
+const dataDirPerm = 0o750
+
+// DataPath returns the location of a file or directory in the data directory. Absolute paths are returned unchanged.
+func (c Config) DataPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.DataDir, path)
+}
+
+// PrepareDataDir makes the data directory absolute, so that it no longer depends on the working directory,
+// and creates it and the downloads directory if they are missing.
+func (c *Config) PrepareDataDir() error {
+	dir, err := filepath.Abs(c.DataDir)
+	if err != nil {
+		return err
+	}
+	c.DataDir = dir
+	for _, path := range []string{dir, c.DataPath(c.DownloadsDir)} {
+		if err := os.MkdirAll(path, dataDirPerm); err != nil {
+			return fmt.Errorf("creating data directory: %w", err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("data directory %s is not a directory", path)
+		}
+	}
+	return nil
+}
+
+// Redacted returns the configuration as a map keyed by the YAML field names, suitable for display.
+// Fields tagged as secret are masked and other strings have credentials such as DSN passwords masked.
+func (c Config) Redacted() map[string]interface{} {
+	return redactStruct(reflect.ValueOf(c), log.NewRedactor())
+}
+
+func redactStruct(v reflect.Value, redactor *log.Redactor) map[string]interface{} {
+	m := make(map[string]interface{}, v.NumField())
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if !ft.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(ft.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = ft.Name
+		}
+		if strings.HasSuffix(ft.Tag.Get("env"), ",secret") {
+			if v.Field(i).IsZero() {
+				m[name] = ""
+			} else {
+				m[name] = log.Mask
+			}
+			continue
+		}
+		m[name] = redactValue(v.Field(i), redactor)
+	}
+	return m
+}
+
+func redactValue(v reflect.Value, redactor *log.Redactor) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem(), redactor)
+	case reflect.Struct:
+		return redactStruct(v, redactor)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i] = redactValue(v.Index(i), redactor)
+		}
+		return s
+	case reflect.String:
+		return redactor.String(v.String())
+	}
+	return v.Interface()
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_PrepareDataDir(t *testing.T) {
+	root := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	c := Config{DataDir: "data", DownloadsDir: "downloads"}
+	require.NoError(t, c.PrepareDataDir())
+	assert.Equal(t, filepath.Join(root, "data"), c.DataDir)
+	fi, err := os.Stat(filepath.Join(root, "data", "downloads"))
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Zero(t, fi.Mode().Perm()&0o007, "not accessible to others")
+
+	assert.Equal(t, filepath.Join(root, "data", "sqlite.db"), c.DataPath("sqlite.db"))
+	assert.Equal(t, "/var/lib/app/history.jsonl", c.DataPath("/var/lib/app/history.jsonl"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), nil, 0o600))
+	c = Config{DataDir: filepath.Join(root, "file")}
+	assert.Error(t, c.PrepareDataDir())
+}
@@ -8,27 +8,68 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 
 	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/health"
 	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/internal/openapi"
 	"github.com/fortify-presales/insecure-go-api/internal/site"
-
+	"github.com/fortify-presales/insecure-go-api/internal/webhook"
 )
 
+// Components are the optional parts of the API. The webhooks API and the note change stream are only
+// registered when they are set.
+type Components struct {
+	Site        site.Services
+	Webhooks    *webhook.Manager
+	NoteChanges *note.Feed
+	// run by /readyz
+	Readiness health.Checks
+}
+
 // BuildHandler sets up the HTTP routing and builds an HTTP handler.
-func BuildHandler(logger log.Logger, cfg *config.Config, repo note.Repository) http.Handler {
+func BuildHandler(logger log.Logger, cfg *config.Config, repo note.Repository, components Components) http.Handler {
 	router := http.NewServeMux()
 
+	router.HandleFunc("GET /healthz", health.Live)
+	router.Handle("GET /readyz", health.Ready(components.Readiness))
+
 	router.Handle("GET /swagger/", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),                        // The url pointing to API definition
 		httpSwagger.DefaultModelsExpandDepth(httpSwagger.HideModel), // Models will not be expanded
 	))
 
-	notesHandler := note.MakeHTTPHandler(repo)
-	router.Handle("/api/v1/notes", notesHandler)
-	router.Handle("/api/v1/notes/", notesHandler)
+	// each version of the notes API is mounted under its own prefix, sharing the repository
+	deprecation, err := notesV1Deprecation(cfg)
+	if err != nil {
+		logger.Errorf("Not announcing the deprecation of version 1 of the notes API: %s", err)
+	}
+	noteVersions := []struct {
+		prefix  string
+		handler http.Handler
+	}{
+		{"/api/v1/notes", deprecation.Wrap(note.MakeHTTPHandler(repo, components.NoteChanges))},
+		{"/api/v2/notes", note.MakeV2HTTPHandler(repo)},
+	}
+	for _, v := range noteVersions {
+		router.Handle(v.prefix, v.handler)
+		router.Handle(v.prefix+"/", v.handler)
+	}
+
+	if components.Webhooks != nil {
+		webhookHandler := webhook.MakeHTTPHandler(logger.Named("webhook"), components.Webhooks)
+		router.Handle("/api/v1/webhooks", webhookHandler)
+		router.Handle("/api/v1/webhooks/", webhookHandler)
+	}
 
-	siteHandler := site.MakeHTTPHandler(logger, cfg);
+	siteHandler := site.MakeHTTPHandler(logger.Named("site"), cfg, components.Site)
 	router.Handle("/api/v1/site", siteHandler)
 	router.Handle("/api/v1/site/", siteHandler)
 
-	return router
-}
\ No newline at end of file
+	// check the traffic against the OpenAPI document
+	doc, err := openapi.Load()
+	if err != nil {
+		logger.Errorf("Not serving the OpenAPI document: %s", err)
+		return router
+	}
+	router.Handle("GET /openapi.json", doc)
+	return doc.Middleware(logger.Named("openapi"), cfg.ValidateResponses)(router)
+}
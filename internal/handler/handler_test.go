@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/health"
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/internal/link"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/internal/site"
+	"github.com/fortify-presales/insecure-go-api/internal/webhook"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestBuildHandler_NoteVersions(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	serve := func(cfg *config.Config, method, target, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		BuildHandler(logger, cfg, repo, Components{}).ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return res
+	}
+
+	res := serve(&config.Config{}, http.MethodGet, "/api/v1/notes", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"noteid"`)
+	assert.Empty(t, res.Header().Get("Deprecation"), "only announced when configured")
+
+	res = serve(&config.Config{}, http.MethodPost, "/api/v2/notes", `{"title":"zap"}`)
+	require.Equal(t, http.StatusCreated, res.Code)
+	res = serve(&config.Config{}, http.MethodGet, "/api/v1/notes", "")
+	assert.Contains(t, res.Body.String(), `"zap"`, "the versions share the repository")
+
+	cfg := &config.Config{NotesV1Deprecation: "2026-06-30", NotesV1Sunset: "2027-01-01T00:00:00Z"}
+	res = serve(cfg, http.MethodGet, "/api/v1/notes", "")
+	assert.Equal(t, "@1782777600", res.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", res.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/notes>; rel="successor-version"`, res.Header().Get("Link"))
+	res = serve(cfg, http.MethodGet, "/api/v2/notes", "")
+	assert.Empty(t, res.Header().Get("Deprecation"))
+
+	res = serve(&config.Config{NotesV1Deprecation: "soon"}, http.MethodGet, "/api/v1/notes", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Empty(t, res.Header().Get("Deprecation"), "an invalid date is logged and ignored")
+}
+
+func TestBuildHandler_OpenAPI(t *testing.T) {
+	logger, logs := log.NewForTest()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	handler := BuildHandler(logger, &config.Config{ValidateResponses: true}, repo, Components{})
+	serve := func(method, target, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(http.MethodGet, "/openapi.json", "", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"openapi": "3.1.0"`)
+
+	res = serve(http.MethodPost, "/api/v2/notes", "", `{"title":"zap","description":"zap is a logging package"}`)
+	require.Equal(t, http.StatusCreated, res.Code)
+	id := strings.TrimPrefix(res.Header().Get("Location"), "/api/v2/notes/")
+	res = serve(http.MethodPost, "/api/v2/notes", "", `{"title":42}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.JSONEq(t, `{"ErrorCode":400,"ErrorMessage":"the request does not match the API description","Errors":[{"In":"body","Name":"/title","Message":"expecting string, got number"}]}`, res.Body.String())
+
+	// the responses of the handlers match the document
+	for _, r := range []struct{ method, target, accept, body string }{
+		{http.MethodGet, "/healthz", "", ""},
+		{http.MethodGet, "/readyz", "", ""},
+		{http.MethodGet, "/api/v1/notes", "", ""},
+		{http.MethodGet, "/api/v1/notes/" + id, "", ""},
+		{http.MethodGet, "/api/v1/notes/" + id, "image/png", ""},
+		{http.MethodPost, "/api/v1/notes", "", `{"title":"cobra"}`},
+		{http.MethodPost, "/api/v1/notes", "", `{"title":"cobra"}`},
+		{http.MethodPut, "/api/v1/notes/" + id, "", `{"title":"zap","description":"updated"}`},
+		{http.MethodGet, "/api/v1/notes/changes?limit=2", "", ""},
+		{http.MethodGet, "/api/v1/notes/changes?since=garbage", "", ""},
+		{http.MethodPost, "/api/v1/notes/sync", "", `{"changes":[{"noteid":"` + id + `","base_version":1,"title":"zap"},{"title":"slog"}]}`},
+		{http.MethodGet, "/api/v2/notes", "", ""},
+		{http.MethodGet, "/api/v2/notes?keywords=fiber", "", ""},
+		{http.MethodGet, "/api/v2/notes/" + id, "", ""},
+		{http.MethodGet, "/api/v2/notes/missing", "", ""},
+		{http.MethodPut, "/api/v2/notes/" + id, "", `{"title":"zap","version":1}`},
+		{http.MethodPut, "/api/v2/notes/" + id, "", `{"title":"zap","description":"v2"}`},
+		{http.MethodPost, "/api/v2/notes", "", `{"title":"zap"}`},
+		{http.MethodGet, "/api/v2/notes", "application/xml", ""},
+		{http.MethodDelete, "/api/v2/notes/" + id, "", ""},
+		{http.MethodDelete, "/api/v2/notes/" + id, "", ""},
+	} {
+		serve(r.method, r.target, r.accept, r.body)
+	}
+	for _, entry := range logs.FilterMessageSnippet("does not match the API description").All() {
+		t.Error(entry.Message)
+	}
+}
+
+func TestBuildHandler_OpenAPISite(t *testing.T) {
+	logger, logs := log.NewForTest()
+	ctx := context.Background()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	jobStore, err := job.NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+	monitorStore, err := monitor.NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+	linkStore, err := link.NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+	webhookStore, err := webhook.NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+	history, err := site.NewHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { history.Close() })
+	commands := site.NewCommandRunner(site.CommandRunnerConfig{Dir: t.TempDir()})
+	jobs := job.NewManager(logger, jobStore, 1, 10, site.Runners(commands))
+	t.Cleanup(jobs.Close)
+	monitors := monitor.NewManager(logger, monitorStore, site.Probes(commands))
+	t.Cleanup(monitors.Close)
+	handler := BuildHandler(logger, &config.Config{ValidateResponses: true}, repo, Components{
+		Site: site.Services{
+			Jobs:      jobs,
+			History:   history,
+			Monitors:  monitors,
+			Commands:  commands,
+			Downloads: site.NewDownloads(t.TempDir(), 1024),
+			Links:     link.NewManager(logger, []byte("secret"), linkStore),
+		},
+		Webhooks: webhook.NewManager(logger, webhookStore, nil),
+	})
+	serve := func(method, target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+	created := func(res *httptest.ResponseRecorder) map[string]interface{} {
+		require.Contains(t, []int{http.StatusCreated, http.StatusAccepted}, res.Code, res.Body.String())
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &v))
+		return v
+	}
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	part, _ := w.CreateFormFile("file", "notes.txt")
+	part.Write([]byte("zap, cobra, slog\n"))
+	w.Close()
+	file := created(serve(http.MethodPost, "/api/v1/site/downloads", w.FormDataContentType(), form.String()))["id"].(string)
+	l := created(serve(http.MethodPost, "/api/v1/site/downloads/"+file+"/links", "", `{"single_use":true}`))
+	res := serve(http.MethodGet, l["url"].(string), "", "")
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	jobID := created(serve(http.MethodPost, "/api/v1/site/jobs", "", `{"type":"port","params":{"hostname":"127.0.0.1","port":1,"timeout":1}}`))["id"].(string)
+	monitorID := created(serve(http.MethodPost, "/api/v1/site/monitors", "", `{"name":"closed","type":"port","params":{"hostname":"127.0.0.1","port":1,"timeout":1}}`))["id"].(string)
+	webhookID := created(serve(http.MethodPost, "/api/v1/webhooks", "", `{"url":"http://127.0.0.1:1/hook","events":["note.created"],"active":true}`))["id"].(string)
+
+	// the responses of the handlers match the document
+	for _, r := range []struct{ method, target, contentType, body string }{
+		{http.MethodGet, "/api/v1/site/ping", "", ""},
+		{http.MethodGet, "/api/v1/site/port?hostname=127.0.0.1&port=1&timeout=1", "", ""},
+		{http.MethodGet, "/api/v1/site/port?hostname=127.0.0.1&port=0", "", ""},
+		{http.MethodGet, "/api/v1/site/tls?hostname=127.0.0.1&port=1&timeout=1", "", ""},
+		{http.MethodGet, "/api/v1/site/http?url=http://127.0.0.1:1/&timeout=1", "", ""},
+		{http.MethodGet, "/api/v1/site/http?url=ftp://127.0.0.1/", "", ""},
+		{http.MethodGet, "/api/v1/site/dns?hostname=localhost&type=TXT&resolver=127.0.0.1:1&timeout=1", "", ""},
+		{http.MethodGet, "/api/v1/site/downloads", "", ""},
+		{http.MethodGet, "/api/v1/site/downloads/" + file, "", ""},
+		{http.MethodGet, "/api/v1/site/downloads/missing", "", ""},
+		{http.MethodGet, "/api/v1/site/download/" + file, "", ""},
+		{http.MethodGet, l["url"].(string), "", ""},
+		{http.MethodGet, "/api/v1/site/links/" + l["id"].(string), "", ""},
+		{http.MethodDelete, "/api/v1/site/links/" + l["id"].(string), "", ""},
+		{http.MethodGet, "/api/v1/site/links/missing", "", ""},
+		{http.MethodGet, "/api/v1/site/jobs/" + jobID, "", ""},
+		{http.MethodDelete, "/api/v1/site/jobs/" + jobID, "", ""},
+		{http.MethodGet, "/api/v1/site/jobs/missing", "", ""},
+		{http.MethodPost, "/api/v1/site/jobs", "", `{"type":"exec"}`},
+		{http.MethodGet, "/api/v1/site/history?limit=10", "", ""},
+		{http.MethodGet, "/api/v1/site/history?since=garbage", "", ""},
+		{http.MethodGet, "/api/v1/site/monitors", "", ""},
+		{http.MethodGet, "/api/v1/site/monitors/" + monitorID, "", ""},
+		{http.MethodGet, "/api/v1/site/monitors/" + monitorID + "/history?entries=true", "", ""},
+		{http.MethodDelete, "/api/v1/site/monitors/" + monitorID, "", ""},
+		{http.MethodGet, "/api/v1/site/monitors/missing", "", ""},
+		{http.MethodGet, "/api/v1/webhooks", "", ""},
+		{http.MethodGet, "/api/v1/webhooks/" + webhookID, "", ""},
+		{http.MethodPut, "/api/v1/webhooks/" + webhookID, "", `{"url":"http://127.0.0.1:1/hook","active":false}`},
+		{http.MethodPut, "/api/v1/webhooks/" + webhookID, "", `{"url":"http://127.0.0.1:1/hook","events":["note.read"]}`},
+		{http.MethodGet, "/api/v1/webhooks/" + webhookID + "/deliveries", "", ""},
+		{http.MethodPost, "/api/v1/webhooks/" + webhookID + "/deliveries/missing/redeliver", "", ""},
+		{http.MethodDelete, "/api/v1/webhooks/" + webhookID, "", ""},
+		{http.MethodGet, "/api/v1/webhooks/missing", "", ""},
+	} {
+		serve(r.method, r.target, r.contentType, r.body)
+	}
+	for _, entry := range logs.FilterMessageSnippet("does not match the API description").All() {
+		t.Error(entry.Message)
+	}
+}
+
+func TestBuildHandler_DownloadsDir(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	cfg := &config.Config{DataDir: t.TempDir(), DownloadsDir: "files"}
+	require.NoError(t, cfg.PrepareDataDir())
+	handler := BuildHandler(logger, cfg, repo, Components{
+		Readiness: health.Checks{
+			"downloads": health.Writable(cfg.DataPath(cfg.DownloadsDir)),
+		},
+	})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	part, _ := w.CreateFormFile("file", "notes.txt")
+	part.Write([]byte("zap, cobra, slog\n"))
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/downloads", &form)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	res := serve(req)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var download site.Download
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &download))
+	assert.FileExists(t, filepath.Join(cfg.DataDir, "files", download.ID), "the site API keeps the downloads in the configured directory")
+	assert.Equal(t, http.StatusOK, serve(httptest.NewRequest(http.MethodGet, "/readyz", nil)).Code)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(cfg.DataDir, "files")))
+	assert.Equal(t, http.StatusServiceUnavailable, serve(httptest.NewRequest(http.MethodGet, "/readyz", nil)).Code)
+}
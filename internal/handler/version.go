@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+)
+
+// Deprecation announces in the headers of its responses that an API version is deprecated
+type Deprecation struct {
+	// when the version was deprecated, sent in the Deprecation header (RFC 9745)
+	Since time.Time
+	// when the version will be removed, sent in the Sunset header (RFC 8594)
+	Sunset time.Time
+	// the path of the version replacing it, sent in a Link header with the successor-version relation
+	Successor string
+}
+
+// Wrap adds the deprecation headers to the responses of next, unless neither date is set.
+func (d Deprecation) Wrap(next http.Handler) http.Handler {
+	if d.Since.IsZero() && d.Sunset.IsZero() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.Since.IsZero() {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		}
+		if !d.Sunset.IsZero() {
+			w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// notesV1Deprecation reads the deprecation of version 1 of the notes API from the configuration.
+func notesV1Deprecation(cfg *config.Config) (Deprecation, error) {
+	d := Deprecation{Successor: "/api/v2/notes"}
+	var err error
+	if d.Since, err = parseDate(cfg.NotesV1Deprecation); err != nil {
+		return Deprecation{}, fmt.Errorf("invalid notes_v1_deprecation: %w", err)
+	}
+	if d.Sunset, err = parseDate(cfg.NotesV1Sunset); err != nil {
+		return Deprecation{}, fmt.Errorf("invalid notes_v1_sunset: %w", err)
+	}
+	return d, nil
+}
+
+// parseDate parses an RFC 3339 date or time. An empty string is the zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds the time taken by all the readiness checks together
+const checkTimeout = 2 * time.Second
+
+// Check reports why a dependency of the service is not usable, or nil if it is.
+type Check func(ctx context.Context) error
+
+// Checks are the readiness checks of the service, by name
+type Checks map[string]Check
+
+// Report is the outcome of the readiness checks
+type Report struct {
+	// "ok" if every check passed, otherwise "unavailable"
+	Status string `json:"status" example:"ok"`
+	// "ok" or the error reported, by check name
+	Checks map[string]string `json:"checks"`
+}
+
+// Run runs the checks concurrently.
+func (c Checks) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	report := Report{Status: "ok", Checks: make(map[string]string, len(c))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range c {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := "ok"
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result != "ok" {
+				report.Status = "unavailable"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// Live answers every request with 200 OK while the process is serving requests.
+func Live(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, Report{Status: "ok", Checks: map[string]string{}})
+}
+
+// Ready returns a handler answering 200 OK when every check passes and 503 Service Unavailable otherwise.
+func Ready(checks Checks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, checks.Run(r.Context()))
+	}
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Writable returns a check that dir is a directory in which files can be created.
+func Writable(dir string) Check {
+	return func(ctx context.Context) error {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		file, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		file.Close()
+		return os.Remove(file.Name())
+	}
+}
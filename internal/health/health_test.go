@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady(t *testing.T) {
+	dir := t.TempDir()
+	checks := Checks{
+		"data_dir": Writable(dir),
+		"database": func(ctx context.Context) error { return nil },
+	}
+
+	res := httptest.NewRecorder()
+	Ready(checks).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{"data_dir":"ok","database":"ok"}}`, res.Body.String())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the probe file is removed")
+
+	checks["database"] = func(ctx context.Context) error { return errors.New("database is locked") }
+	checks["downloads"] = Writable(filepath.Join(dir, "missing"))
+	res = httptest.NewRecorder()
+	Ready(checks).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	var report Report
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &report))
+	assert.Equal(t, "unavailable", report.Status)
+	assert.Equal(t, "ok", report.Checks["data_dir"])
+	assert.Equal(t, "database is locked", report.Checks["database"])
+	assert.Contains(t, report.Checks["downloads"], "no such file or directory")
+}
+
+func TestLive(t *testing.T) {
+	res := httptest.NewRecorder()
+	Live(res, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{}}`, res.Body.String())
+}
@@ -0,0 +1,286 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// task is a job that has been submitted and hasn't finished yet
+type task struct {
+	job    Job
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Manager runs jobs on a bounded pool of workers. Submitted jobs wait in a bounded queue
+// until a worker is free; every change of state is persisted in the store.
+type Manager struct {
+	logger  log.Logger
+	store   Store
+	runners map[string]Runner
+
+	queue   chan *task
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	active  map[string]*task
+	stopped bool
+}
+
+// NewManager starts workers goroutines running the jobs submitted to the manager. At most
+// queueSize jobs wait for a worker; further submissions fail with ErrQueueFull.
+func NewManager(logger log.Logger, store Store, workers, queueSize int, runners map[string]Runner) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		logger:  logger,
+		store:   store,
+		runners: runners,
+		queue:   make(chan *task, queueSize),
+		ctx:     ctx,
+		stop:    stop,
+		active:  make(map[string]*task),
+	}
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+// Types returns the types of job that can be submitted.
+func (m *Manager) Types() []string {
+	types := make([]string, 0, len(m.runners))
+	for t := range m.runners {
+		types = append(types, t)
+	}
+	return types
+}
+
+// Submit validates and queues a job of the given type.
+func (m *Manager) Submit(ctx context.Context, jobType string, params json.RawMessage) (Job, error) {
+	runner, ok := m.runners[jobType]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %q", ErrUnknownType, jobType)
+	}
+	params, err := runner.Prepare(params)
+	if err != nil {
+		return Job{}, fmt.Errorf("%w: %s", ErrInvalidParams, err)
+	}
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{
+		ID:        uid.String(),
+		Type:      jobType,
+		Status:    StatusQueued,
+		Params:    params,
+		CreatedOn: time.Now(),
+	}
+	if err := m.store.Save(ctx, job); err != nil {
+		return Job{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return m.abandon(job, ErrManagerStopped)
+	}
+	t := &task{job: job}
+	t.ctx, t.cancel = context.WithCancel(m.ctx)
+	select {
+	case m.queue <- t:
+		m.active[job.ID] = t
+		m.logger.With(ctx, "job_id", job.ID).Infof("Queued %s job", jobType)
+		return job, nil
+	default:
+		t.cancel()
+		return m.abandon(job, ErrQueueFull)
+	}
+}
+
+// abandon records that a job that couldn't be queued has failed.
+func (m *Manager) abandon(job Job, err error) (Job, error) {
+	now := time.Now()
+	job.Status = StatusFailed
+	job.Error = err.Error()
+	job.FinishedOn = &now
+	m.save(job)
+	return job, err
+}
+
+// Get returns the current state of a job.
+func (m *Manager) Get(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	if t, ok := m.active[id]; ok {
+		job := t.job
+		m.mu.Unlock()
+		return job, nil
+	}
+	m.mu.Unlock()
+	return m.store.Get(ctx, id)
+}
+
+// Cancel cancels a queued or running job. A queued job is cancelled immediately; a running job
+// is cancelled through its context and keeps the running status until its runner returns.
+func (m *Manager) Cancel(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	t, ok := m.active[id]
+	if !ok {
+		m.mu.Unlock()
+		job, err := m.store.Get(ctx, id)
+		if err != nil {
+			return Job{}, err
+		}
+		return job, ErrJobFinished
+	}
+	if t.job.Status.Finished() {
+		job := t.job
+		m.mu.Unlock()
+		return job, ErrJobFinished
+	}
+	t.cancel()
+	if t.job.Status == StatusQueued {
+		m.finish(t, StatusCanceled, nil, context.Canceled)
+	}
+	job := t.job
+	m.mu.Unlock()
+
+	if job.Status.Finished() {
+		m.save(job)
+		m.forget(id)
+	}
+	m.logger.With(ctx, "job_id", id).Info("Cancelled job")
+	return job, nil
+}
+
+// Close cancels all queued and running jobs and waits for the workers to stop.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	m.stopped = true
+	m.stop()
+	close(m.queue)
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for t := range m.queue {
+		m.run(t)
+	}
+}
+
+func (m *Manager) run(t *task) {
+	m.mu.Lock()
+	if t.job.Status.Finished() {
+		// cancelled while queued
+		m.mu.Unlock()
+		return
+	}
+	if t.ctx.Err() != nil {
+		m.finish(t, StatusCanceled, nil, t.ctx.Err())
+		job := t.job
+		m.mu.Unlock()
+		m.save(job)
+		m.forget(job.ID)
+		return
+	}
+	now := time.Now()
+	t.job.Status = StatusRunning
+	t.job.StartedOn = &now
+	job := t.job
+	m.mu.Unlock()
+	m.save(job)
+
+	logger := m.logger.With(nil, "job_id", job.ID)
+	logger.Infof("Running %s job", job.Type)
+	progress := func(percent int) {
+		if percent < 0 || percent > 100 {
+			return
+		}
+		m.mu.Lock()
+		if percent == t.job.Progress {
+			m.mu.Unlock()
+			return
+		}
+		t.job.Progress = percent
+		job := t.job
+		m.mu.Unlock()
+		m.save(job)
+	}
+	result, err := m.runners[job.Type].Run(t.ctx, job.Params, progress)
+
+	m.mu.Lock()
+	status := StatusSucceeded
+	switch {
+	case t.ctx.Err() != nil:
+		status, err = StatusCanceled, t.ctx.Err()
+	case err != nil:
+		status = StatusFailed
+	}
+	m.finish(t, status, result, err)
+	job = t.job
+	m.mu.Unlock()
+	m.save(job)
+	m.forget(job.ID)
+	if job.Error != "" {
+		logger.Warnf("%s job %s: %s", job.Type, job.Status, job.Error)
+	} else {
+		logger.Infof("%s job %s", job.Type, job.Status)
+	}
+}
+
+// finish records the outcome of a task. The caller must hold m.mu.
+func (m *Manager) finish(t *task, status Status, result interface{}, err error) {
+	if result != nil {
+		if data, merr := json.Marshal(result); merr != nil {
+			status, err = StatusFailed, errors.Join(err, merr)
+		} else {
+			t.job.Result = data
+		}
+	}
+	now := time.Now()
+	t.job.Status = status
+	t.job.FinishedOn = &now
+	if status == StatusSucceeded {
+		t.job.Progress = 100
+	}
+	if err != nil {
+		t.job.Error = err.Error()
+	}
+	t.cancel()
+}
+
+// forget drops a finished task once its final state has been saved, so that it is read from the store.
+func (m *Manager) forget(id string) {
+	m.mu.Lock()
+	delete(m.active, id)
+	m.mu.Unlock()
+}
+
+// save persists the state of a job, logging failures since the job carries on regardless.
+func (m *Manager) save(job Job) {
+	if err := m.store.Save(context.Background(), job); err != nil {
+		m.logger.With(nil, "job_id", job.ID).Errorf("Unable to save job: %s", err)
+	}
+}
@@ -0,0 +1,162 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// testRunner waits for a release before finishing, or until the job is cancelled
+type testRunner struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newTestRunner() *testRunner {
+	return &testRunner{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (r *testRunner) Prepare(params json.RawMessage) (json.RawMessage, error) {
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, errors.New("name not provided")
+	}
+	return params, nil
+}
+
+func (r *testRunner) Run(ctx context.Context, params json.RawMessage, progress func(int)) (interface{}, error) {
+	r.started <- struct{}{}
+	progress(50)
+	select {
+	case <-r.release:
+		return map[string]string{"greeting": "hello"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func newTestStore(t *testing.T) (Store, *sql.DB) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	return store, db
+}
+
+func waitFor(t *testing.T, m *Manager, id string, status Status) Job {
+	var job Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = m.Get(context.Background(), id)
+		require.NoError(t, err)
+		return job.Status == status
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestManager(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store, _ := newTestStore(t)
+	runner := newTestRunner()
+	m := NewManager(logger, store, 1, 1, map[string]Runner{"greet": runner})
+	defer m.Close()
+	ctx := context.Background()
+	params := json.RawMessage(`{"name":"world"}`)
+
+	_, err := m.Submit(ctx, "unknown", params)
+	assert.ErrorIs(t, err, ErrUnknownType)
+	_, err = m.Submit(ctx, "greet", json.RawMessage(`{}`))
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	// first job runs, second waits in the queue, third doesn't fit
+	first, err := m.Submit(ctx, "greet", params)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, first.Status)
+	<-runner.started
+	running := waitFor(t, m, first.ID, StatusRunning)
+	assert.Equal(t, 50, running.Progress)
+	assert.NotNil(t, running.StartedOn)
+
+	second, err := m.Submit(ctx, "greet", params)
+	require.NoError(t, err)
+	third, err := m.Submit(ctx, "greet", params)
+	assert.ErrorIs(t, err, ErrQueueFull)
+	stored, err := store.Get(ctx, third.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, stored.Status)
+
+	// cancelling the queued job takes effect immediately
+	canceled, err := m.Cancel(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCanceled, canceled.Status)
+
+	runner.release <- struct{}{}
+	done := waitFor(t, m, first.ID, StatusSucceeded)
+	assert.Equal(t, 100, done.Progress)
+	assert.JSONEq(t, `{"greeting":"hello"}`, string(done.Result))
+	assert.JSONEq(t, `{"name":"world"}`, string(done.Params))
+	assert.NotNil(t, done.FinishedOn)
+
+	_, err = m.Cancel(ctx, first.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+	_, err = m.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	// cancelling a running job goes through its context
+	fourth, err := m.Submit(ctx, "greet", params)
+	require.NoError(t, err)
+	<-runner.started
+	waitFor(t, m, fourth.ID, StatusRunning)
+	_, err = m.Cancel(ctx, fourth.ID)
+	require.NoError(t, err)
+	canceled = waitFor(t, m, fourth.ID, StatusCanceled)
+	assert.Equal(t, context.Canceled.Error(), canceled.Error)
+}
+
+func TestManager_Close(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store, db := newTestStore(t)
+	runner := newTestRunner()
+	m := NewManager(logger, store, 1, 10, map[string]Runner{"greet": runner})
+	ctx := context.Background()
+
+	running, err := m.Submit(ctx, "greet", json.RawMessage(`{"name":"a"}`))
+	require.NoError(t, err)
+	<-runner.started
+	queued, err := m.Submit(ctx, "greet", json.RawMessage(`{"name":"b"}`))
+	require.NoError(t, err)
+
+	m.Close()
+	for _, id := range []string{running.ID, queued.ID} {
+		job, err := store.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, StatusCanceled, job.Status)
+	}
+	_, err = m.Submit(ctx, "greet", json.RawMessage(`{"name":"c"}`))
+	assert.ErrorIs(t, err, ErrManagerStopped)
+
+	// jobs left unfinished by a previous run are failed when the store is opened again
+	require.NoError(t, store.Save(ctx, Job{ID: "stale", Type: "greet", Status: StatusRunning, CreatedOn: time.Now()}))
+	store, err = NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+	stale, err := store.Get(ctx, "stale")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, stale.Status)
+	assert.NotNil(t, stale.FinishedOn)
+}
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job already finished")
+	ErrUnknownType    = errors.New("unknown job type")
+	ErrInvalidParams  = errors.New("invalid job parameters")
+	ErrQueueFull      = errors.New("job queue is full")
+	ErrManagerStopped = errors.New("job manager stopped")
+)
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Finished reports whether a job with the status will not change any more.
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Job is a long-running task executed in the background
+type Job struct {
+	ID     string          `json:"id" example:"0b1e8f4e-5f3a-4d3c-9c43-6a1f2d9e7b10"`
+	Type   string          `json:"type" example:"ping"`
+	Status Status          `json:"status" example:"running"`
+	Params json.RawMessage `json:"params,omitempty" swaggertype:"object"`
+	// percentage of the work done
+	Progress   int             `json:"progress" example:"50"`
+	Result     json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error      string          `json:"error,omitempty"`
+	CreatedOn  time.Time       `json:"created_on"`
+	StartedOn  *time.Time      `json:"started_on,omitempty"`
+	FinishedOn *time.Time      `json:"finished_on,omitempty"`
+}
+
+// Runner performs the jobs of one type.
+type Runner interface {
+	// Prepare validates the parameters of a job when it is submitted and returns them with defaults applied.
+	Prepare(params json.RawMessage) (json.RawMessage, error)
+	// Run performs the job, reporting the percentage of work done through progress, and returns its result.
+	// Run must return promptly once ctx is cancelled.
+	Run(ctx context.Context, params json.RawMessage, progress func(percent int)) (interface{}, error)
+}
+
+// Store persists jobs
+type Store interface {
+	// Save creates or replaces a job
+	Save(ctx context.Context, job Job) error
+	// Get returns the job with the given ID or ErrJobNotFound
+	Get(ctx context.Context, id string) (Job, error)
+}
@@ -0,0 +1,92 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SQLiteStore stores jobs in the jobs table of a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates the jobs table if needed. Jobs left queued or running by a previous
+// run of the server can never finish, so they are marked as failed.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (Store, error) {
+	query := `
+    CREATE TABLE IF NOT EXISTS jobs (
+        id TEXT PRIMARY KEY,
+        type TEXT NOT NULL,
+        status TEXT NOT NULL,
+        params TEXT,
+        progress INTEGER NOT NULL DEFAULT 0,
+        result TEXT,
+        error TEXT NOT NULL DEFAULT '',
+        created_on DATETIME NOT NULL,
+        started_on DATETIME,
+        finished_on DATETIME
+    );
+    `
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+	_, err := db.ExecContext(ctx, "UPDATE jobs SET status = ?, error = ?, finished_on = ? WHERE status IN (?, ?)",
+		StatusFailed, "interrupted by server restart", time.Now(), StatusQueued, StatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, job Job) error {
+	_, err := s.db.ExecContext(ctx, `
+    INSERT INTO jobs (id, type, status, params, progress, result, error, created_on, started_on, finished_on)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(id) DO UPDATE SET
+        status = excluded.status, progress = excluded.progress, result = excluded.result,
+        error = excluded.error, started_on = excluded.started_on, finished_on = excluded.finished_on
+    `, job.ID, job.Type, job.Status, nullJSON(job.Params), job.Progress, nullJSON(job.Result), job.Error,
+		job.CreatedOn, job.StartedOn, job.FinishedOn)
+	return err
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (Job, error) {
+	var (
+		job        Job
+		params     sql.NullString
+		result     sql.NullString
+		startedOn  sql.NullTime
+		finishedOn sql.NullTime
+	)
+	row := s.db.QueryRowContext(ctx, `
+    SELECT id, type, status, params, progress, result, error, created_on, started_on, finished_on
+    FROM jobs WHERE id = ?`, id)
+	err := row.Scan(&job.ID, &job.Type, &job.Status, &params, &job.Progress, &result, &job.Error,
+		&job.CreatedOn, &startedOn, &finishedOn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	if params.Valid {
+		job.Params = json.RawMessage(params.String)
+	}
+	if result.Valid {
+		job.Result = json.RawMessage(result.String)
+	}
+	if startedOn.Valid {
+		job.StartedOn = &startedOn.Time
+	}
+	if finishedOn.Valid {
+		job.FinishedOn = &finishedOn.Time
+	}
+	return job, nil
+}
+
+func nullJSON(raw json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(raw), Valid: len(raw) > 0}
+}
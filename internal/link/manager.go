@@ -0,0 +1,205 @@
+package link
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// Manager mints links signed with HMAC-SHA256 and checks them when they are redeemed.
+// Links must also be in the store, so that they can be revoked and used once only.
+type Manager struct {
+	logger log.Logger
+	key    []byte
+	store  Store
+	now    func() time.Time
+}
+
+func NewManager(logger log.Logger, key []byte, store Store) *Manager {
+	return &Manager{logger: logger, key: key, store: store, now: time.Now}
+}
+
+// Create stores a new link to the file with the given ID. createdBy is the remote address of the client.
+func (m *Manager) Create(ctx context.Context, fileID, createdBy string, req Request) (Link, error) {
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = defaultExpiresIn
+	}
+	if req.ExpiresIn < 1 || req.ExpiresIn > maxExpiresIn {
+		return Link{}, fmt.Errorf("%w: must be between 1 and %d seconds", ErrInvalidExpiry, maxExpiresIn)
+	}
+	if req.IP != "" {
+		ip := net.ParseIP(req.IP)
+		if ip == nil {
+			return Link{}, fmt.Errorf("%w: %q", ErrInvalidAddress, req.IP)
+		}
+		req.IP = ip.String()
+	}
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return Link{}, err
+	}
+	now := m.now().UTC()
+	l := Link{
+		ID:        uid.String(),
+		FileID:    fileID,
+		SingleUse: req.SingleUse,
+		IP:        req.IP,
+		Expires:   now.Add(time.Duration(req.ExpiresIn) * time.Second).Truncate(time.Second),
+		CreatedOn: now,
+		CreatedBy: createdBy,
+	}
+	if err := m.store.Save(ctx, l); err != nil {
+		return Link{}, err
+	}
+	m.logger.With(ctx, "link_id", l.ID).Infof("Created link to file %s expiring at %s", fileID, l.Expires.Format(time.RFC3339))
+	return l, nil
+}
+
+// Query returns the signed query parameters that redeem a link.
+func (m *Manager) Query(l Link) url.Values {
+	query := url.Values{}
+	query.Set("link", l.ID)
+	query.Set("expires", strconv.FormatInt(l.Expires.Unix(), 10))
+	if l.SingleUse {
+		query.Set("once", "1")
+	}
+	if l.IP != "" {
+		query.Set("ip", l.IP)
+	}
+	query.Set("sig", m.sign(l.FileID, query))
+	return query
+}
+
+// Signed reports whether a request carries link parameters.
+func Signed(query url.Values) bool {
+	return query.Has("sig") || query.Has("link")
+}
+
+// Redeem checks the signed query parameters of a request for the file with the given ID.
+// Every attempt with a valid signature is recorded as a redemption of the link.
+//
+// A single use link is used up by the first request. A few more requests resuming the download, which
+// only ask for a range after the start of the file, are served to the same client address until the
+// link expires, so that an interrupted download can be completed.
+func (m *Manager) Redeem(ctx context.Context, fileID string, query url.Values, client string, resume bool) (Link, error) {
+	expected := m.sign(fileID, query)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return Link{}, ErrBadSignature
+	}
+	l, err := m.store.Get(ctx, query.Get("link"))
+	if errors.Is(err, ErrLinkNotFound) {
+		// signed but unknown, such as a link minted before the store was reset
+		return Link{}, ErrBadSignature
+	}
+	if err != nil {
+		return Link{}, err
+	}
+
+	now := m.now()
+	err = m.check(ctx, l, client, resume, now)
+	r := Redemption{LinkID: l.ID, Time: now, Client: client, RequestID: log.RequestID(ctx)}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if rerr := m.store.AddRedemption(ctx, r); rerr != nil {
+		return Link{}, rerr
+	}
+	if err != nil {
+		m.logger.With(ctx, "link_id", l.ID).Infof("Refused link to file %s from %s: %s", fileID, client, err)
+		return Link{}, err
+	}
+	m.logger.With(ctx, "link_id", l.ID).Infof("Redeemed link to file %s from %s", fileID, client)
+	return l, nil
+}
+
+func (m *Manager) check(ctx context.Context, l Link, client string, resume bool, now time.Time) error {
+	switch {
+	case l.RevokedOn != nil:
+		return ErrLinkRevoked
+	case !now.Before(l.Expires):
+		return ErrLinkExpired
+	case l.IP != "" && !sameAddress(l.IP, client):
+		return ErrAddressMismatch
+	case l.SingleUse && l.UsedOn != nil && resume && sameAddress(host(l.UsedBy), client):
+		return m.checkResume(ctx, l)
+	case l.SingleUse:
+		return m.store.Use(ctx, l.ID, now, client)
+	}
+	return nil
+}
+
+// checkResume refuses to resume the download of a single use link more than maxResumes times.
+func (m *Manager) checkResume(ctx context.Context, l Link) error {
+	redemptions, err := m.store.Redemptions(ctx, l.ID)
+	if err != nil {
+		return err
+	}
+	served := 0
+	for _, r := range redemptions {
+		if r.Error == "" {
+			served++
+		}
+	}
+	// the first request used the link
+	if served > maxResumes {
+		return ErrLinkUsed
+	}
+	return nil
+}
+
+// Get returns a link with its redemptions.
+func (m *Manager) Get(ctx context.Context, id string) (Audit, error) {
+	l, err := m.store.Get(ctx, id)
+	if err != nil {
+		return Audit{}, err
+	}
+	redemptions, err := m.store.Redemptions(ctx, id)
+	if err != nil {
+		return Audit{}, err
+	}
+	return Audit{Link: l, Redemptions: redemptions}, nil
+}
+
+// Revoke stops a link from being redeemed.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	if err := m.store.Revoke(ctx, id, m.now()); err != nil {
+		return err
+	}
+	m.logger.With(ctx, "link_id", id).Info("Revoked link")
+	return nil
+}
+
+// sign returns the signature of the link parameters in query for the file with the given ID.
+func (m *Manager) sign(fileID string, query url.Values) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(strings.Join([]string{
+		"v1", query.Get("link"), fileID, query.Get("expires"), query.Get("once"), query.Get("ip"),
+	}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sameAddress reports whether the host of a remote address is the IP address ip.
+func sameAddress(ip, remoteAddr string) bool {
+	return net.ParseIP(ip).Equal(net.ParseIP(host(remoteAddr)))
+}
+
+// host returns the host of a remote address, which may have no port.
+func host(remoteAddr string) string {
+	h, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return h
+}
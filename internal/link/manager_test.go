@@ -0,0 +1,105 @@
+package link
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	logger, _ := log.NewForTest()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	return NewManager(logger, []byte("test-key"), store)
+}
+
+func TestManager_Redeem(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	l, err := m.Create(ctx, "12345", "10.0.0.1:1234", Request{})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), l.Expires, 2*time.Second)
+	query := m.Query(l)
+	assert.True(t, Signed(query))
+
+	_, err = m.Redeem(ctx, "12345", query, "10.0.0.2:1234", false)
+	assert.NoError(t, err)
+	_, err = m.Redeem(ctx, "12345", query, "10.0.0.3:1234", false)
+	assert.NoError(t, err, "links can be used repeatedly unless single use")
+
+	// the signature covers the file and every parameter
+	_, err = m.Redeem(ctx, "67890", query, "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, ErrBadSignature)
+	tampered := m.Query(l)
+	tampered.Set("expires", "4102444800")
+	_, err = m.Redeem(ctx, "12345", tampered, "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, ErrBadSignature)
+	_, err = NewManager(m.logger, []byte("other-key"), m.store).Redeem(ctx, "12345", query, "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, ErrBadSignature)
+
+	require.NoError(t, m.Revoke(ctx, l.ID))
+	_, err = m.Redeem(ctx, "12345", query, "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, ErrLinkRevoked)
+	assert.ErrorIs(t, m.Revoke(ctx, "missing"), ErrLinkNotFound)
+
+	audit, err := m.Get(ctx, l.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, audit.RevokedOn)
+	require.Len(t, audit.Redemptions, 3, "only attempts with a valid signature are recorded")
+	assert.Equal(t, "10.0.0.2:1234", audit.Redemptions[0].Client)
+	assert.Empty(t, audit.Redemptions[0].Error)
+	assert.Equal(t, ErrLinkRevoked.Error(), audit.Redemptions[2].Error)
+}
+
+func TestManager_Constraints(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	once, err := m.Create(ctx, "12345", "10.0.0.1:1234", Request{SingleUse: true})
+	require.NoError(t, err)
+	_, err = m.Redeem(ctx, "12345", m.Query(once), "10.0.0.2:1234", true)
+	assert.NoError(t, err)
+	_, err = m.Redeem(ctx, "12345", m.Query(once), "10.0.0.2:5678", true)
+	assert.NoError(t, err, "the client that used the link can fetch the rest of the file")
+	_, err = m.Redeem(ctx, "12345", m.Query(once), "10.0.0.3:1234", true)
+	assert.ErrorIs(t, err, ErrLinkUsed, "other clients cannot")
+	_, err = m.Redeem(ctx, "12345", m.Query(once), "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, ErrLinkUsed, "nor can the whole file be downloaded again")
+	for i := 1; i < maxResumes; i++ {
+		_, err = m.Redeem(ctx, "12345", m.Query(once), "10.0.0.2:1234", true)
+		assert.NoError(t, err)
+	}
+	_, err = m.Redeem(ctx, "12345", m.Query(once), "10.0.0.2:1234", true)
+	assert.ErrorIs(t, err, ErrLinkUsed, "the download is only resumed a few times")
+
+	bound, err := m.Create(ctx, "12345", "10.0.0.1:1234", Request{IP: "::ffff:10.0.0.2"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", bound.IP)
+	_, err = m.Redeem(ctx, "12345", m.Query(bound), "10.0.0.3:1234", false)
+	assert.ErrorIs(t, err, ErrAddressMismatch)
+	_, err = m.Redeem(ctx, "12345", m.Query(bound), "10.0.0.2:1234", false)
+	assert.NoError(t, err)
+
+	expiring, err := m.Create(ctx, "12345", "10.0.0.1:1234", Request{ExpiresIn: 60})
+	require.NoError(t, err)
+	m.now = func() time.Time { return time.Now().Add(time.Minute) }
+	_, err = m.Redeem(ctx, "12345", m.Query(expiring), "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, ErrLinkExpired)
+
+	_, err = m.Create(ctx, "12345", "10.0.0.1:1234", Request{ExpiresIn: maxExpiresIn + 1})
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+	_, err = m.Create(ctx, "12345", "10.0.0.1:1234", Request{IP: "example.com"})
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
@@ -0,0 +1,87 @@
+package link
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrLinkNotFound    = errors.New("link not found")
+	ErrInvalidExpiry   = errors.New("invalid link expiry")
+	ErrInvalidAddress  = errors.New("invalid link address")
+	ErrBadSignature    = errors.New("invalid link signature")
+	ErrLinkExpired     = errors.New("link expired")
+	ErrLinkRevoked     = errors.New("link revoked")
+	ErrLinkUsed        = errors.New("link already used")
+	ErrAddressMismatch = errors.New("link not valid from this address")
+)
+
+// Bounds and default of the lifetime of a link, in seconds
+const (
+	defaultExpiresIn = 3600
+	maxExpiresIn     = 7 * 86400
+)
+
+// maxResumes bounds the partial requests a single use link serves after its first use
+const maxResumes = 5
+
+// Link grants access to a file in the downloads catalogue until it expires or is revoked
+type Link struct {
+	ID     string `json:"id" example:"3f2c1a9e-7b4d-4e8a-9c61-0d5e2b7f8a13"`
+	FileID string `json:"file_id" example:"12345"`
+	// the link can be redeemed once only
+	SingleUse bool `json:"single_use"`
+	// the only client address the link can be redeemed from, if any
+	IP        string    `json:"ip,omitempty" example:"203.0.113.7"`
+	Expires   time.Time `json:"expires"`
+	CreatedOn time.Time `json:"created_on"`
+	// the remote address of the client that created the link
+	CreatedBy string     `json:"created_by" example:"127.0.0.1:51234"`
+	RevokedOn *time.Time `json:"revoked_on,omitempty"`
+	UsedOn    *time.Time `json:"used_on,omitempty"`
+	// the remote address of the client that used a single use link
+	UsedBy string `json:"used_by,omitempty" example:"203.0.113.7:51234"`
+	// the signed URL path and query, only returned when the link is created
+	URL string `json:"url,omitempty" example:"/api/v1/site/download/12345?expires=1700000000&link=3f2c1a9e-7b4d-4e8a-9c61-0d5e2b7f8a13&sig=..."`
+}
+
+// Request describes a link to create
+type Request struct {
+	// seconds until the link expires (1-604800, default 3600)
+	ExpiresIn int  `json:"expires_in" example:"3600"`
+	SingleUse bool `json:"single_use"`
+	// binds the link to a client address
+	IP string `json:"ip,omitempty" example:"203.0.113.7"`
+}
+
+// Redemption is the audit record of an attempt to use a link with a valid signature
+type Redemption struct {
+	ID     int64     `json:"id"`
+	LinkID string    `json:"link_id"`
+	Time   time.Time `json:"time"`
+	// the remote address of the client that used the link
+	Client    string `json:"client" example:"127.0.0.1:51234"`
+	RequestID string `json:"request_id,omitempty"`
+	// why the link was refused, empty if the file was served
+	Error string `json:"error,omitempty"`
+}
+
+// Audit is a link with its redemptions, oldest first
+type Audit struct {
+	Link
+	Redemptions []Redemption `json:"redemptions"`
+}
+
+// Store persists links and their redemptions
+type Store interface {
+	Save(ctx context.Context, l Link) error
+	Get(ctx context.Context, id string) (Link, error)
+	// Revoke marks a link as revoked unless it already is
+	Revoke(ctx context.Context, id string, t time.Time) error
+	// Use marks a link as used by a client, returning ErrLinkUsed if it already was
+	Use(ctx context.Context, id string, t time.Time, client string) error
+	AddRedemption(ctx context.Context, r Redemption) error
+	// Redemptions returns the redemptions of a link, oldest first
+	Redemptions(ctx context.Context, id string) ([]Redemption, error)
+}
@@ -0,0 +1,124 @@
+package link
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLiteStore stores links and their redemptions in a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates the download_links and download_link_redemptions tables if needed.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (Store, error) {
+	query := `
+    CREATE TABLE IF NOT EXISTS download_links (
+        id TEXT PRIMARY KEY,
+        file_id TEXT NOT NULL,
+        single_use BOOLEAN NOT NULL,
+        ip TEXT NOT NULL DEFAULT '',
+        expires DATETIME NOT NULL,
+        created_on DATETIME NOT NULL,
+        created_by TEXT NOT NULL,
+        revoked_on DATETIME,
+        used_on DATETIME,
+        used_by TEXT NOT NULL DEFAULT ''
+    );
+    CREATE TABLE IF NOT EXISTS download_link_redemptions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        link_id TEXT NOT NULL,
+        time DATETIME NOT NULL,
+        client TEXT NOT NULL,
+        request_id TEXT NOT NULL DEFAULT '',
+        error TEXT NOT NULL DEFAULT ''
+    );
+    CREATE INDEX IF NOT EXISTS download_link_redemptions_link ON download_link_redemptions (link_id);
+    `
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, l Link) error {
+	_, err := s.db.ExecContext(ctx, `
+    INSERT INTO download_links (id, file_id, single_use, ip, expires, created_on, created_by, revoked_on, used_on, used_by)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.ID, l.FileID, l.SingleUse, l.IP, l.Expires.UTC(), l.CreatedOn.UTC(), l.CreatedBy, l.RevokedOn, l.UsedOn, l.UsedBy)
+	return err
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (Link, error) {
+	var (
+		l         Link
+		revokedOn sql.NullTime
+		usedOn    sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+    SELECT id, file_id, single_use, ip, expires, created_on, created_by, revoked_on, used_on, used_by
+    FROM download_links WHERE id = ?`, id).
+		Scan(&l.ID, &l.FileID, &l.SingleUse, &l.IP, &l.Expires, &l.CreatedOn, &l.CreatedBy, &revokedOn, &usedOn, &l.UsedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrLinkNotFound
+	}
+	if err != nil {
+		return Link{}, err
+	}
+	if revokedOn.Valid {
+		l.RevokedOn = &revokedOn.Time
+	}
+	if usedOn.Valid {
+		l.UsedOn = &usedOn.Time
+	}
+	return l, nil
+}
+
+func (s *SQLiteStore) Revoke(ctx context.Context, id string, t time.Time) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE download_links SET revoked_on = COALESCE(revoked_on, ?) WHERE id = ?", t.UTC(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Use(ctx context.Context, id string, t time.Time, client string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE download_links SET used_on = ?, used_by = ? WHERE id = ? AND used_on IS NULL", t.UTC(), client, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrLinkUsed
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddRedemption(ctx context.Context, r Redemption) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO download_link_redemptions (link_id, time, client, request_id, error) VALUES (?, ?, ?, ?, ?)",
+		r.LinkID, r.Time.UTC(), r.Client, r.RequestID, r.Error)
+	return err
+}
+
+func (s *SQLiteStore) Redemptions(ctx context.Context, id string) ([]Redemption, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT id, link_id, time, client, request_id, error FROM download_link_redemptions
+    WHERE link_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	redemptions := []Redemption{}
+	for rows.Next() {
+		var r Redemption
+		if err := rows.Scan(&r.ID, &r.LinkID, &r.Time, &r.Client, &r.RequestID, &r.Error); err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, r)
+	}
+	return redemptions, rows.Err()
+}
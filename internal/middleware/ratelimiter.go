@@ -2,22 +2,71 @@ package middleware
 
 import (
 	"net/http"
+	"sync/atomic"
 
 	"golang.org/x/time/rate"
 )
 
-func RateLimiter(limit int) Middleware {
+// RateLimit is a token bucket shared by all requests whose state can be inspected and changed at runtime.
+type RateLimit struct {
+	limiter  *rate.Limiter
+	allowed  atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// RateLimitState describes the configuration and activity of a RateLimit.
+type RateLimitState struct {
+	// the number of requests per second added to the bucket
+	Limit float64 `json:"limit"`
+	// the maximum number of requests allowed at once
+	Burst int `json:"burst"`
+	// the number of requests currently available
+	Tokens float64 `json:"tokens"`
+	// the number of requests let through since the server started
+	Allowed uint64 `json:"allowed"`
+	// the number of requests answered with 429 since the server started
+	Rejected uint64 `json:"rejected"`
+}
+
+// NewRateLimit creates a rate limit allowing limit requests per second with bursts of up to burst requests.
+func NewRateLimit(limit rate.Limit, burst int) *RateLimit {
+	return &RateLimit{limiter: rate.NewLimiter(limit, burst)}
+}
+
+// Middleware returns a middleware that answers 429 Too Many Requests when the rate limit is exceeded.
+func (l *RateLimit) Middleware() Middleware {
 	return func(next http.Handler) http.Handler {
-		// Limit requests
-		limiter := rate.NewLimiter(1, limit)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
+			if !l.limiter.Allow() {
+				l.rejected.Add(1)
 				w.WriteHeader(http.StatusTooManyRequests)
 				return
 			}
+			l.allowed.Add(1)
 
 			next.ServeHTTP(w, r)
 		})
 	}
+}
 
-}
\ No newline at end of file
+// State returns the current state of the rate limit.
+func (l *RateLimit) State() RateLimitState {
+	return RateLimitState{
+		Limit:    float64(l.limiter.Limit()),
+		Burst:    l.limiter.Burst(),
+		Tokens:   l.limiter.Tokens(),
+		Allowed:  l.allowed.Load(),
+		Rejected: l.rejected.Load(),
+	}
+}
+
+// Set changes the number of requests per second and the burst size.
+func (l *RateLimit) Set(limit rate.Limit, burst int) {
+	l.limiter.SetLimit(limit)
+	l.limiter.SetBurst(burst)
+}
+
+func RateLimiter(limit int) Middleware {
+	// Limit requests
+	return NewRateLimit(1, limit).Middleware()
+}
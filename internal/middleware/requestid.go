@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// RequestID records the request ID and correlation ID of each request in its context, generating a
+// request ID when the client didn't send an X-Request-ID header, and echoes the request ID in the response.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := log.WithRequest(r.Context(), r)
+			w.Header().Set("X-Request-ID", log.RequestID(ctx))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
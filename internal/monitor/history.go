@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// summarize computes the uptime, latency percentiles and incidents of the checks made in [from, to).
+func summarize(id string, from, to time.Time, checks []Check) History {
+	h := History{MonitorID: id, From: from, To: to, Checks: len(checks), Uptime: 100, Incidents: []Incident{}}
+	var latencies []float64
+	var incident *Incident
+	for _, c := range checks {
+		if c.Up {
+			h.Up++
+			latencies = append(latencies, c.Latency)
+			if incident != nil {
+				end := c.Time
+				incident.End = &end
+				incident.Duration = end.Sub(incident.Start).Seconds()
+				h.Incidents = append(h.Incidents, *incident)
+				incident = nil
+			}
+			continue
+		}
+		if incident == nil {
+			incident = &Incident{Start: c.Time, Error: c.Error}
+		}
+		incident.Checks++
+	}
+	if incident != nil {
+		incident.Duration = to.Sub(incident.Start).Seconds()
+		h.Incidents = append(h.Incidents, *incident)
+	}
+	if h.Checks > 0 {
+		h.Uptime = math.Round(10000*float64(h.Up)/float64(h.Checks)) / 100
+	}
+	if len(latencies) > 0 {
+		sort.Float64s(latencies)
+		h.Latency = &LatencyStats{
+			Min: latencies[0],
+			P50: percentile(latencies, 50),
+			P90: percentile(latencies, 90),
+			P95: percentile(latencies, 95),
+			P99: percentile(latencies, 99),
+			Max: latencies[len(latencies)-1],
+		}
+	}
+	return h
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
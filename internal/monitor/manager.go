@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// notifyTimeout bounds the time spent telling the notifiers about a state change
+const notifyTimeout = 10 * time.Second
+
+// Manager checks every monitor on its interval, stores the checks and notifies state changes.
+type Manager struct {
+	logger    log.Logger
+	store     Store
+	probes    map[string]Probe
+	notifiers []Notifier
+
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// NewManager creates a manager running the given probes by monitor type. Call Start to schedule
+// the stored monitors.
+func NewManager(logger log.Logger, store Store, probes map[string]Probe, notifiers ...Notifier) *Manager {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Manager{
+		logger:    logger,
+		store:     store,
+		probes:    probes,
+		notifiers: notifiers,
+		ctx:       ctx,
+		stop:      stop,
+		running:   make(map[string]context.CancelFunc),
+	}
+}
+
+// Start schedules the checks of every stored monitor.
+func (m *Manager) Start(ctx context.Context) error {
+	monitors, err := m.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mon := range monitors {
+		m.schedule(mon)
+	}
+	m.logger.Infof("Scheduled %d monitors", len(monitors))
+	return nil
+}
+
+// Close stops checking the monitors and waits for checks in progress to end.
+func (m *Manager) Close() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// Create validates and stores a new monitor and schedules its checks, starting with one straight away.
+func (m *Manager) Create(ctx context.Context, mon Monitor) (Monitor, error) {
+	probe, ok := m.probes[mon.Type]
+	if !ok {
+		return Monitor{}, fmt.Errorf("%w: %q", ErrUnknownType, mon.Type)
+	}
+	params, err := probe.Prepare(mon.Params)
+	if err != nil {
+		return Monitor{}, fmt.Errorf("%w: %s", ErrInvalidParams, err)
+	}
+	if mon.Interval == 0 {
+		mon.Interval = defaultInterval
+	}
+	if mon.Interval < minInterval || mon.Interval > maxInterval {
+		return Monitor{}, fmt.Errorf("%w: must be between %d and %d seconds", ErrInvalidInterval, minInterval, maxInterval)
+	}
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return Monitor{}, err
+	}
+	mon = Monitor{
+		ID:        uid.String(),
+		Name:      mon.Name,
+		Type:      mon.Type,
+		Params:    params,
+		Interval:  mon.Interval,
+		State:     StateUnknown,
+		CreatedOn: time.Now(),
+	}
+	if mon.Name == "" {
+		mon.Name = mon.Type + " " + string(params)
+	}
+	if err := m.store.Save(ctx, mon); err != nil {
+		return Monitor{}, err
+	}
+	m.schedule(mon)
+	m.logger.With(ctx, "monitor_id", mon.ID).Infof("Created %s monitor %q checked every %d seconds", mon.Type, mon.Name, mon.Interval)
+	return mon, nil
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (Monitor, error) {
+	return m.store.Get(ctx, id)
+}
+
+func (m *Manager) List(ctx context.Context) ([]Monitor, error) {
+	return m.store.List(ctx)
+}
+
+// Delete stops checking a monitor and removes it with its checks.
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	if cancel, ok := m.running[id]; ok {
+		cancel()
+		delete(m.running, id)
+	}
+	m.mu.Unlock()
+	return m.store.Delete(ctx, id)
+}
+
+// History summarizes the checks of a monitor made in [from, to), including the checks themselves if entries is set.
+func (m *Manager) History(ctx context.Context, id string, from, to time.Time, entries bool) (History, error) {
+	if _, err := m.store.Get(ctx, id); err != nil {
+		return History{}, err
+	}
+	checks, err := m.store.Checks(ctx, id, from, to)
+	if err != nil {
+		return History{}, err
+	}
+	h := summarize(id, from, to, checks)
+	if entries {
+		h.Entries = checks
+	}
+	return h, nil
+}
+
+// schedule checks a monitor straight away and then on its interval until it is deleted or the manager closed.
+func (m *Manager) schedule(mon Monitor) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.mu.Lock()
+	m.running[mon.ID] = cancel
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(time.Duration(mon.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			mon = m.check(ctx, mon)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// check probes a monitor once, stores the check and notifies a change of state. It returns the updated monitor.
+func (m *Manager) check(ctx context.Context, mon Monitor) Monitor {
+	logger := m.logger.With(nil, "monitor_id", mon.ID)
+	// a check must not overlap the next one
+	checkCtx, cancel := context.WithTimeout(ctx, time.Duration(mon.Interval)*time.Second)
+	outcome, err := m.probes[mon.Type].Check(checkCtx, mon.Params)
+	cancel()
+	check := Check{Time: time.Now(), Up: outcome.Up && err == nil, Latency: outcome.Latency, Error: outcome.Error}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	if outcome.Result != nil {
+		if data, err := json.Marshal(outcome.Result); err == nil {
+			check.Result = data
+		}
+	}
+	previous := mon.State
+	mon.State = StateDown
+	if check.Up {
+		mon.State = StateUp
+	}
+	mon.LastCheckedOn = &check.Time
+	if mon.State != previous {
+		mon.StateChangedOn = &check.Time
+	}
+
+	// saving under the lock keeps a monitor deleted meanwhile from being saved again
+	m.mu.Lock()
+	if ctx.Err() != nil {
+		// deleted or shutting down: the outcome is meaningless
+		m.mu.Unlock()
+		return mon
+	}
+	if err := m.store.AddCheck(ctx, mon.ID, check); err != nil {
+		logger.Errorf("Unable to save check: %s", err)
+	}
+	if err := m.store.Save(ctx, mon); err != nil {
+		logger.Errorf("Unable to save monitor: %s", err)
+	}
+	m.mu.Unlock()
+
+	// coming up for the first time is not worth a notification
+	if mon.State != previous && !(previous == StateUnknown && mon.State == StateUp) {
+		m.notify(ctx, Event{Monitor: mon, Previous: previous, Current: mon.State, Check: check})
+	}
+	return mon
+}
+
+func (m *Manager) notify(ctx context.Context, event Event) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	for _, n := range m.notifiers {
+		if err := n.Notify(ctx, event); err != nil {
+			m.logger.With(nil, "monitor_id", event.Monitor.ID).Warnf("Unable to notify state change: %s", err)
+		}
+	}
+}
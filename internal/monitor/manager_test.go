@@ -0,0 +1,177 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// fakeProbe reports the outcomes it is given in turn
+type fakeProbe struct {
+	mu       sync.Mutex
+	outcomes []Outcome
+}
+
+func (p *fakeProbe) Prepare(params json.RawMessage) (json.RawMessage, error) {
+	if string(params) == "{}" {
+		return nil, errors.New("target not provided")
+	}
+	return params, nil
+}
+
+func (p *fakeProbe) Check(ctx context.Context, params json.RawMessage) (Outcome, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.outcomes) == 0 {
+		return Outcome{}, errors.New("no outcome")
+	}
+	o := p.outcomes[0]
+	p.outcomes = p.outcomes[1:]
+	return o, nil
+}
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func newTestManager(t *testing.T, probe Probe, notifiers ...Notifier) *Manager {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	logger, _ := log.NewForTest()
+	m := NewManager(logger, store, map[string]Probe{"fake": probe}, notifiers...)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestManager(t *testing.T) {
+	probe := &fakeProbe{outcomes: []Outcome{{Up: true, Latency: 10, Result: map[string]int{"status": 200}}}}
+	notifier := &recordingNotifier{}
+	m := newTestManager(t, probe, notifier)
+	ctx := context.Background()
+
+	_, err := m.Create(ctx, Monitor{Type: "other", Params: json.RawMessage(`{"host":"a"}`)})
+	assert.ErrorIs(t, err, ErrUnknownType)
+	_, err = m.Create(ctx, Monitor{Type: "fake", Params: json.RawMessage(`{}`)})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	_, err = m.Create(ctx, Monitor{Type: "fake", Params: json.RawMessage(`{"host":"a"}`), Interval: 1})
+	assert.ErrorIs(t, err, ErrInvalidInterval)
+
+	// the first check runs straight away
+	mon, err := m.Create(ctx, Monitor{Name: "a", Type: "fake", Params: json.RawMessage(`{"host":"a"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, 60, mon.Interval)
+	assert.Equal(t, StateUnknown, mon.State)
+	require.Eventually(t, func() bool {
+		mon, err = m.Get(ctx, mon.ID)
+		require.NoError(t, err)
+		return mon.State == StateUp
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotNil(t, mon.LastCheckedOn)
+
+	// further checks are driven by hand rather than waiting for the interval
+	probe.outcomes = []Outcome{{Up: false, Error: "refused"}, {Up: false, Error: "refused"}, {Up: true, Latency: 30}}
+	for i := 0; i < 3; i++ {
+		mon = m.check(ctx, mon)
+	}
+	assert.Equal(t, StateUp, mon.State)
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, StateUp, notifier.events[0].Previous)
+	assert.Equal(t, StateDown, notifier.events[0].Current)
+	assert.Equal(t, "refused", notifier.events[0].Check.Error)
+	assert.Equal(t, StateUp, notifier.events[1].Current)
+
+	h, err := m.History(ctx, mon.ID, time.Now().Add(-time.Hour), time.Now().Add(time.Second), true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, h.Checks)
+	assert.Equal(t, 2, h.Up)
+	assert.Equal(t, 50.0, h.Uptime)
+	require.Len(t, h.Incidents, 1)
+	assert.Equal(t, 2, h.Incidents[0].Checks)
+	assert.NotNil(t, h.Incidents[0].End)
+	require.Len(t, h.Entries, 4)
+	assert.JSONEq(t, `{"status":200}`, string(h.Entries[0].Result))
+
+	monitors, err := m.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, monitors, 1)
+	require.NoError(t, m.Delete(ctx, mon.ID))
+	assert.ErrorIs(t, m.Delete(ctx, mon.ID), ErrMonitorNotFound)
+	_, err = m.History(ctx, mon.ID, time.Time{}, time.Now(), false)
+	assert.ErrorIs(t, err, ErrMonitorNotFound)
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	var checks []Check
+	for i := 1; i <= 10; i++ {
+		checks = append(checks, Check{Time: at(i), Up: true, Latency: float64(i * 10)})
+	}
+	checks = append(checks,
+		Check{Time: at(11), Error: "timeout"},
+		Check{Time: at(12), Error: "refused"},
+		Check{Time: at(13), Up: true, Latency: 5},
+		Check{Time: at(14), Error: "timeout"},
+	)
+
+	h := summarize("m", start, at(20), checks)
+	assert.Equal(t, 14, h.Checks)
+	assert.Equal(t, 11, h.Up)
+	assert.Equal(t, 78.57, h.Uptime)
+	assert.Equal(t, &LatencyStats{Min: 5, P50: 50, P90: 90, P95: 100, P99: 100, Max: 100}, h.Latency)
+	require.Len(t, h.Incidents, 2)
+	assert.Equal(t, Incident{Start: at(11), End: &checks[12].Time, Duration: 120, Checks: 2, Error: "timeout"}, h.Incidents[0])
+	// the last incident is ongoing at the end of the period
+	assert.Nil(t, h.Incidents[1].End)
+	assert.Equal(t, 360.0, h.Incidents[1].Duration)
+
+	h = summarize("m", start, at(20), nil)
+	assert.Equal(t, 100.0, h.Uptime)
+	assert.Nil(t, h.Latency)
+	assert.Empty(t, h.Incidents)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Current == StateUp {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.URL)
+	event := Event{Monitor: Monitor{ID: "m", Name: "site"}, Previous: StateUp, Current: StateDown, Check: Check{Error: "refused"}}
+	require.NoError(t, n.Notify(context.Background(), event))
+	assert.Equal(t, "refused", received.Check.Error)
+	assert.Equal(t, "site", received.Monitor.Name)
+
+	event.Previous, event.Current = StateDown, StateUp
+	assert.Error(t, n.Notify(context.Background(), event))
+}
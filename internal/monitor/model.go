@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMonitorNotFound = errors.New("monitor not found")
+	ErrUnknownType     = errors.New("unknown monitor type")
+	ErrInvalidParams   = errors.New("invalid monitor parameters")
+	ErrInvalidInterval = errors.New("invalid monitor interval")
+)
+
+// Bounds and default of the interval between checks, in seconds
+const (
+	defaultInterval = 60
+	minInterval     = 5
+	maxInterval     = 86400
+)
+
+// State is the outcome of the latest check of a monitor
+type State string
+
+const (
+	StateUnknown State = "unknown"
+	StateUp      State = "up"
+	StateDown    State = "down"
+)
+
+// Monitor checks a host or URL on an interval with one of the site probes
+type Monitor struct {
+	ID   string `json:"id" example:"0b1e8f4e-5f3a-4d3c-9c43-6a1f2d9e7b10"`
+	Name string `json:"name" example:"Swagger UI"`
+	// the probe used: "ping", "dns", "port", "tls" or "http"
+	Type string `json:"type" example:"http"`
+	// the probe parameters, as for a job of the same type
+	Params json.RawMessage `json:"params" swaggertype:"object"`
+	// seconds between checks (5-86400, default 60)
+	Interval       int        `json:"interval" example:"60"`
+	State          State      `json:"state" example:"up"`
+	CreatedOn      time.Time  `json:"created_on"`
+	LastCheckedOn  *time.Time `json:"last_checked_on,omitempty"`
+	StateChangedOn *time.Time `json:"state_changed_on,omitempty"`
+}
+
+// Check is the outcome of one probe of a monitor
+type Check struct {
+	Time time.Time `json:"time"`
+	Up   bool      `json:"up"`
+	// latency measured by the probe in milliseconds
+	Latency float64         `json:"latency_ms,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+}
+
+// Outcome is what a probe found
+type Outcome struct {
+	Up bool
+	// milliseconds
+	Latency float64
+	// why the target is down
+	Error string
+	// the probe result, stored with the check
+	Result interface{}
+}
+
+// Probe checks a target of one type.
+type Probe interface {
+	// Prepare validates the parameters of a monitor when it is created and returns them with defaults applied.
+	Prepare(params json.RawMessage) (json.RawMessage, error)
+	// Check probes the target once. An error means that the probe couldn't run, which counts as down.
+	Check(ctx context.Context, params json.RawMessage) (Outcome, error)
+}
+
+// History summarizes the checks of a monitor over a period
+type History struct {
+	MonitorID string    `json:"monitor_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	// number of checks in the period
+	Checks int `json:"checks"`
+	Up     int `json:"up"`
+	// percentage of checks that were up, or 100 if there were no checks
+	Uptime float64 `json:"uptime_percent"`
+	// latency of the checks that were up, omitted if there were none
+	Latency *LatencyStats `json:"latency,omitempty"`
+	// periods during which the monitor was down, oldest first
+	Incidents []Incident `json:"incidents"`
+	// the checks in the period, oldest first, when requested
+	Entries []Check `json:"entries,omitempty"`
+}
+
+// LatencyStats holds latency percentiles in milliseconds
+type LatencyStats struct {
+	Min float64 `json:"min_ms"`
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P95 float64 `json:"p95_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// Incident is a period during which a monitor was down
+type Incident struct {
+	Start time.Time `json:"start"`
+	// the time of the first check that was up again, omitted while the incident is ongoing
+	End *time.Time `json:"end,omitempty"`
+	// seconds from the start to the end, or to the end of the period if ongoing
+	Duration float64 `json:"duration_seconds"`
+	// number of checks that were down
+	Checks int `json:"checks"`
+	// the error reported by the first check that was down
+	Error string `json:"error,omitempty"`
+}
+
+// Store persists monitors and their checks
+type Store interface {
+	Save(ctx context.Context, m Monitor) error
+	Get(ctx context.Context, id string) (Monitor, error)
+	List(ctx context.Context) ([]Monitor, error)
+	// Delete removes a monitor and its checks
+	Delete(ctx context.Context, id string) error
+	AddCheck(ctx context.Context, id string, check Check) error
+	// Checks returns the checks of a monitor made in [from, to), oldest first
+	Checks(ctx context.Context, id string, from, to time.Time) ([]Check, error)
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// Event reports that a monitor changed state
+type Event struct {
+	Monitor  Monitor `json:"monitor"`
+	Previous State   `json:"previous"`
+	Current  State   `json:"current"`
+	Check    Check   `json:"check"`
+}
+
+// Notifier is told when a monitor changes state.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// LogNotifier logs state changes, as a warning when a monitor goes down.
+type LogNotifier struct {
+	Logger log.Logger
+}
+
+func (n LogNotifier) Notify(ctx context.Context, event Event) error {
+	logger := n.Logger.With(ctx, "monitor_id", event.Monitor.ID)
+	if event.Current == StateDown {
+		logger.Warnf("Monitor %q is down: %s", event.Monitor.Name, event.Check.Error)
+	} else {
+		logger.Infof("Monitor %q is %s", event.Monitor.Name, event.Current)
+	}
+	return nil
+}
+
+// WebhookNotifier posts state changes as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url with a 10 second timeout.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
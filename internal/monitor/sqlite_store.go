@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SQLiteStore stores monitors and their checks in a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates the monitors and monitor_checks tables if needed. Check times are stored
+// in UTC so that they can be compared as text.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (Store, error) {
+	query := `
+    CREATE TABLE IF NOT EXISTS monitors (
+        id TEXT PRIMARY KEY,
+        name TEXT NOT NULL,
+        type TEXT NOT NULL,
+        params TEXT NOT NULL,
+        interval INTEGER NOT NULL,
+        state TEXT NOT NULL,
+        created_on DATETIME NOT NULL,
+        last_checked_on DATETIME,
+        state_changed_on DATETIME
+    );
+    CREATE TABLE IF NOT EXISTS monitor_checks (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        monitor_id TEXT NOT NULL,
+        time DATETIME NOT NULL,
+        up BOOLEAN NOT NULL,
+        latency REAL NOT NULL DEFAULT 0,
+        error TEXT NOT NULL DEFAULT '',
+        result TEXT
+    );
+    CREATE INDEX IF NOT EXISTS monitor_checks_time ON monitor_checks (monitor_id, time);
+    `
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, m Monitor) error {
+	_, err := s.db.ExecContext(ctx, `
+    INSERT INTO monitors (id, name, type, params, interval, state, created_on, last_checked_on, state_changed_on)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(id) DO UPDATE SET
+        name = excluded.name, params = excluded.params, interval = excluded.interval, state = excluded.state,
+        last_checked_on = excluded.last_checked_on, state_changed_on = excluded.state_changed_on
+    `, m.ID, m.Name, m.Type, string(m.Params), m.Interval, m.State, m.CreatedOn, m.LastCheckedOn, m.StateChangedOn)
+	return err
+}
+
+const monitorColumns = "id, name, type, params, interval, state, created_on, last_checked_on, state_changed_on"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMonitor(row scanner) (Monitor, error) {
+	var (
+		m              Monitor
+		params         string
+		lastCheckedOn  sql.NullTime
+		stateChangedOn sql.NullTime
+	)
+	err := row.Scan(&m.ID, &m.Name, &m.Type, &params, &m.Interval, &m.State, &m.CreatedOn, &lastCheckedOn, &stateChangedOn)
+	if err != nil {
+		return Monitor{}, err
+	}
+	m.Params = json.RawMessage(params)
+	if lastCheckedOn.Valid {
+		m.LastCheckedOn = &lastCheckedOn.Time
+	}
+	if stateChangedOn.Valid {
+		m.StateChangedOn = &stateChangedOn.Time
+	}
+	return m, nil
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (Monitor, error) {
+	m, err := scanMonitor(s.db.QueryRowContext(ctx, "SELECT "+monitorColumns+" FROM monitors WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Monitor{}, ErrMonitorNotFound
+	}
+	return m, err
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]Monitor, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+monitorColumns+" FROM monitors ORDER BY created_on")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	monitors := []Monitor{}
+	for rows.Next() {
+		m, err := scanMonitor(rows)
+		if err != nil {
+			return nil, err
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors, rows.Err()
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "DELETE FROM monitors WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMonitorNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM monitor_checks WHERE monitor_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) AddCheck(ctx context.Context, id string, check Check) error {
+	var result sql.NullString
+	if len(check.Result) > 0 {
+		result = sql.NullString{String: string(check.Result), Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, "INSERT INTO monitor_checks (monitor_id, time, up, latency, error, result) VALUES (?, ?, ?, ?, ?, ?)",
+		id, check.Time.UTC(), check.Up, check.Latency, check.Error, result)
+	return err
+}
+
+func (s *SQLiteStore) Checks(ctx context.Context, id string, from, to time.Time) ([]Check, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT time, up, latency, error, result FROM monitor_checks
+    WHERE monitor_id = ? AND time >= ? AND time < ? ORDER BY time, id`, id, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var checks []Check
+	for rows.Next() {
+		var (
+			check  Check
+			result sql.NullString
+		)
+		if err := rows.Scan(&check.Time, &check.Up, &check.Latency, &check.Error, &result); err != nil {
+			return nil, err
+		}
+		if result.Valid {
+			check.Result = json.RawMessage(result.String)
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
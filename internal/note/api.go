@@ -1,31 +1,55 @@
 package note
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"net/http"
 
 	model "github.com/fortify-presales/insecure-go-api/internal/models"
 )
 
+// StatusClientClosedRequest is the non-standard status code reported when the client
+// disconnects before the request completes.
+const StatusClientClosedRequest = 499
+
+// errorStatus maps request cancellation and timeouts to their own status codes and
+// returns fallback for any other error.
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
 // NoteHandler organizes HTTP handler functions for CRUD on Note entity
 type NoteHandler struct {
 	Repository Repository // interface for persistence
+	Feed       *Feed      // recent changes, streamed to clients
 }
 
-func MakeHTTPHandler(repo Repository) http.Handler {
+// MakeHTTPHandler builds the notes API handler. The change stream is registered when feed is not nil.
+func MakeHTTPHandler(repo Repository, feed *Feed) http.Handler {
 
 	// Iniitialize handlers
 	noteHandler := &NoteHandler{
 		Repository: repo, // Injecting dependency
+		Feed:       feed,
 	}
 
 	router := http.NewServeMux()
-	router.HandleFunc("GET /api/v1/notes", noteHandler.GetAll)
-	router.HandleFunc("GET /api/v1/notes/{id}", noteHandler.Get)
-	router.HandleFunc("POST /api/v1/notes", noteHandler.Post)
-	router.HandleFunc("PUT /api/v1/notes/{id}", noteHandler.Put)
-	router.HandleFunc("DELETE /api/v1/notes/{id}", noteHandler.Delete)
+	router.Handle("GET /api/v1/notes", acceptable(noteHandler.GetAll))
+	router.Handle("GET /api/v1/notes/{id}", acceptable(noteHandler.Get))
+	router.Handle("POST /api/v1/notes", acceptable(noteHandler.Post))
+	router.Handle("PUT /api/v1/notes/{id}", acceptable(noteHandler.Put))
+	router.Handle("DELETE /api/v1/notes/{id}", acceptable(noteHandler.Delete))
+	router.HandleFunc("GET /api/v1/notes/changes", noteHandler.Changes)
+	router.HandleFunc("POST /api/v1/notes/sync", noteHandler.Sync)
+	if feed != nil {
+		router.HandleFunc("GET /api/v1/notes/events", noteHandler.Events)
+	}
 
 	return router
 }
@@ -35,30 +59,31 @@ func MakeHTTPHandler(repo Repository) http.Handler {
 // @Summary      Create Note
 // @Description  Create a new Note
 // @Tags         notes
-// @Accept       json
-// @Produce      json
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
 // @Param		 Note	body		Note			true	"Note"
 // @Success      200  {object}  Note
 // @Failure      400  {object}  model.APIError
 // @Failure      404  {object}  model.APIError
+// @Failure      406  {object}  model.APIError
+// @Failure      415  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /notes/ [post]
 func (h *NoteHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var note Note
-	// Decode the incoming note json
-	err := json.NewDecoder(r.Body).Decode(&note)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Decode the incoming note
+	if err := decode(r, &note); err != nil {
+		respondError(w, r, decodeStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 
 	// Create note
-	if _, err := h.Repository.Create(note); err != nil {
+	if _, err := h.Repository.Create(r.Context(), note); err != nil {
 		if errors.Is(err, ErrNoteExists) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondError(w, r, errorStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -69,34 +94,28 @@ func (h *NoteHandler) Post(w http.ResponseWriter, r *http.Request) {
 // @Summary      Get Notes
 // @Description  Get all Notes
 // @Tags         notes
-// @Accept       json
-// @Produce      json
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
 // @Param        keywords    query     string  false  "search by keywords"  example(alphadex)
 // @Success      200  {array}  	Note
 // @Failure      400  {object}  model.APIError
 // @Failure      404  {object}  model.APIError
+// @Failure      406  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /notes [get]
 func (h *NoteHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	keywords := r.URL.Query().Get("keywords")
 	// Get all
-	if notes, err := h.Repository.GetAll(keywords); err != nil {
+	notes, err := h.Repository.GetAll(r.Context(), keywords)
+	if err != nil {
 		if errors.Is(err, model.ErrNotFound) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondError(w, r, errorStatus(err, http.StatusInternalServerError), err)
 		return
-
-	} else {
-		j, err := json.Marshal(notes)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(j)
 	}
+	respond(w, r, http.StatusOK, notes)
 }
 
 // Get handles HTTP Get with Id
@@ -104,34 +123,29 @@ func (h *NoteHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 // @Summary      Get Note
 // @Description  Get a Note
 // @Tags         notes
-// @Accept       json
-// @Produce      json
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
 // @Param		 id	path		string				true	"Note ID"
 // @Success      200  {object}  Note
 // @Failure      400  {object}  model.APIError
 // @Failure      404  {object}  model.APIError	"Could not find Note Id"
+// @Failure      406  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /notes/{id} [get]
 func (h *NoteHandler) Get(w http.ResponseWriter, r *http.Request) {
 	// Getting route parameter id
 	id := r.PathValue("id")
 	// Get by id
-	if note, err := h.Repository.GetById(id); err != nil {
+	note, err := h.Repository.GetById(r.Context(), id)
+	if err != nil {
 		if errors.Is(err, model.ErrNotFound) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondError(w, r, errorStatus(err, http.StatusInternalServerError), err)
 		return
-	} else {
-		w.Header().Set("Content-Type", "application/json")
-		j, err := json.Marshal(note)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(j)
 	}
+	respond(w, r, http.StatusOK, note)
 }
 
 // Put handles HTTP Put with Id
@@ -139,28 +153,29 @@ func (h *NoteHandler) Get(w http.ResponseWriter, r *http.Request) {
 // @Summary      Update Note
 // @Description  Update an existing Note
 // @Tags         notes
-// @Accept       json
-// @Produce      json
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
 // @Param		 id		path	string				true	"Note ID"
 // @Param		 Note	body	Note			true	"Note"
 // @Success      200  {object}  Note
 // @Failure      400  {object}  model.APIError
 // @Failure      404  {object}  model.APIError	"Could not find Note Id"
+// @Failure      406  {object}  model.APIError
+// @Failure      415  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /notes/{id} [put]
 func (h *NoteHandler) Put(w http.ResponseWriter, r *http.Request) {
 	// Getting route parameter id
 	id := r.PathValue("id")
 	var note Note
-	// Decode the incoming note json
-	err := json.NewDecoder(r.Body).Decode(&note)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Decode the incoming note
+	if err := decode(r, &note); err != nil {
+		respondError(w, r, decodeStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 	// Update
-	if err := h.Repository.Update(id, note); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Repository.Update(r.Context(), id, note); err != nil {
+		respondError(w, r, errorStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -171,20 +186,21 @@ func (h *NoteHandler) Put(w http.ResponseWriter, r *http.Request) {
 // @Summary      Delete Note
 // @Description  Delete a Note
 // @Tags         notes
-// @Accept       json
-// @Produce      json
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
 // @Param		 id		path	string				true	"Note ID"
 // @Success      200  {object}  model.APIMessage
 // @Failure      400  {object}  model.APIError
 // @Failure      404  {object}  model.APIError	"Could not find Note Id"
+// @Failure      406  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /notes/{id} [delete]
 func (h *NoteHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Getting route parameter id
 	id := r.PathValue("id")
 	// delete
-	if err := h.Repository.Delete(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Repository.Delete(r.Context(), id); err != nil {
+		respondError(w, r, errorStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
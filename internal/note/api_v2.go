@@ -0,0 +1,262 @@
+package note
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	model "github.com/fortify-presales/insecure-go-api/internal/models"
+)
+
+// v2Prefix is where version 2 of the notes API is served
+const v2Prefix = "/api/v2/notes"
+
+// NoteV2 is the version 2 representation of a note
+type NoteV2 struct {
+	ID          string    `json:"id" xml:"id" yaml:"id"`
+	Title       string    `json:"title" xml:"title" yaml:"title"`
+	Description string    `json:"description" xml:"description" yaml:"description"`
+	CreatedAt   time.Time `json:"createdAt" xml:"createdAt" yaml:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" xml:"updatedAt" yaml:"updatedAt"`
+	// changes with every update. Send it back with an update to make it conditional
+	Version uint64      `json:"version" xml:"version" yaml:"version"`
+	Links   NoteLinksV2 `json:"links" xml:"links" yaml:"links"`
+}
+
+// NoteLinksV2 are the links of a version 2 note
+type NoteLinksV2 struct {
+	Self string `json:"self" xml:"self" yaml:"self"`
+	// the notes collection
+	Collection string `json:"collection" xml:"collection" yaml:"collection"`
+}
+
+func (n NoteV2) csvHeader() []string {
+	return []string{"id", "title", "description", "createdAt", "updatedAt", "version", "self"}
+}
+
+func (n NoteV2) csvRecord() []string {
+	return []string{n.ID, n.Title, n.Description, n.CreatedAt.Format(time.RFC3339), n.UpdatedAt.Format(time.RFC3339),
+		strconv.FormatUint(n.Version, 10), n.Links.Self}
+}
+
+// NoteInputV2 is the body of a version 2 create or update
+type NoteInputV2 struct {
+	Title       string `json:"title" xml:"title" yaml:"title"`
+	Description string `json:"description" xml:"description" yaml:"description"`
+	// the version the update was made to. The update fails with 409 Conflict if the note has changed since.
+	// Unconditional when 0
+	Version uint64 `json:"version,omitempty" xml:"version,omitempty" yaml:"version,omitempty"`
+}
+
+func (in *NoteInputV2) setCSV(fields map[string]string) error {
+	in.Title = fields["title"]
+	in.Description = fields["description"]
+	if v := fields["version"]; v != "" {
+		version, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", v)
+		}
+		in.Version = version
+	}
+	return nil
+}
+
+// toV2 converts a note to its version 2 representation.
+func toV2(n Note) NoteV2 {
+	return NoteV2{
+		ID:          n.NoteID,
+		Title:       n.Title,
+		Description: n.Description,
+		CreatedAt:   n.CreatedOn,
+		UpdatedAt:   n.UpdatedOn,
+		Version:     n.Version,
+		Links: NoteLinksV2{
+			Self:       v2Prefix + "/" + n.NoteID,
+			Collection: v2Prefix,
+		},
+	}
+}
+
+// NoteV2Handler organizes HTTP handler functions for version 2 of the notes API
+type NoteV2Handler struct {
+	Repository Repository
+}
+
+// MakeV2HTTPHandler builds version 2 of the notes API. It shares the repository with version 1.
+func MakeV2HTTPHandler(repo Repository) http.Handler {
+	noteHandler := &NoteV2Handler{Repository: repo}
+
+	router := http.NewServeMux()
+	router.Handle("GET "+v2Prefix, acceptable(noteHandler.List))
+	router.Handle("GET "+v2Prefix+"/{id}", acceptable(noteHandler.Get))
+	router.Handle("POST "+v2Prefix, acceptable(noteHandler.Create))
+	router.Handle("PUT "+v2Prefix+"/{id}", acceptable(noteHandler.Update))
+	router.Handle("DELETE "+v2Prefix+"/{id}", acceptable(noteHandler.Delete))
+
+	return router
+}
+
+// List returns the notes whose title or description contains the keywords query parameter, or every note.
+//
+// @Summary      Get Notes (v2)
+// @Description  Get all Notes, or the Notes whose title or description contains the keywords
+// @Tags         notes-v2
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
+// @Param        keywords    query     string  false  "search by keywords"  example(alphadex)
+// @Success      200  {array}   NoteV2
+// @Failure      406  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /../v2/notes [get]
+func (h *NoteV2Handler) List(w http.ResponseWriter, r *http.Request) {
+	notes, err := h.Repository.GetAll(r.Context(), r.URL.Query().Get("keywords"))
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		respondError(w, r, errorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	list := make([]NoteV2, 0, len(notes))
+	for _, n := range notes {
+		list = append(list, toV2(n))
+	}
+	respond(w, r, http.StatusOK, list)
+}
+
+// Get returns a note.
+//
+// @Summary      Get Note (v2)
+// @Description  Get a Note
+// @Tags         notes-v2
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
+// @Param		 id	path		string				true	"Note ID"
+// @Success      200  {object}  NoteV2
+// @Failure      404  {object}  model.APIError	"Could not find Note Id"
+// @Failure      406  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /../v2/notes/{id} [get]
+func (h *NoteV2Handler) Get(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Repository.GetById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	respond(w, r, http.StatusOK, toV2(n))
+}
+
+// Create creates a note, returning it with its location.
+//
+// @Summary      Create Note (v2)
+// @Description  Create a new Note, returning it with its location
+// @Tags         notes-v2
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
+// @Param		 Note	body		NoteInputV2		true	"Note"
+// @Success      201  {object}  NoteV2
+// @Header       201  {string}  Location  "the URL of the Note"
+// @Failure      400  {object}  model.APIError
+// @Failure      406  {object}  model.APIError
+// @Failure      409  {object}  model.APIError	"Another Note has the title"
+// @Failure      415  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /../v2/notes [post]
+func (h *NoteV2Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var in NoteInputV2
+	if err := decode(r, &in); err != nil {
+		respondError(w, r, decodeStatus(err, http.StatusBadRequest), fmt.Errorf("invalid note: %w", err))
+		return
+	}
+	id, err := h.Repository.Create(r.Context(), Note{Title: in.Title, Description: in.Description})
+	if err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	n, err := h.Repository.GetById(r.Context(), id)
+	if err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Location", v2Prefix+"/"+id)
+	respond(w, r, http.StatusCreated, toV2(n))
+}
+
+// Update replaces the title and description of a note, returning the note. The update is
+// conditional when the body has a version.
+//
+// @Summary      Update Note (v2)
+// @Description  Update an existing Note
+// @Description  When the body has a version, the update fails with 409 Conflict if the Note has changed since
+// @Tags         notes-v2
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
+// @Param		 id		path	string				true	"Note ID"
+// @Param		 Note	body	NoteInputV2		true	"Note"
+// @Success      200  {object}  NoteV2
+// @Failure      400  {object}  model.APIError
+// @Failure      404  {object}  model.APIError	"Could not find Note Id"
+// @Failure      406  {object}  model.APIError
+// @Failure      409  {object}  model.APIError	"The Note has changed since the version"
+// @Failure      415  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /../v2/notes/{id} [put]
+func (h *NoteV2Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var in NoteInputV2
+	if err := decode(r, &in); err != nil {
+		respondError(w, r, decodeStatus(err, http.StatusBadRequest), fmt.Errorf("invalid note: %w", err))
+		return
+	}
+	current, err := h.Repository.GetById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	if in.Version == 0 {
+		in.Version = current.Version
+	}
+	n, err := h.Repository.Apply(r.Context(), SyncChange{NoteID: current.NoteID, BaseVersion: in.Version, Title: in.Title, Description: in.Description})
+	if err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	respond(w, r, http.StatusOK, toV2(n))
+}
+
+// Delete deletes a note.
+//
+// @Summary      Delete Note (v2)
+// @Description  Delete a Note
+// @Tags         notes-v2
+// @Accept       json,xml,application/yaml,text/csv
+// @Produce      json,xml,application/yaml,text/csv
+// @Param		 id		path	string				true	"Note ID"
+// @Success      204
+// @Failure      404  {object}  model.APIError	"Could not find Note Id"
+// @Failure      406  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /../v2/notes/{id} [delete]
+func (h *NoteV2Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := h.Repository.GetById(r.Context(), id); err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	if err := h.Repository.Delete(r.Context(), id); err != nil {
+		respondError(w, r, v2ErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v2ErrorStatus maps a repository error to a status code. Unlike version 1, a missing note is 404 Not Found.
+func v2ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNoteNotExists):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNoteExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusConflict
+	}
+	return errorStatus(err, http.StatusInternalServerError)
+}
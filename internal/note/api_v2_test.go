@@ -0,0 +1,60 @@
+package note
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteV2Handler(t *testing.T) {
+	handler := MakeV2HTTPHandler(newTestSQLiteRepository(t))
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return res
+	}
+
+	res := serve(http.MethodPost, "/api/v2/notes", `{"title":"zap","description":"zap is a logging package"}`)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var created NoteV2
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	assert.Equal(t, "/api/v2/notes/"+created.ID, res.Header().Get("Location"))
+	assert.Equal(t, "/api/v2/notes/"+created.ID, created.Links.Self)
+	assert.False(t, created.UpdatedAt.IsZero())
+	assert.NotZero(t, created.Version)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &fields))
+	assert.Contains(t, fields, "createdAt")
+	assert.NotContains(t, fields, "noteid")
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/api/v2/notes", `{"title":"zap"}`).Code)
+
+	res = serve(http.MethodPut, "/api/v2/notes/"+created.ID, fmt.Sprintf(`{"title":"zap","description":"updated","version":%d}`, created.Version))
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	var updated NoteV2
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &updated))
+	assert.Equal(t, "updated", updated.Description)
+	assert.Greater(t, updated.Version, created.Version)
+	res = serve(http.MethodPut, "/api/v2/notes/"+created.ID, fmt.Sprintf(`{"title":"zap","description":"stale","version":%d}`, created.Version))
+	assert.Equal(t, http.StatusConflict, res.Code, "the update was made to an old version")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/api/v2/notes/"+created.ID, `{"title":"zap","description":"unconditional"}`).Code)
+
+	res = serve(http.MethodGet, "/api/v2/notes?keywords=zap", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	var list []NoteV2
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "unconditional", list[0].Description)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/v2/notes/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v2/notes/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/api/v2/notes/"+created.ID, `{"title":"zap"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/api/v2/notes/"+created.ID, "").Code)
+	res = serve(http.MethodGet, "/api/v2/notes?keywords=zap", "")
+	assert.JSONEq(t, `[]`, res.Body.String())
+}
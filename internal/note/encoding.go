@@ -0,0 +1,264 @@
+package note
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	model "github.com/fortify-presales/insecure-go-api/internal/models"
+)
+
+// Media types of the note representations
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML  = "application/xml"
+	MediaTypeYAML = "application/yaml"
+	MediaTypeCSV  = "text/csv"
+)
+
+var (
+	ErrNotAcceptable        = errors.New("none of the accepted media types is supported: expecting application/json, application/xml, application/yaml or text/csv")
+	ErrUnsupportedMediaType = errors.New("unsupported content type: expecting application/json, application/xml, application/yaml or text/csv")
+)
+
+// mediaTypes maps the media types and their common aliases to the media type of the representation
+var mediaTypes = map[string]string{
+	MediaTypeJSON:        MediaTypeJSON,
+	MediaTypeXML:         MediaTypeXML,
+	"text/xml":           MediaTypeXML,
+	MediaTypeYAML:        MediaTypeYAML,
+	"application/x-yaml": MediaTypeYAML,
+	"text/yaml":          MediaTypeYAML,
+	MediaTypeCSV:         MediaTypeCSV,
+	// wildcards prefer JSON
+	"*/*":           MediaTypeJSON,
+	"application/*": MediaTypeJSON,
+	"text/*":        MediaTypeCSV,
+}
+
+// negotiate returns the media type of the representation best matching the Accept header of the request.
+// JSON is returned when there is no Accept header.
+func negotiate(r *http.Request) (string, error) {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(accept) == "" {
+		return MediaTypeJSON, nil
+	}
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if supported, ok := mediaTypes[mediaType]; ok && q > 0 {
+			candidates = append(candidates, candidate{supported, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrNotAcceptable
+	}
+	// the client's order breaks ties
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].mediaType, nil
+}
+
+// acceptable rejects a request accepting none of the representations with 406 Not Acceptable
+// before it is handled.
+func acceptable(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := negotiate(r); err != nil {
+			respondError(w, r, http.StatusNotAcceptable, err)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// respond writes v in the representation negotiated with the request, or a 406 Not Acceptable error
+// as JSON when the request accepts none of them.
+func respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	mediaType, err := negotiate(r)
+	if err != nil {
+		mediaType = MediaTypeJSON
+		status = http.StatusNotAcceptable
+		v = model.APIError{ErrorCode: status, ErrorMessage: err.Error()}
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Vary", "Accept")
+	w.WriteHeader(status)
+	encode(w, mediaType, v)
+}
+
+// respondError writes an error as a model.APIError in the representation negotiated with the request.
+func respondError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	respond(w, r, status, model.APIError{ErrorCode: status, ErrorMessage: err.Error()})
+}
+
+func encode(w io.Writer, mediaType string, v interface{}) error {
+	switch mediaType {
+	case MediaTypeXML:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		return enc.EncodeElement(xmlValue(v))
+	case MediaTypeYAML:
+		return yaml.NewEncoder(w).Encode(v)
+	case MediaTypeCSV:
+		return encodeCSV(w, v)
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// xmlList is the root element of a list
+type xmlList struct {
+	Items interface{} `xml:"note"`
+}
+
+// xmlValue returns the value to encode as XML and its root element: <notes> holding a <note> for each
+// note of a list, or <note> or <error>.
+func xmlValue(v interface{}) (interface{}, xml.StartElement) {
+	name := "note"
+	switch v.(type) {
+	case model.APIError:
+		name = "error"
+	default:
+		if reflect.ValueOf(v).Kind() == reflect.Slice {
+			return xmlList{Items: v}, xml.StartElement{Name: xml.Name{Local: "notes"}}
+		}
+	}
+	return v, xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// csvRow is a value with a CSV representation
+type csvRow interface {
+	csvHeader() []string
+	csvRecord() []string
+}
+
+// encodeCSV writes a header line followed by a record for the value, or for each element of a list.
+func encodeCSV(w io.Writer, v interface{}) error {
+	var rows []csvRow
+	switch v := v.(type) {
+	case model.APIError:
+		rows = append(rows, apiErrorRow(v))
+	case csvRow:
+		rows = append(rows, v)
+	default:
+		list := reflect.ValueOf(v)
+		if list.Kind() != reflect.Slice {
+			return fmt.Errorf("no CSV representation of %T", v)
+		}
+		for i := 0; i < list.Len(); i++ {
+			row, ok := list.Index(i).Interface().(csvRow)
+			if !ok {
+				return fmt.Errorf("no CSV representation of %T", v)
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) == 0 {
+			// write the header of an empty list
+			if row, ok := reflect.Zero(list.Type().Elem()).Interface().(csvRow); ok {
+				cw := csv.NewWriter(w)
+				cw.Write(row.csvHeader())
+				cw.Flush()
+				return cw.Error()
+			}
+		}
+	}
+	cw := csv.NewWriter(w)
+	for i, row := range rows {
+		if i == 0 {
+			cw.Write(row.csvHeader())
+		}
+		cw.Write(row.csvRecord())
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// apiErrorRow is the CSV representation of an error
+type apiErrorRow model.APIError
+
+func (e apiErrorRow) csvHeader() []string {
+	return []string{"ErrorCode", "ErrorMessage"}
+}
+
+func (e apiErrorRow) csvRecord() []string {
+	return []string{strconv.Itoa(e.ErrorCode), e.ErrorMessage}
+}
+
+// csvFields is a value that can be read from a CSV record, keyed by the header
+type csvFields interface {
+	setCSV(fields map[string]string) error
+}
+
+// decode reads the request body into v in the representation given by its Content-Type.
+// A body without a Content-Type is read as JSON.
+func decode(r *http.Request, v interface{}) error {
+	mediaType := MediaTypeJSON
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return ErrUnsupportedMediaType
+		}
+		supported, ok := mediaTypes[parsed]
+		if !ok || strings.Contains(parsed, "*") {
+			return ErrUnsupportedMediaType
+		}
+		mediaType = supported
+	}
+	switch mediaType {
+	case MediaTypeXML:
+		return xml.NewDecoder(r.Body).Decode(v)
+	case MediaTypeYAML:
+		return yaml.NewDecoder(r.Body).Decode(v)
+	case MediaTypeCSV:
+		target, ok := v.(csvFields)
+		if !ok {
+			return ErrUnsupportedMediaType
+		}
+		records, err := csv.NewReader(r.Body).ReadAll()
+		if err != nil {
+			return err
+		}
+		if len(records) != 2 {
+			return errors.New("expecting a CSV header and a single record")
+		}
+		fields := make(map[string]string)
+		for i, name := range records[0] {
+			fields[strings.TrimSpace(name)] = records[1][i]
+		}
+		return target.setCSV(fields)
+	}
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// decodeStatus maps a decoding error to a status code, returning fallback for a malformed body.
+func decodeStatus(err error, fallback int) int {
+	if errors.Is(err, ErrUnsupportedMediaType) {
+		return http.StatusUnsupportedMediaType
+	}
+	return fallback
+}
@@ -0,0 +1,137 @@
+package note
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	model "github.com/fortify-presales/insecure-go-api/internal/models"
+)
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                                     MediaTypeJSON,
+		"*/*":                                  MediaTypeJSON,
+		"application/xml":                      MediaTypeXML,
+		"text/xml":                             MediaTypeXML,
+		"application/x-yaml":                   MediaTypeYAML,
+		"text/csv; charset=utf-8":              MediaTypeCSV,
+		"text/html, text/csv;q=0.5, */*;q=0.1": MediaTypeCSV,
+		"application/json;q=0.5, application/yaml": MediaTypeYAML,
+		"application/xml, application/yaml":        MediaTypeXML,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		got, err := negotiate(r)
+		require.NoError(t, err, accept)
+		assert.Equal(t, want, got, accept)
+	}
+	for _, accept := range []string{"image/png", "application/json;q=0", "text/html"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		_, err := negotiate(r)
+		assert.ErrorIs(t, err, ErrNotAcceptable, accept)
+	}
+}
+
+func TestNoteHandler_Encoding(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+	v1 := MakeHTTPHandler(repo, nil)
+	v2 := MakeV2HTTPHandler(repo)
+	serve := func(handler http.Handler, method, target, accept, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	// request bodies
+	res := serve(v1, http.MethodPost, "/api/v1/notes", "", "application/xml", `<note><title>zap</title><description>zap is a logging package</description></note>`)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	res = serve(v1, http.MethodPost, "/api/v1/notes", "", "application/yaml", "title: cobra\ndescription: cobra is a CLI package\n")
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	res = serve(v2, http.MethodPost, "/api/v2/notes", "", "text/csv", "title,description\nchi,\"chi is a router, for net/http\"\n")
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	res = serve(v1, http.MethodPost, "/api/v1/notes", "", "text/plain", "title: gin")
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.Code)
+	res = serve(v2, http.MethodPost, "/api/v2/notes", "", "text/csv", "title,description\ngin,one\necho,two\n")
+	assert.Equal(t, http.StatusBadRequest, res.Code, "a CSV body holds a single note")
+	res = serve(v1, http.MethodPost, "/api/v1/notes", "image/png", "", `{"title":"fiber"}`)
+	assert.Equal(t, http.StatusNotAcceptable, res.Code)
+	assert.Equal(t, MediaTypeJSON, res.Header().Get("Content-Type"))
+	res = serve(v2, http.MethodGet, "/api/v2/notes?keywords=fiber", "", "", "")
+	assert.JSONEq(t, `[]`, res.Body.String(), "a request that is not acceptable is not handled")
+
+	// XML
+	res = serve(v1, http.MethodGet, "/api/v1/notes?keywords=zap", "application/xml", "", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, MediaTypeXML, res.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", res.Header().Get("Vary"))
+	var xmlNotes struct {
+		XMLName xml.Name `xml:"notes"`
+		Notes   []Note   `xml:"note"`
+	}
+	require.NoError(t, xml.Unmarshal(res.Body.Bytes(), &xmlNotes))
+	require.Len(t, xmlNotes.Notes, 1)
+	zap := xmlNotes.Notes[0]
+	assert.Equal(t, "zap is a logging package", zap.Description)
+	res = serve(v1, http.MethodGet, "/api/v1/notes/"+zap.NoteID, "text/xml", "", "")
+	var xmlNote Note
+	require.NoError(t, xml.Unmarshal(res.Body.Bytes(), &xmlNote))
+	assert.Equal(t, zap.NoteID, xmlNote.NoteID)
+	res = serve(v2, http.MethodGet, "/api/v2/notes/missing", "application/xml", "", "")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Contains(t, res.Body.String(), "<error>")
+
+	// YAML
+	res = serve(v2, http.MethodPut, "/api/v2/notes/"+zap.NoteID, "application/yaml", "application/yaml", "title: zap\ndescription: updated\n")
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	assert.Equal(t, MediaTypeYAML, res.Header().Get("Content-Type"))
+	var yamlNote NoteV2
+	require.NoError(t, yaml.Unmarshal(res.Body.Bytes(), &yamlNote))
+	assert.Equal(t, "updated", yamlNote.Description)
+	assert.Equal(t, "/api/v2/notes/"+zap.NoteID, yamlNote.Links.Self)
+	res = serve(v1, http.MethodGet, "/api/v1/notes/missing", "application/yaml", "", "")
+	var yamlError model.APIError
+	require.NoError(t, yaml.Unmarshal(res.Body.Bytes(), &yamlError))
+	assert.Equal(t, res.Code, yamlError.ErrorCode)
+
+	// CSV
+	res = serve(v2, http.MethodGet, "/api/v2/notes?keywords=chi", "text/csv", "", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, MediaTypeCSV, res.Header().Get("Content-Type"))
+	records, err := csv.NewReader(res.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"id", "title", "description", "createdAt", "updatedAt", "version", "self"}, records[0])
+	assert.Equal(t, "chi is a router, for net/http", records[1][2])
+	res = serve(v2, http.MethodGet, "/api/v2/notes?keywords=fiber", "text/csv", "", "")
+	assert.Equal(t, "id,title,description,createdAt,updatedAt,version,self\n", res.Body.String(), "an empty list has a header")
+	res = serve(v1, http.MethodGet, "/api/v1/notes/"+zap.NoteID, "text/csv", "", "")
+	records, err = csv.NewReader(res.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"noteid", "title", "description", "createdon"}, records[0], "version 1 is frozen")
+	for _, accept := range []string{"application/json", "application/xml", "application/yaml"} {
+		res = serve(v1, http.MethodGet, "/api/v1/notes/"+zap.NoteID, accept, "", "")
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.NotRegexp(t, `"version"|<version>|\nversion:`, res.Body.String(), accept)
+	}
+	res = serve(v2, http.MethodPut, "/api/v2/notes/"+zap.NoteID, "text/csv", "application/json", `{"title":"zap","version":1}`)
+	assert.Equal(t, http.StatusConflict, res.Code)
+	assert.True(t, strings.HasPrefix(res.Body.String(), "ErrorCode,ErrorMessage\n409,"), res.Body.String())
+}
@@ -0,0 +1,231 @@
+package note
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// feedSubscriberBuffer is the number of changes queued for a subscriber before it is disconnected.
+	feedSubscriberBuffer = 64
+	// feedHeartbeatInterval is how often a comment is sent to idle event streams to keep connections open.
+	feedHeartbeatInterval = 15 * time.Second
+)
+
+// Change is a note change event kept by a Feed
+type Change struct {
+	ID uint64 `json:"id"`
+	// created, updated or deleted
+	Type string `json:"type" example:"created"`
+	// the note after the change, or before it was deleted
+	Note Note      `json:"note"`
+	Time time.Time `json:"time"`
+}
+
+// Match reports whether the title or description of the changed note contains the keywords, ignoring case.
+func (c Change) Match(keywords string) bool {
+	keywords = strings.ToLower(keywords)
+	return strings.Contains(strings.ToLower(c.Note.Title), keywords) ||
+		strings.Contains(strings.ToLower(c.Note.Description), keywords)
+}
+
+// Feed keeps the most recent note changes in memory so that they can be streamed over HTTP.
+// Feed a repository with OnChange(feed.Publish).
+type Feed struct {
+	mu      sync.Mutex
+	changes []Change
+	next    int
+	full    bool
+	seq     uint64
+	subs    map[chan Change]struct{}
+
+	heartbeat time.Duration
+}
+
+// NewFeed creates a feed that keeps the last size changes.
+func NewFeed(size int) *Feed {
+	if size < 1 {
+		size = 1
+	}
+	return &Feed{
+		changes:   make([]Change, size),
+		subs:      make(map[chan Change]struct{}),
+		heartbeat: feedHeartbeatInterval,
+	}
+}
+
+// Publish adds a change to the feed and sends it to the subscribers. A subscriber that has fallen
+// behind is disconnected, so that it can resume from the backlog rather than miss changes.
+func (f *Feed) Publish(event Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	c := Change{
+		ID:   f.seq,
+		Type: strings.TrimPrefix(event.Type, "note."),
+		Note: event.Note,
+		Time: event.Time,
+	}
+	f.changes[f.next] = c
+	f.next = (f.next + 1) % len(f.changes)
+	if f.next == 0 {
+		f.full = true
+	}
+	for ch := range f.subs {
+		select {
+		case ch <- c:
+		default: // never block a note change on a slow subscriber
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscription receives the changes published to a Feed
+type Subscription struct {
+	// the changes after the resumed ID, oldest first
+	Backlog []Change
+	// false when the changes after the resumed ID are no longer kept, or the ID is unknown
+	Resumed bool
+	// the ID of the latest change when subscribing
+	Last uint64
+	// receives every change published afterwards. Closed if the subscriber falls behind
+	Changes <-chan Change
+	cancel  func()
+}
+
+// Cancel unsubscribes.
+func (s Subscription) Cancel() {
+	s.cancel()
+}
+
+// Subscribe subscribes to the changes published from now on.
+func (f *Feed) Subscribe() Subscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.subscribeLocked(nil, true)
+}
+
+// Resume subscribes to the changes after the one with the given ID, returning those still kept
+// as the backlog. There is no backlog when changes after the ID are no longer kept.
+func (f *Feed) Resume(after uint64) Subscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ordered []Change
+	if f.full {
+		ordered = append(ordered, f.changes[f.next:]...)
+	}
+	ordered = append(ordered, f.changes[:f.next]...)
+	// the changes kept are the latest, numbered up to seq
+	kept := f.seq - uint64(len(ordered))
+	if after > f.seq || after < kept {
+		return f.subscribeLocked(nil, false)
+	}
+	return f.subscribeLocked(ordered[after-kept:], true)
+}
+
+func (f *Feed) subscribeLocked(backlog []Change, resumed bool) Subscription {
+	ch := make(chan Change, feedSubscriberBuffer)
+	f.subs[ch] = struct{}{}
+	return Subscription{
+		Backlog: backlog,
+		Resumed: resumed,
+		Last:    f.seq,
+		Changes: ch,
+		cancel: func() {
+			f.mu.Lock()
+			delete(f.subs, ch)
+			f.mu.Unlock()
+		},
+	}
+}
+
+// Events handles HTTP Get of the note change stream
+//
+// @Summary      Stream Note Changes
+// @Description  Stream note changes as Server-Sent Events named created, updated and deleted, with the change as data
+// @Description  A client reconnecting with the Last-Event-ID header (or lastEventId parameter) resumes after that change.
+// @Description  A reset event is sent instead when the changes since then are no longer kept, after which the notes should be fetched again
+// @Tags         notes
+// @Produce      text/event-stream
+// @Param        keywords     query     string  false  "only changes to notes whose title or description contains the keywords"
+// @Param        lastEventId  query     string  false  "resume after this event ID"
+// @Param        Last-Event-ID  header  string  false  "resume after this event ID"
+// @Success      200  {object}  Change
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /notes/events [get]
+func (h *NoteHandler) Events(w http.ResponseWriter, r *http.Request) {
+	keywords := r.URL.Query().Get("keywords")
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+	var after uint64
+	if lastID != "" {
+		var err error
+		if after, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var sub Subscription
+	if lastID != "" {
+		sub = h.Feed.Resume(after)
+	} else {
+		sub = h.Feed.Subscribe()
+	}
+	defer sub.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(c Change) {
+		if keywords != "" && !c.Match(keywords) {
+			return
+		}
+		data, _ := json.Marshal(c)
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.ID, c.Type, data)
+	}
+	if !sub.Resumed {
+		// resume from the latest change once the notes are fetched again
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", sub.Last)
+	}
+	for _, c := range sub.Backlog {
+		send(c)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.Feed.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-sub.Changes:
+			if !ok {
+				// fell behind: the client reconnects and resumes from the backlog
+				return
+			}
+			send(c)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
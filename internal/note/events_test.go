@@ -0,0 +1,176 @@
+package note
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestFeed(t *testing.T) {
+	feed := NewFeed(2)
+	logger, _ := log.NewForTest()
+	repo, err := NewInmemoryRepository(logger, OnChange(feed.Publish))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	sub := feed.Resume(0)
+	defer sub.Cancel()
+	assert.True(t, sub.Resumed)
+	assert.Empty(t, sub.Backlog)
+
+	id, err := repo.Create(ctx, Note{Title: "zap", Description: "zap is a logging package"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Update(ctx, id, Note{Title: "zap", Description: "updated"}))
+	require.NoError(t, repo.Delete(ctx, id))
+	assert.ErrorIs(t, repo.Delete(ctx, id), ErrNoteNotExists, "no change is published for a failed delete")
+
+	var types []string
+	for range 3 {
+		c := <-sub.Changes
+		assert.Equal(t, id, c.Note.NoteID)
+		types = append(types, c.Type)
+	}
+	assert.Equal(t, []string{"created", "updated", "deleted"}, types)
+
+	resumed := feed.Resume(1)
+	defer resumed.Cancel()
+	assert.True(t, resumed.Resumed)
+	require.Len(t, resumed.Backlog, 2)
+	assert.Equal(t, uint64(2), resumed.Backlog[0].ID)
+	assert.Equal(t, "updated", resumed.Backlog[0].Note.Description)
+
+	assert.True(t, feed.Resume(3).Resumed)
+	assert.Empty(t, feed.Resume(3).Backlog)
+	assert.False(t, feed.Resume(0).Resumed, "the first change is no longer kept")
+	assert.False(t, feed.Resume(4).Resumed, "an ID from before a restart is unknown")
+	assert.Equal(t, uint64(3), feed.Resume(4).Last)
+
+	slow := feed.Subscribe()
+	for range feedSubscriberBuffer + 1 {
+		feed.Publish(Event{Type: EventCreated})
+	}
+	for range slow.Changes {
+	}
+	_, ok := <-slow.Changes
+	assert.False(t, ok, "a subscriber that falls behind is disconnected")
+}
+
+// sseEvent is an event or comment read from a Server-Sent Events stream
+type sseEvent struct {
+	id, event, data, comment string
+}
+
+// readEvents returns a function reading the next event or comment from a stream.
+func readEvents(t *testing.T, res *http.Response) func() sseEvent {
+	scanner := bufio.NewScanner(res.Body)
+	return func() sseEvent {
+		var e sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				return e
+			case strings.HasPrefix(line, ":"):
+				e.comment = strings.TrimSpace(line[1:])
+			default:
+				field, value, _ := strings.Cut(line, ": ")
+				switch field {
+				case "id":
+					e.id = value
+				case "event":
+					e.event = value
+				case "data":
+					e.data = value
+				}
+			}
+		}
+		require.NoError(t, scanner.Err())
+		t.Fatal("stream ended")
+		return e
+	}
+}
+
+func TestNoteHandler_Events(t *testing.T) {
+	feed := NewFeed(4)
+	feed.heartbeat = 50 * time.Millisecond
+	repo := newTestSQLiteRepository(t, OnChange(feed.Publish))
+	srv := httptest.NewServer(MakeHTTPHandler(repo, feed))
+	// close the server after the streams
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+	// the notes the repository is populated with are published too
+	seeded := feed.Subscribe().Last
+	id := func(n uint64) string { return strconv.FormatUint(seeded+n, 10) }
+
+	stream := func(query, lastID string) (*http.Response, func() sseEvent) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/notes/events"+query, nil)
+		require.NoError(t, err)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		read := readEvents(t, res)
+		// skip heartbeats
+		return res, func() sseEvent {
+			for {
+				if e := read(); e.comment == "" {
+					return e
+				}
+			}
+		}
+	}
+
+	res, err := http.Get(srv.URL + "/api/v1/notes/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "heartbeat", readEvents(t, res)().comment, "an idle stream is sent heartbeats")
+
+	res, next := stream("?keywords=ZAP", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	_, err = repo.Create(ctx, Note{Title: "cobra", Description: "cobra is a CLI package"})
+	require.NoError(t, err)
+	noteID, err := repo.Create(ctx, Note{Title: "zap", Description: "zap is a logging package"})
+	require.NoError(t, err)
+	e := next()
+	assert.Equal(t, id(2), e.id, "changes to other notes are filtered out")
+	assert.Equal(t, "created", e.event)
+	var c Change
+	require.NoError(t, json.Unmarshal([]byte(e.data), &c))
+	assert.Equal(t, noteID, c.Note.NoteID)
+	assert.Equal(t, "zap", c.Note.Title)
+
+	require.NoError(t, repo.Update(ctx, noteID, Note{Title: "zap", Description: "updated"}))
+	require.NoError(t, repo.Delete(ctx, noteID))
+	_, next = stream("", id(1))
+	for _, want := range []string{"created", "updated", "deleted"} {
+		assert.Equal(t, want, next().event)
+	}
+	_, next = stream("?lastEventId="+id(2), "")
+	assert.Equal(t, id(3), next().id)
+
+	for _, title := range []string{"chi", "gin", "echo", "fiber"} {
+		_, err = repo.Create(ctx, Note{Title: title, Description: title + " is a web framework"})
+		require.NoError(t, err)
+	}
+	_, next = stream("", id(1))
+	e = next()
+	assert.Equal(t, "reset", e.event, "the changes after the first are no longer kept")
+	assert.Equal(t, id(8), e.id)
+
+	res, _ = stream("", "latest")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -2,7 +2,10 @@ package note
 
 import (
 	// internal
+	"cmp"
+	"context"
 	"errors"
+	"slices"
 	"time"
 
 	// external
@@ -10,23 +13,34 @@ import (
 
 	"github.com/fortify-presales/insecure-go-api/internal/models"
 	"github.com/fortify-presales/insecure-go-api/pkg/log"
-
 )
 
 // inmemoryRepository provides concrete implementation for repository interface
 type inmemoryRepository struct {
-	noteStore map[string]Note
+	noteStore  map[string]Note
+	tombstones map[string]Tombstone
+	// the change sequence
+	epoch  string
+	seq    uint64
 	logger log.Logger
+	options
 }
 
-func NewInmemoryRepository(logger log.Logger) (Repository, error) {
+func NewInmemoryRepository(logger log.Logger, opts ...Option) (Repository, error) {
+	epoch, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
 	return &inmemoryRepository{
-		noteStore: make(map[string]Note),
-		logger: logger,
+		noteStore:  make(map[string]Note),
+		tombstones: make(map[string]Tombstone),
+		epoch:      epoch.String(),
+		logger:     logger,
+		options:    newOptions(opts),
 	}, nil
 }
 
-func (i *inmemoryRepository) Populate() error{
+func (i *inmemoryRepository) Populate(ctx context.Context) error {
 	note1 := Note{
 		NoteID:      "1",
 		Title:       "slog",
@@ -39,8 +53,8 @@ func (i *inmemoryRepository) Populate() error{
 		Description: "viper is a configuration management package",
 		CreatedOn:   time.Now(),
 	}
-	i.Create(note1)
-	i.Create(note2)
+	i.Create(ctx, note1)
+	i.Create(ctx, note2)
 	return nil
 }
 
@@ -52,38 +66,81 @@ func (i *inmemoryRepository) isNoteTitleExists(title string) bool {
 	}
 	return false
 }
-func (i *inmemoryRepository) Create(n Note) (string, error) {
+func (i *inmemoryRepository) Create(ctx context.Context, n Note) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	if _, ok := i.noteStore[n.NoteID]; ok {
 		return "", errors.New("NoteID exists")
 	}
 	if i.isNoteTitleExists(n.Title) {
 		return "", ErrNoteExists
 	}
-	n.CreatedOn = time.Now()
 	// Create a Version 4 UUID.
 	uid, _ := uuid.NewV4()
-	n.NoteID = uid.String()
-	i.noteStore[n.NoteID] = n
-	return n.NoteID, nil
+	return i.insert(uid.String(), n).NoteID, nil
+}
+
+// insert creates a note with the given ID, replacing the tombstone of a note deleted with that ID.
+func (i *inmemoryRepository) insert(id string, n Note) Note {
+	i.seq++
+	n.NoteID = id
+	n.CreatedOn = time.Now()
+	n.UpdatedOn = n.CreatedOn
+	n.Version = i.seq
+	i.noteStore[id] = n
+	delete(i.tombstones, id)
+	i.changed(Event{Type: EventCreated, Note: n, Time: time.Now()})
+	return n
+}
+
+// update replaces the title and description of a note.
+func (i *inmemoryRepository) update(id string, n Note) Note {
+	i.seq++
+	n.NoteID = id
+	n.CreatedOn = i.noteStore[id].CreatedOn
+	n.UpdatedOn = time.Now()
+	n.Version = i.seq
+	i.noteStore[id] = n
+	i.changed(Event{Type: EventUpdated, Note: n, Time: time.Now()})
+	return n
+}
+
+// remove deletes a note, leaving a tombstone.
+func (i *inmemoryRepository) remove(n Note) {
+	i.seq++
+	n.Version = i.seq
+	delete(i.noteStore, n.NoteID)
+	i.tombstones[n.NoteID] = Tombstone{NoteID: n.NoteID, Version: n.Version, DeletedOn: time.Now()}
+	i.changed(Event{Type: EventDeleted, Note: n, Time: time.Now()})
 }
 
-func (i *inmemoryRepository) Update(id string, n Note) error {
+func (i *inmemoryRepository) Update(ctx context.Context, id string, n Note) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if _, ok := i.noteStore[id]; !ok {
 		return ErrNoteNotExists
 	}
-	n.CreatedOn = time.Now()
-	i.noteStore[id] = n
+	i.update(id, n)
 	return nil
 }
 
-func (i *inmemoryRepository) Delete(id string) error {
-	if _, ok := i.noteStore[id]; !ok {
+func (i *inmemoryRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	n, ok := i.noteStore[id]
+	if !ok {
 		return ErrNoteNotExists
 	}
-	delete(i.noteStore, id)
+	i.remove(n)
 	return nil
 }
-func (i *inmemoryRepository) GetById(id string) (Note, error) {
+func (i *inmemoryRepository) GetById(ctx context.Context, id string) (Note, error) {
+	if err := ctx.Err(); err != nil {
+		return Note{}, err
+	}
 	if v, ok := i.noteStore[id]; !ok {
 		return Note{}, ErrNoteNotExists
 	} else {
@@ -92,7 +149,10 @@ func (i *inmemoryRepository) GetById(id string) (Note, error) {
 
 }
 
-func (i *inmemoryRepository) GetAll(query string) ([]Note, error) {
+func (i *inmemoryRepository) GetAll(ctx context.Context, query string) ([]Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if len(i.noteStore) == 0 {
 		return nil, model.ErrNotFound
 	}
@@ -102,3 +162,66 @@ func (i *inmemoryRepository) GetAll(query string) ([]Note, error) {
 	}
 	return notes, nil
 }
+
+func (i *inmemoryRepository) Stats(ctx context.Context) (Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return Stats{}, err
+	}
+	return Stats{Notes: len(i.noteStore)}, nil
+}
+
+func (i *inmemoryRepository) Changes(ctx context.Context, since uint64, limit int) (ChangeSet, error) {
+	if err := ctx.Err(); err != nil {
+		return ChangeSet{}, err
+	}
+	var upserts []Note
+	for _, n := range i.noteStore {
+		if n.Version > since {
+			upserts = append(upserts, n)
+		}
+	}
+	slices.SortFunc(upserts, func(a, b Note) int { return cmp.Compare(a.Version, b.Version) })
+	var tombstones []Tombstone
+	for _, t := range i.tombstones {
+		if t.Version > since {
+			tombstones = append(tombstones, t)
+		}
+	}
+	slices.SortFunc(tombstones, func(a, b Tombstone) int { return cmp.Compare(a.Version, b.Version) })
+	return mergeChanges(ChangeSet{Version: since, Latest: i.seq, Epoch: i.epoch}, upserts, tombstones, limit), nil
+}
+
+func (i *inmemoryRepository) Apply(ctx context.Context, c SyncChange) (Note, error) {
+	if err := ctx.Err(); err != nil {
+		return Note{}, err
+	}
+	current := i.noteStore[c.NoteID]
+	n := Note{Title: c.Title, Description: c.Description}
+	switch {
+	case current.Version != c.BaseVersion:
+		return current, ErrVersionConflict
+	case current.NoteID == "" && c.Deleted:
+		// never created, so nothing to delete
+		return Note{}, nil
+	case c.Deleted:
+		i.remove(current)
+		return Note{}, nil
+	}
+	for _, v := range i.noteStore {
+		if v.Title == n.Title && v.NoteID != current.NoteID {
+			return Note{}, ErrNoteExists
+		}
+	}
+	if current.NoteID == "" {
+		id := c.NoteID
+		if id == "" {
+			uid, err := uuid.NewV4()
+			if err != nil {
+				return Note{}, err
+			}
+			id = uid.String()
+		}
+		return i.insert(id, n), nil
+	}
+	return i.update(current.NoteID, n), nil
+}
@@ -1,6 +1,8 @@
 package note
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"time"
 )
@@ -10,21 +12,154 @@ import (
 var (
 	ErrNoteExists          = errors.New("note title exists")
 	ErrNoteNotExists error = errors.New("note doesn't exist")
+	// the note was changed since the version a client change is based on
+	ErrVersionConflict = errors.New("note version conflict")
 )
 
 type Note struct {
-	NoteID      string    `json:"noteid,omitempty"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CreatedOn   time.Time `json:"createdon,omitempty"`
+	NoteID      string    `json:"noteid,omitempty" xml:"noteid,omitempty" yaml:"noteid,omitempty"`
+	Title       string    `json:"title" xml:"title" yaml:"title"`
+	Description string    `json:"description" xml:"description" yaml:"description"`
+	CreatedOn   time.Time `json:"createdon,omitempty" xml:"createdon,omitempty" yaml:"createdon,omitempty"`
+	// when the note was last changed. Left out of the v1 representation, which is frozen
+	UpdatedOn time.Time `json:"-" xml:"-" yaml:"-"`
+	// the position of the latest change to the note in the repository change sequence.
+	// Left out of the v1 representation too: it is returned by version 2 and the sync protocol
+	Version uint64 `json:"-" xml:"-" yaml:"-"`
+}
+
+func (n Note) csvHeader() []string {
+	return []string{"noteid", "title", "description", "createdon"}
+}
+
+func (n Note) csvRecord() []string {
+	return []string{n.NoteID, n.Title, n.Description, n.CreatedOn.Format(time.RFC3339)}
+}
+
+func (n *Note) setCSV(fields map[string]string) error {
+	n.NoteID = fields["noteid"]
+	n.Title = fields["title"]
+	n.Description = fields["description"]
+	return nil
+}
+
+// Tombstone records the deletion of a note
+type Tombstone struct {
+	NoteID string `json:"noteid"`
+	// the position of the deletion in the repository change sequence
+	Version   uint64    `json:"version"`
+	DeletedOn time.Time `json:"deletedon"`
+}
+
+// ChangeSet is the notes changed and deleted after a position in the change sequence, in sequence order
+type ChangeSet struct {
+	Upserts    []Note
+	Tombstones []Tombstone
+	// the position of the last change in the set, or the position it was read from when it is empty
+	Version uint64
+	// more changes follow the set
+	More bool
+	// the position of the latest change in the repository
+	Latest uint64
+	// identifies the change sequence, which starts again when the repository is re-created
+	Epoch string
+}
+
+// SyncChange is a change made by an offline client to the version of a note it last saw
+type SyncChange struct {
+	// the note changed. Creates a note when the note is not known and BaseVersion is 0,
+	// with a new ID when empty
+	NoteID string `json:"noteid,omitempty"`
+	// the version of the note the change was made to, 0 for a new note
+	BaseVersion uint64 `json:"base_version"`
+	// delete rather than update the note
+	Deleted     bool   `json:"deleted,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Types of note change events
+const (
+	EventCreated = "note.created"
+	EventUpdated = "note.updated"
+	EventDeleted = "note.deleted"
+)
+
+// Event describes a change to a note
+type Event struct {
+	Type string
+	// the note after the change, or before it was deleted
+	Note Note
+	Time time.Time
+}
+
+// Outbox records note change events in the transaction making the change, so that an event
+// is recorded if and only if the change is committed.
+type Outbox interface {
+	Enqueue(ctx context.Context, tx *sql.Tx, event Event) error
+}
+
+// Option configures a Repository
+type Option func(*options)
+
+type options struct {
+	outbox Outbox
+	hooks  []func(Event)
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// changed calls the change hooks.
+func (o options) changed(event Event) {
+	for _, hook := range o.hooks {
+		hook(event)
+	}
+}
+
+// WithOutbox records an event in the outbox for every note created, updated or deleted.
+// Only the SQLite repository supports an outbox, as it needs a transaction.
+func WithOutbox(outbox Outbox) Option {
+	return func(o *options) {
+		o.outbox = outbox
+	}
+}
+
+// OnChange calls hook after every note is created, updated or deleted.
+func OnChange(hook func(Event)) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hook)
+	}
+}
+
+// Stats describes the contents of a repository and, for database backed repositories, its connection pool.
+type Stats struct {
+	Notes    int          `json:"notes"`
+	Database *sql.DBStats `json:"database,omitempty"`
 }
 
 // CRUD interface
+//
+// Every method takes a context so that work is abandoned when the caller
+// (typically the HTTP request) is cancelled or times out.
 type Repository interface {
-	Populate() error
-	Create(Note) (string, error)
-	Update(string, Note) error
-	Delete(string) error
-	GetById(string) (Note, error)
-	GetAll(string) ([]Note, error)
+	Populate(context.Context) error
+	Create(context.Context, Note) (string, error)
+	Update(context.Context, string, Note) error
+	Delete(context.Context, string) error
+	GetById(context.Context, string) (Note, error)
+	GetAll(context.Context, string) ([]Note, error)
+	Stats(context.Context) (Stats, error)
+	// Changes returns at most limit changes after the given position in the change sequence.
+	// Only the latest change to each note is returned.
+	Changes(ctx context.Context, since uint64, limit int) (ChangeSet, error)
+	// Apply makes a client change if the note is still at its base version, returning the note
+	// after the change. Otherwise it returns ErrVersionConflict with the note as it is, which is
+	// empty if the note was deleted.
+	Apply(context.Context, SyncChange) (Note, error)
 }
@@ -2,8 +2,10 @@ package note
 
 import (
 	// internal
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/mattn/go-sqlite3"
@@ -13,53 +15,211 @@ import (
 
 // SQLiteRepository  provides concrete implementation for repository interface
 type SQLiteRepository struct {
-	db     *sql.DB
-	logger log.Logger
+	db           *sql.DB
+	logger       log.Logger
+	queryTimeout time.Duration
+	options
 }
 
-func NewSQLiteRepository(db *sql.DB, logger log.Logger) (Repository, error) {
+// NewSQLiteRepository creates a repository backed by the given database. Each query is
+// bounded by queryTimeout in addition to the caller's context; a zero timeout disables it.
+func NewSQLiteRepository(db *sql.DB, queryTimeout time.Duration, logger log.Logger, opts ...Option) (Repository, error) {
 	return &SQLiteRepository{
-		db:     db,
-		logger: logger,
+		db:           db,
+		logger:       logger,
+		queryTimeout: queryTimeout,
+		options:      newOptions(opts),
 	}, nil
 }
 
-func (r *SQLiteRepository) Populate() error {
+// withTimeout derives a context for a single query from the caller's context.
+func (r *SQLiteRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+// queryError returns the context error if the query was abandoned because the context
+// was cancelled or timed out, so callers can tell it apart from a database failure.
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// change runs fn in a transaction, recording the event it returns in the outbox, if there is one,
+// before committing. The change hooks are called once the transaction is committed. The transaction
+// is rolled back if fn returns an event without a type, as nothing was changed.
+func (r *SQLiteRepository) change(ctx context.Context, fn func(tx *sql.Tx) (Event, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	event, err := fn(tx)
+	if err != nil || event.Type == "" {
+		return err
+	}
+	event.Time = time.Now()
+	if r.outbox != nil {
+		if err := r.outbox.Enqueue(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.changed(event)
+	return nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const noteColumns = "id, title, description, created_on, updated_on, version"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNote(row scanner) (Note, error) {
+	var note Note
+	err := row.Scan(&note.NoteID, &note.Title, &note.Description, &note.CreatedOn, &note.UpdatedOn, &note.Version)
+	return note, err
+}
+
+// getNote reads a note using the database or a transaction.
+func getNote(ctx context.Context, q querier, id string) (Note, error) {
+	note, err := scanNote(q.QueryRowContext(ctx, "SELECT "+noteColumns+" FROM notes WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Note{}, ErrNoteNotExists
+		}
+		return Note{}, err
+	}
+	return note, nil
+}
+
+// nextVersion advances the change sequence. Called first in a transaction making a change,
+// it also takes the database write lock.
+func nextVersion(ctx context.Context, tx *sql.Tx) (uint64, error) {
+	if _, err := tx.ExecContext(ctx, "UPDATE note_sequence SET seq = seq + 1"); err != nil {
+		return 0, err
+	}
+	var version uint64
+	err := tx.QueryRowContext(ctx, "SELECT seq FROM note_sequence").Scan(&version)
+	return version, err
+}
+
+// insertNote creates a note with the given ID, replacing the tombstone of a note deleted with that ID.
+func insertNote(ctx context.Context, tx *sql.Tx, id string, n Note, version uint64) (Note, error) {
+	if _, err := tx.ExecContext(ctx, "INSERT INTO notes(id, title, description, created_on, updated_on, version) values(?,?,?, datetime('now'), datetime('now'), ?)",
+		id, n.Title, n.Description, version); err != nil {
+		return Note{}, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM note_tombstones WHERE id = ?", id); err != nil {
+		return Note{}, err
+	}
+	return getNote(ctx, tx, id)
+}
+
+// updateNote replaces the title and description of a note.
+func updateNote(ctx context.Context, tx *sql.Tx, id string, n Note, version uint64) (Note, error) {
+	res, err := tx.ExecContext(ctx, "UPDATE notes SET title = ?, description = ?, updated_on = datetime('now'), version = ? WHERE id = ?",
+		n.Title, n.Description, version, id)
+	if err != nil {
+		return Note{}, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return Note{}, err
+	}
+	if rowsAffected == 0 {
+		return Note{}, errors.New("update failed")
+	}
+	return getNote(ctx, tx, id)
+}
+
+// deleteNote deletes a note, leaving a tombstone.
+func deleteNote(ctx context.Context, tx *sql.Tx, id string, version uint64) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notes WHERE id = ?", id); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+    INSERT INTO note_tombstones (id, version, deleted_on) VALUES (?, ?, ?)
+    ON CONFLICT(id) DO UPDATE SET version = excluded.version, deleted_on = excluded.deleted_on
+    `, id, version, time.Now().UTC())
+	return err
+}
+
+// Populate recreates the notes tables with the initial notes, so that the demo starts from the same notes
+// on every run. The other tables of the database are kept. The change sequence starts a new epoch, which
+// sync clients see as a reset; neither the outbox nor the change hooks are told about the initial notes.
+func (r *SQLiteRepository) Populate(ctx context.Context) error {
 	r.logger.Info("Populating SQLite database with initial data")
 	query := `
+    DROP TABLE IF EXISTS notes;
+    DROP TABLE IF EXISTS note_tombstones;
+    DROP TABLE IF EXISTS note_sequence;
     CREATE TABLE IF NOT EXISTS notes (
         id TEXT PRIMARY KEY, -- Storing UUID as text
         title TEXT NOT NULL UNIQUE,
         description TEXT NOT NULL,
-        created_on DATETIME NOT NULL
+        created_on DATETIME NOT NULL,
+        updated_on DATETIME NOT NULL,
+        version INTEGER NOT NULL DEFAULT 0
+    );
+    CREATE INDEX IF NOT EXISTS notes_version ON notes (version);
+    CREATE TABLE IF NOT EXISTS note_tombstones (
+        id TEXT PRIMARY KEY,
+        version INTEGER NOT NULL,
+        deleted_on DATETIME NOT NULL
     );
+    CREATE INDEX IF NOT EXISTS note_tombstones_version ON note_tombstones (version);
+    -- the change sequence, a single row
+    CREATE TABLE IF NOT EXISTS note_sequence (
+        epoch TEXT NOT NULL,
+        seq INTEGER NOT NULL
+    );
+    INSERT INTO note_sequence (epoch, seq) SELECT lower(hex(randomblob(8))), 0 WHERE NOT EXISTS (SELECT 1 FROM note_sequence);
     `
 
-	_, err := r.db.Exec(query)
-
-	note1 := Note{
-		NoteID:      "1",
-		Title:       "slog",
-		Description: "slog is a logging package",
-	}
-	note2 := Note{
-		NoteID:      "2",
-		Title:       "viper",
-		Description: "viper is a configuration management package",
-	}
-	created1, err := r.Create(note1)
-	if created1 != "" {
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if _, err := r.db.ExecContext(qctx, query); err != nil {
+		r.logger.Error(err)
+		return queryError(qctx, err)
 	}
+
+	// the initial notes are inserted directly rather than through Create, so that the reset is not
+	// announced to the outbox and the change hooks as newly created notes on every start
+	tx, err := r.db.BeginTx(qctx, nil)
 	if err != nil {
 		r.logger.Error(err)
-		return err
+		return queryError(qctx, err)
 	}
-	created2, err := r.Create(note2)
-	if created2 != "" {
+	defer tx.Rollback()
+	for _, n := range []Note{
+		{Title: "slog", Description: "slog is a logging package"},
+		{Title: "viper", Description: "viper is a configuration management package"},
+	} {
+		version, err := nextVersion(qctx, tx)
+		if err != nil {
+			r.logger.Error(err)
+			return queryError(qctx, err)
+		}
+		uid, _ := uuid.NewV4()
+		if _, err := insertNote(qctx, tx, uid.String(), n, version); err != nil {
+			r.logger.Error(err)
+			return queryError(qctx, err)
+		}
 	}
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		r.logger.Error(err)
-		return err
+		return queryError(qctx, err)
 	}
 	return nil
 }
@@ -74,8 +234,8 @@ func (r *SQLiteRepository) isNoteTitleExists(title string) bool {
 	return false
 }*/
 
-func (r *SQLiteRepository) Create(n Note) (string, error) {
-	r.logger.Infof("Creating a new note with title: %s", n.Title)
+func (r *SQLiteRepository) Create(ctx context.Context, n Note) (string, error) {
+	r.logger.Info("Creating a new note")
 	//if _, ok := i.noteStore[n.NoteID]; ok {
 	//	return "", errors.New("NoteID exists")
 	//}
@@ -84,12 +244,20 @@ func (r *SQLiteRepository) Create(n Note) (string, error) {
 	//}
 	// Create a Version 4 UUID.
 	uid, _ := uuid.NewV4()
-	res, err := r.db.Exec("INSERT INTO notes(id, title, description, created_on) values(?,?,?, datetime('now'))",
-		uid, n.Title, n.Description)
-	if res != nil {
-		r.logger.Info(res)
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	err := r.change(qctx, func(tx *sql.Tx) (Event, error) {
+		version, err := nextVersion(qctx, tx)
+		if err != nil {
+			return Event{}, err
+		}
+		created, err := insertNote(qctx, tx, uid.String(), n, version)
+		if err != nil {
+			return Event{}, err
+		}
 		r.logger.Infof("Created note with ID: %s", uid)
-	}
+		return Event{Type: EventCreated, Note: created}, nil
+	})
 	if err != nil {
 		r.logger.Info(err)
 		var sqliteErr sqlite3.Error
@@ -98,7 +266,7 @@ func (r *SQLiteRepository) Create(n Note) (string, error) {
 				return "", ErrNoteExists
 			}
 		}
-		return "", err
+		return "", queryError(qctx, err)
 	}
 
 	//id, err := res.LastInsertId()
@@ -107,93 +275,244 @@ func (r *SQLiteRepository) Create(n Note) (string, error) {
 	//}
 	//n.NoteID = id
 
-	return n.NoteID, nil
+	return uid.String(), nil
 }
 
-func (r *SQLiteRepository) Update(id string, n Note) error {
+func (r *SQLiteRepository) Update(ctx context.Context, id string, n Note) error {
 	if id == "" {
 		return errors.New("invalid NoteID")
 	}
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 	// check if note with id exists
-	res, err := r.db.Exec("UPDATE notes SET title = ?, description = ? WHERE id = ?",
-		n.Title, n.Description, n.NoteID)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := res.RowsAffected()
+	err := r.change(qctx, func(tx *sql.Tx) (Event, error) {
+		version, err := nextVersion(qctx, tx)
+		if err != nil {
+			return Event{}, err
+		}
+		updated, err := updateNote(qctx, tx, id, n, version)
+		return Event{Type: EventUpdated, Note: updated}, err
+	})
 	if err != nil {
-		return err
+		return queryError(qctx, err)
 	}
-
-	if rowsAffected == 0 {
-		return errors.New("update failed")
-	}
-
 	return nil
 }
 
-func (r *SQLiteRepository) Delete(id string) error {
+func (r *SQLiteRepository) Delete(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("invalid NoteID")
 	}
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 	// check if note with id exists
-	res, err := r.db.Exec("DELETE FROM notes WHERE noteid = ?", id)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := res.RowsAffected()
+	err := r.change(qctx, func(tx *sql.Tx) (Event, error) {
+		version, err := nextVersion(qctx, tx)
+		if err != nil {
+			return Event{}, err
+		}
+		deleted, err := getNote(qctx, tx, id)
+		if errors.Is(err, ErrNoteNotExists) {
+			return Event{}, errors.New("delete failed")
+		}
+		if err != nil {
+			return Event{}, err
+		}
+		if err := deleteNote(qctx, tx, id, version); err != nil {
+			return Event{}, err
+		}
+		deleted.Version = version
+		return Event{Type: EventDeleted, Note: deleted}, nil
+	})
 	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return errors.New("delete failed")
+		return queryError(qctx, err)
 	}
-
-	return err
+	return nil
 }
 
-func (r *SQLiteRepository) GetById(id string) (Note, error) {
+func (r *SQLiteRepository) GetById(ctx context.Context, id string) (Note, error) {
 	if id == "" {
 		return Note{}, errors.New("invalid NoteID")
 	}
-	var note Note
-	row := r.db.QueryRow("SELECT * FROM notes WHERE noteid = ?", id)
-	if err := row.Scan(&note.NoteID, &note.Title, &note.Description, &note.CreatedOn); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return Note{}, ErrNoteNotExists
-		}
-		return Note{}, err
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	note, err := getNote(qctx, r.db, id)
+	if err != nil && !errors.Is(err, ErrNoteNotExists) {
+		return Note{}, queryError(qctx, err)
 	}
-	return note, nil
+	return note, err
 }
 
-func (r *SQLiteRepository) GetAll(keywords string) ([]Note, error) {
+func (r *SQLiteRepository) GetAll(ctx context.Context, keywords string) ([]Note, error) {
 	if keywords == "" {
 		r.logger.Info("Retrieving all notes")
 	} else {
 		r.logger.Infof("Retrieving notes using keywords: %s", keywords)
 	}
-	rows, err := r.db.Query("SELECT * FROM notes WHERE title LIKE ? OR description LIKE ?", "%"+keywords+"%", "%"+keywords+"%")
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(qctx, "SELECT "+noteColumns+" FROM notes WHERE title LIKE ? OR description LIKE ?", "%"+keywords+"%", "%"+keywords+"%")
 
 	if err != nil {
 		r.logger.Error(err)
-		return nil, err
+		return nil, queryError(qctx, err)
 	}
 	defer rows.Close()
 
-	r.logger.Infof("Found %d notes", rows)
 	var all []Note
 	for rows.Next() {
-		var note Note
-		r.logger.Info(note)
-
-		if err := rows.Scan(&note.NoteID, &note.Title, &note.Description, &note.CreatedOn); err != nil {
-			return nil, err
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, queryError(qctx, err)
 		}
+		// one message per row: keep the text constant so that the log sampler can throttle it
+		r.logger.With(nil, "id", note.NoteID).Debug("Retrieved note")
 		all = append(all, note)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(qctx, err)
+	}
+	r.logger.Infof("Found %d notes", len(all))
 	return all, nil
 }
+
+func (r *SQLiteRepository) Stats(ctx context.Context) (Stats, error) {
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var stats Stats
+	if err := r.db.QueryRowContext(qctx, "SELECT COUNT(*) FROM notes").Scan(&stats.Notes); err != nil {
+		return Stats{}, queryError(qctx, err)
+	}
+	dbStats := r.db.Stats()
+	stats.Database = &dbStats
+	return stats, nil
+}
+
+func (r *SQLiteRepository) Changes(ctx context.Context, since uint64, limit int) (ChangeSet, error) {
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	// read the changes and the sequence in one transaction so that they are consistent
+	tx, err := r.db.BeginTx(qctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return ChangeSet{}, queryError(qctx, err)
+	}
+	defer tx.Rollback()
+
+	cs := ChangeSet{Version: since}
+	if err := tx.QueryRowContext(qctx, "SELECT epoch, seq FROM note_sequence").Scan(&cs.Epoch, &cs.Latest); err != nil {
+		return ChangeSet{}, queryError(qctx, err)
+	}
+	// read one more of each than needed to tell whether more changes follow
+	rows, err := tx.QueryContext(qctx, "SELECT "+noteColumns+" FROM notes WHERE version > ? ORDER BY version LIMIT ?", since, limit+1)
+	if err != nil {
+		return ChangeSet{}, queryError(qctx, err)
+	}
+	var upserts []Note
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			rows.Close()
+			return ChangeSet{}, queryError(qctx, err)
+		}
+		upserts = append(upserts, note)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ChangeSet{}, queryError(qctx, err)
+	}
+	rows, err = tx.QueryContext(qctx, "SELECT id, version, deleted_on FROM note_tombstones WHERE version > ? ORDER BY version LIMIT ?", since, limit+1)
+	if err != nil {
+		return ChangeSet{}, queryError(qctx, err)
+	}
+	defer rows.Close()
+	var tombstones []Tombstone
+	for rows.Next() {
+		var t Tombstone
+		if err := rows.Scan(&t.NoteID, &t.Version, &t.DeletedOn); err != nil {
+			return ChangeSet{}, queryError(qctx, err)
+		}
+		tombstones = append(tombstones, t)
+	}
+	if err := rows.Err(); err != nil {
+		return ChangeSet{}, queryError(qctx, err)
+	}
+	return mergeChanges(cs, upserts, tombstones, limit), nil
+}
+
+// mergeChanges adds the first limit of the upserts and tombstones, each in sequence order, to the change set.
+func mergeChanges(cs ChangeSet, upserts []Note, tombstones []Tombstone, limit int) ChangeSet {
+	cs.Upserts = []Note{}
+	cs.Tombstones = []Tombstone{}
+	for len(cs.Upserts)+len(cs.Tombstones) < limit && (len(upserts) > 0 || len(tombstones) > 0) {
+		if len(tombstones) == 0 || (len(upserts) > 0 && upserts[0].Version < tombstones[0].Version) {
+			cs.Upserts = append(cs.Upserts, upserts[0])
+			cs.Version = upserts[0].Version
+			upserts = upserts[1:]
+		} else {
+			cs.Tombstones = append(cs.Tombstones, tombstones[0])
+			cs.Version = tombstones[0].Version
+			tombstones = tombstones[1:]
+		}
+	}
+	cs.More = len(upserts) > 0 || len(tombstones) > 0
+	return cs
+}
+
+func (r *SQLiteRepository) Apply(ctx context.Context, c SyncChange) (Note, error) {
+	qctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var result Note
+	err := r.change(qctx, func(tx *sql.Tx) (Event, error) {
+		version, err := nextVersion(qctx, tx)
+		if err != nil {
+			return Event{}, err
+		}
+		current := Note{}
+		if c.NoteID != "" {
+			current, err = getNote(qctx, tx, c.NoteID)
+			if err != nil && !errors.Is(err, ErrNoteNotExists) {
+				return Event{}, err
+			}
+		}
+		n := Note{Title: c.Title, Description: c.Description}
+		switch {
+		case current.Version != c.BaseVersion:
+			result = current
+			return Event{}, ErrVersionConflict
+		case current.NoteID == "" && c.Deleted:
+			// never created, so nothing to delete
+			return Event{}, nil
+		case current.NoteID == "":
+			id := c.NoteID
+			if id == "" {
+				uid, err := uuid.NewV4()
+				if err != nil {
+					return Event{}, err
+				}
+				id = uid.String()
+			}
+			result, err = insertNote(qctx, tx, id, n, version)
+			return Event{Type: EventCreated, Note: result}, err
+		case c.Deleted:
+			if err := deleteNote(qctx, tx, c.NoteID, version); err != nil {
+				return Event{}, err
+			}
+			current.Version = version
+			return Event{Type: EventDeleted, Note: current}, nil
+		default:
+			result, err = updateNote(qctx, tx, c.NoteID, n, version)
+			return Event{Type: EventUpdated, Note: result}, err
+		}
+	})
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return Note{}, ErrNoteExists
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			return result, err
+		}
+		return Note{}, queryError(qctx, err)
+	}
+	return result, nil
+}
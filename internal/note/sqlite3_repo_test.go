@@ -0,0 +1,85 @@
+package note
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func newTestSQLiteRepository(t *testing.T, opts ...Option) Repository {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	logger, _ := log.NewForTest()
+	repo, err := NewSQLiteRepository(db, time.Second, logger, opts...)
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	return repo
+}
+
+func TestSQLiteRepository_CRUD(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+	ctx := context.Background()
+
+	id, err := repo.Create(ctx, Note{Title: "zap", Description: "zap is a logging package"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	_, err = repo.Create(ctx, Note{Title: "zap", Description: "duplicate"})
+	assert.ErrorIs(t, err, ErrNoteExists)
+
+	n, err := repo.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "zap", n.Title)
+
+	require.NoError(t, repo.Update(ctx, id, Note{Title: "zap", Description: "updated"}))
+	n, _ = repo.GetById(ctx, id)
+	assert.Equal(t, "updated", n.Description)
+
+	notes, err := repo.GetAll(ctx, "zap")
+	require.NoError(t, err)
+	assert.Len(t, notes, 1)
+
+	require.NoError(t, repo.Delete(ctx, id))
+	_, err = repo.GetById(ctx, id)
+	assert.ErrorIs(t, err, ErrNoteNotExists)
+}
+
+func TestSQLiteRepository_Cancelled(t *testing.T) {
+	repo := newTestSQLiteRepository(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.GetAll(ctx, "")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.Create(ctx, Note{Title: "cancelled"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNoteHandler_CancelledRequest(t *testing.T) {
+	handler := MakeHTTPHandler(newTestSQLiteRepository(t), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/notes", nil).WithContext(ctx)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, StatusClientClosedRequest, res.Code)
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/notes", nil).WithContext(ctx)
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+}
@@ -0,0 +1,237 @@
+package note
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// changes returned by a change feed request unless a limit is given
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+	// client changes accepted by a sync request
+	maxSyncChanges = 100
+)
+
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+// Outcomes of a client change
+const (
+	SyncApplied = "applied"
+	// the note was changed since the base version of the client change
+	SyncConflict = "conflict"
+	// the change is invalid, such as a title used by another note
+	SyncRejected = "rejected"
+	// the change could not be applied and can be retried
+	SyncFailed = "failed"
+)
+
+// SyncNote is a note as the change feed and sync requests return it, with its version
+type SyncNote struct {
+	NoteID      string    `json:"noteid"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedOn   time.Time `json:"createdon"`
+	UpdatedOn   time.Time `json:"updatedon"`
+	// the position of the latest change to the note in the change sequence. Pass it as the base
+	// version of a change to the note
+	Version uint64 `json:"version"`
+}
+
+// toSync converts a note to the representation of the sync protocol.
+func toSync(n Note) SyncNote {
+	return SyncNote{
+		NoteID:      n.NoteID,
+		Title:       n.Title,
+		Description: n.Description,
+		CreatedOn:   n.CreatedOn,
+		UpdatedOn:   n.UpdatedOn,
+		Version:     n.Version,
+	}
+}
+
+// Changes is a page of the change feed
+type Changes struct {
+	// the notes created or updated since the token, oldest change first
+	Upserts []SyncNote `json:"upserts"`
+	// the notes deleted since the token, oldest first
+	Tombstones []Tombstone `json:"tombstones"`
+	// pass as since to get the changes that follow
+	Token string `json:"token"`
+	// more changes follow: request them with the token straight away
+	More bool `json:"more"`
+	// the token was issued for another change sequence, for example before the database was re-created.
+	// Discard the local notes and apply the changes from scratch
+	Reset bool `json:"reset"`
+}
+
+// SyncRequest is the changes made by an offline client
+type SyncRequest struct {
+	Changes []SyncChange `json:"changes"`
+}
+
+// SyncResult is the outcome of a client change
+type SyncResult struct {
+	NoteID string `json:"noteid,omitempty"`
+	// applied, conflict, rejected or failed
+	Status string `json:"status" example:"applied"`
+	// the note after the change was applied, or as it is when the change conflicts with it.
+	// Missing when the note is deleted
+	Note  *SyncNote `json:"note,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// SyncResponse reports the outcome of each client change, in the order of the request
+type SyncResponse struct {
+	Results []SyncResult `json:"results"`
+}
+
+// syncToken encodes a position in a change sequence. Clients treat it as opaque.
+func syncToken(epoch string, version uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("v1:%s:%d", epoch, version)))
+}
+
+// parseSyncToken decodes a token, returning the change sequence and the position in it.
+func parseSyncToken(token string) (string, uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, ErrInvalidSyncToken
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 || parts[0] != "v1" {
+		return "", 0, ErrInvalidSyncToken
+	}
+	version, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidSyncToken
+	}
+	return parts[1], version, nil
+}
+
+// Changes handles HTTP Get of the change feed
+//
+// @Summary      Get Note Changes
+// @Description  Get the notes created, updated and deleted since a sync token, and the token to pass next time.
+// @Description  Without a token every note is returned. Request again straight away while more is true
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        since  query     string  false  "sync token returned by the previous request"
+// @Param        limit  query     int     false  "changes to return, at most 1000"  default(100)
+// @Success      200  {object}  Changes
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /notes/changes [get]
+func (h *NoteHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	limit := defaultChangesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxChangesLimit {
+			http.Error(w, fmt.Sprintf("invalid limit %q: expecting 1 to %d", v, maxChangesLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	var (
+		epoch string
+		since uint64
+	)
+	if token := r.URL.Query().Get("since"); token != "" {
+		var err error
+		if epoch, since, err = parseSyncToken(token); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cs, err := h.Repository.Changes(r.Context(), since, limit)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	reset := since > 0 && (epoch != cs.Epoch || since > cs.Latest)
+	if reset {
+		if cs, err = h.Repository.Changes(r.Context(), 0, limit); err != nil {
+			http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+			return
+		}
+	}
+	upserts := make([]SyncNote, len(cs.Upserts))
+	for i, n := range cs.Upserts {
+		upserts[i] = toSync(n)
+	}
+	writeJSON(w, http.StatusOK, Changes{
+		Upserts:    upserts,
+		Tombstones: cs.Tombstones,
+		Token:      syncToken(cs.Epoch, cs.Version),
+		More:       cs.More,
+		Reset:      reset,
+	})
+}
+
+// Sync handles HTTP Post of client changes
+//
+// @Summary      Sync Note Changes
+// @Description  Apply the changes an offline client made to the versions of the notes it last saw.
+// @Description  A change to a note that was changed since is not applied and is reported as a conflict together with the note as it is.
+// @Description  Example: {"changes": [{"noteid": "1b4e28ba-2fa1-11d2-883f-0016d3cca427", "base_version": 3, "title": "zap", "description": "zap is a logging package"}]}
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        SyncRequest  body      SyncRequest  true  "client changes"
+// @Success      200  {object}  SyncResponse
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /notes/sync [post]
+func (h *NoteHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid sync request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) > maxSyncChanges {
+		http.Error(w, fmt.Sprintf("too many changes: at most %d are accepted", maxSyncChanges), http.StatusBadRequest)
+		return
+	}
+
+	res := SyncResponse{Results: make([]SyncResult, 0, len(req.Changes))}
+	for _, c := range req.Changes {
+		n, err := h.Repository.Apply(r.Context(), c)
+		result := SyncResult{NoteID: c.NoteID}
+		switch {
+		case err == nil:
+			result.Status = SyncApplied
+		case errors.Is(err, ErrVersionConflict):
+			result.Status = SyncConflict
+			result.Error = err.Error()
+		case errors.Is(err, ErrNoteExists):
+			result.Status = SyncRejected
+			result.Error = err.Error()
+		case r.Context().Err() != nil:
+			http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+			return
+		default:
+			result.Status = SyncFailed
+			result.Error = err.Error()
+		}
+		if n.NoteID != "" {
+			result.NoteID = n.NoteID
+			sn := toSync(n)
+			result.Note = &sn
+		}
+		res.Results = append(res.Results, result)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,113 @@
+package note
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestSync(t *testing.T) {
+	repos := map[string]func(t *testing.T) Repository{
+		"sqlite": func(t *testing.T) Repository { return newTestSQLiteRepository(t) },
+		"inmemory": func(t *testing.T) Repository {
+			logger, _ := log.NewForTest()
+			repo, err := NewInmemoryRepository(logger)
+			require.NoError(t, err)
+			require.NoError(t, repo.Populate(context.Background()))
+			return repo
+		},
+	}
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			handler := MakeHTTPHandler(repo, nil)
+			serve := func(method, target, body string) *httptest.ResponseRecorder {
+				res := httptest.NewRecorder()
+				handler.ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+				return res
+			}
+			changes := func(query string) Changes {
+				res := serve(http.MethodGet, "/api/v1/notes/changes"+query, "")
+				require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+				var c Changes
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &c))
+				return c
+			}
+			sync := func(changes ...SyncChange) []SyncResult {
+				body, _ := json.Marshal(SyncRequest{Changes: changes})
+				res := serve(http.MethodPost, "/api/v1/notes/sync", string(body))
+				require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+				var sr SyncResponse
+				require.NoError(t, json.Unmarshal(res.Body.Bytes(), &sr))
+				require.Len(t, sr.Results, len(changes))
+				return sr.Results
+			}
+
+			// a new client pages through every note
+			first := changes("?limit=1")
+			require.Len(t, first.Upserts, 1)
+			assert.True(t, first.More)
+			assert.False(t, first.Reset)
+			second := changes("?limit=1&since=" + first.Token)
+			require.Len(t, second.Upserts, 1)
+			assert.Greater(t, second.Upserts[0].Version, first.Upserts[0].Version)
+			current := changes("?since=" + second.Token)
+			assert.Empty(t, current.Upserts)
+			assert.False(t, current.More)
+			assert.Equal(t, second.Token, current.Token)
+			slog := first.Upserts[0]
+
+			results := sync(
+				SyncChange{NoteID: "2c1f6a2e-offline", Title: "zap", Description: "zap is a logging package"},
+				SyncChange{NoteID: slog.NoteID, BaseVersion: slog.Version, Title: slog.Title, Description: "edited offline"},
+				SyncChange{NoteID: slog.NoteID, BaseVersion: slog.Version, Title: slog.Title, Description: "edited on another device"},
+				SyncChange{Title: "zap", Description: "duplicate"},
+			)
+			assert.Equal(t, SyncApplied, results[0].Status)
+			assert.Equal(t, "2c1f6a2e-offline", results[0].Note.NoteID, "a client can choose the ID of a new note")
+			assert.Equal(t, SyncApplied, results[1].Status)
+			edited := *results[1].Note
+			assert.Equal(t, "edited offline", edited.Description)
+			assert.Equal(t, SyncConflict, results[2].Status, "the base version is stale")
+			assert.Equal(t, edited, *results[2].Note)
+			assert.Equal(t, SyncRejected, results[3].Status)
+
+			results = sync(
+				SyncChange{NoteID: edited.NoteID, BaseVersion: edited.Version, Deleted: true},
+				SyncChange{NoteID: edited.NoteID, BaseVersion: edited.Version, Title: "slog"},
+				SyncChange{NoteID: "never-synced", Deleted: true},
+			)
+			assert.Equal(t, SyncApplied, results[0].Status)
+			assert.Nil(t, results[0].Note)
+			assert.Equal(t, SyncConflict, results[1].Status, "the note was deleted")
+			assert.Nil(t, results[1].Note)
+			assert.Equal(t, SyncApplied, results[2].Status)
+
+			latest := changes("?since=" + current.Token)
+			require.Len(t, latest.Upserts, 1, "only the latest change to each note is returned")
+			assert.Equal(t, "zap", latest.Upserts[0].Title)
+			require.Len(t, latest.Tombstones, 1)
+			assert.Equal(t, slog.NoteID, latest.Tombstones[0].NoteID)
+			assert.Empty(t, changes("?since="+latest.Token).Upserts)
+
+			// a token for another change sequence starts the client again
+			reset := changes("?since=" + syncToken("recreated", 1))
+			assert.True(t, reset.Reset)
+			assert.Len(t, reset.Upserts, 2)
+			assert.Len(t, reset.Tombstones, 1)
+			assert.Equal(t, latest.Token, reset.Token)
+
+			assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/notes/changes?since=garbage", "").Code)
+			assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/notes/changes?limit=0", "").Code)
+			assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/notes/sync", "{").Code)
+		})
+	}
+}
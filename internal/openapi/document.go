@@ -0,0 +1,227 @@
+// Package openapi serves the OpenAPI 3.1 document of the API and checks the traffic against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// the OpenAPI document. Keep it in step with the handlers: the tests check the responses against it
+//
+//go:embed openapi.json
+var document []byte
+
+// Document is a parsed OpenAPI document
+type Document struct {
+	raw     []byte
+	routes  []route
+	schemas map[string]*Schema
+}
+
+// Parameter is a path, query or header parameter of an operation
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// MediaType is the schema of a body in a media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes the body of a response
+type Response struct {
+	Content map[string]MediaType `json:"content"`
+}
+
+// Operation is a method of a path
+type Operation struct {
+	ID          string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// route is a path template and its operations by method
+type route struct {
+	template   string
+	segments   []string
+	operations map[string]*Operation
+}
+
+// Load parses the OpenAPI document of the API.
+func Load() (*Document, error) {
+	return Parse(document)
+}
+
+// Parse parses an OpenAPI document.
+func Parse(raw []byte) (*Document, error) {
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+	d := &Document{raw: raw, schemas: doc.Components.Schemas}
+	for template, item := range doc.Paths {
+		r := route{
+			template:   template,
+			segments:   strings.Split(strings.Trim(template, "/"), "/"),
+			operations: make(map[string]*Operation),
+		}
+		// parameters shared by the operations of the path
+		var shared []Parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("invalid parameters of %s: %w", template, err)
+			}
+		}
+		for method, raw := range item {
+			if method == "parameters" || method == "summary" || method == "description" {
+				continue
+			}
+			op := new(Operation)
+			if err := json.Unmarshal(raw, op); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %w", strings.ToUpper(method), template, err)
+			}
+			op.Parameters = append(append([]Parameter(nil), shared...), op.Parameters...)
+			r.operations[strings.ToUpper(method)] = op
+		}
+		d.routes = append(d.routes, r)
+	}
+	if err := d.checkRefs(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ServeHTTP serves the document as JSON.
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d.raw)
+}
+
+// Operation returns the operation matching a request and the values of its path parameters, or nil
+// when the request is not described by the document. A literal path segment takes precedence over a
+// template, so that /notes/changes is not taken for /notes/{id}.
+func (d *Document) Operation(r *http.Request) (*Operation, map[string]string) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var (
+		best      *route
+		bestScore = -1
+	)
+	for i := range d.routes {
+		rt := &d.routes[i]
+		if len(rt.segments) != len(segments) || rt.operations[r.Method] == nil {
+			continue
+		}
+		score := 0
+		for j, s := range rt.segments {
+			if isTemplate(s) {
+				continue
+			}
+			if s != segments[j] {
+				score = -1
+				break
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rt, score
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	params := make(map[string]string)
+	for j, s := range best.segments {
+		if isTemplate(s) {
+			params[s[1:len(s)-1]] = segments[j]
+		}
+	}
+	return best.operations[r.Method], params
+}
+
+func isTemplate(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// resolve follows a reference to a component schema.
+func (d *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// checkRefs checks that every reference names a component schema.
+func (d *Document) checkRefs() error {
+	var check func(s *Schema) error
+	check = func(s *Schema) error {
+		if s == nil {
+			return nil
+		}
+		if s.Ref != "" {
+			if !strings.HasPrefix(s.Ref, "#/components/schemas/") || d.resolve(s) == nil {
+				return fmt.Errorf("unknown schema %s", s.Ref)
+			}
+			return nil
+		}
+		for _, p := range s.Properties {
+			if err := check(p); err != nil {
+				return err
+			}
+		}
+		if err := check(s.Items); err != nil {
+			return err
+		}
+		return check(s.AdditionalProperties)
+	}
+	for _, s := range d.schemas {
+		if err := check(s); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.routes {
+		for _, op := range r.operations {
+			for _, p := range op.Parameters {
+				if err := check(p.Schema); err != nil {
+					return err
+				}
+			}
+			if op.RequestBody != nil {
+				for _, mt := range op.RequestBody.Content {
+					if err := check(mt.Schema); err != nil {
+						return err
+					}
+				}
+			}
+			for _, res := range op.Responses {
+				for _, mt := range res.Content {
+					if err := check(mt.Schema); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fortify-presales/insecure-go-api/internal/middleware"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+const (
+	// maxCheckedRequest bounds the JSON request body read for checking. Longer requests are
+	// answered with 413 Request Entity Too Large
+	maxCheckedRequest = 1 << 20
+	// maxRecordedResponse bounds the response body kept for checking. Longer responses, such as
+	// streams, are not checked
+	maxRecordedResponse = 1 << 20
+)
+
+var errRequestTooLarge = fmt.Errorf("the request body is larger than %d bytes", maxCheckedRequest)
+
+// ValidationError is the body of a 400 Bad Request answering a request that does not match the document,
+// and of a 413 Request Entity Too Large answering one whose body is too long to check.
+// It extends model.APIError with the violations.
+type ValidationError struct {
+	ErrorCode    int
+	ErrorMessage string
+	Errors       []Violation
+}
+
+// Middleware returns a middleware answering requests that do not match the document with 400 Bad Request,
+// listing the violations. Requests the document does not describe are passed on unchecked, as are bodies
+// in other media types than JSON. When checkResponses is set, the responses are checked too and the ways
+// they differ from the document are logged; it is meant for testing.
+func (d *Document) Middleware(logger log.Logger, checkResponses bool) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, params := d.Operation(r)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			violations, err := d.checkRequest(op, params, r)
+			if errors.Is(err, errRequestTooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, err.Error(), nil)
+				return
+			}
+			if len(violations) > 0 {
+				writeError(w, http.StatusBadRequest, "the request does not match the API description", violations)
+				return
+			}
+			if !checkResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if violations := d.checkResponse(op, rec); len(violations) > 0 {
+				messages := make([]string, len(violations))
+				for i, v := range violations {
+					messages[i] = v.String()
+				}
+				logger.With(r.Context()).Warnf("The response to %s %s does not match the API description: %s",
+					r.Method, r.URL.Path, strings.Join(messages, "; "))
+			}
+		})
+	}
+}
+
+// checkRequest returns the ways the parameters and body of a request do not match an operation.
+// A JSON body is read and replaced so that the handler can read it again; errRequestTooLarge is
+// returned when it is longer than maxCheckedRequest. Bodies in other media types are left to the handler.
+func (d *Document) checkRequest(op *Operation, pathParams map[string]string, r *http.Request) ([]Violation, error) {
+	var violations []Violation
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var (
+			value string
+			ok    bool
+		)
+		switch p.In {
+		case "path":
+			value, ok = pathParams[p.Name]
+		case "query":
+			ok = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			ok = value != ""
+		}
+		if !ok {
+			if p.Required {
+				violations = append(violations, Violation{In: p.In, Name: p.Name, Message: "required"})
+			}
+			continue
+		}
+		violations = d.validate(p.Schema, parameterValue(d.resolve(p.Schema), value), p.In, p.Name, violations)
+	}
+
+	if op.RequestBody == nil || r.Body == nil {
+		return violations, nil
+	}
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		// the handler rejects a malformed content type
+		mediaType, _, _ = mime.ParseMediaType(ct)
+	}
+	content, ok := op.RequestBody.Content["application/json"]
+	if !ok || !isJSON(mediaType) {
+		if r.ContentLength == 0 && op.RequestBody.Required {
+			violations = append(violations, Violation{In: "body", Message: "required"})
+		}
+		return violations, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCheckedRequest+1))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return append(violations, Violation{In: "body", Message: err.Error()}), nil
+	}
+	if len(body) > maxCheckedRequest {
+		return violations, errRequestTooLarge
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			violations = append(violations, Violation{In: "body", Message: "required"})
+		}
+		return violations, nil
+	}
+	v, err := decodeJSON(body)
+	if err != nil {
+		return append(violations, Violation{In: "body", Message: "invalid JSON: " + err.Error()}), nil
+	}
+	return d.validate(content.Schema, v, "body", "", violations), nil
+}
+
+// writeError answers a request the middleware refuses, in the shape of model.APIError.
+func writeError(w http.ResponseWriter, status int, message string, violations []Violation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ValidationError{
+		ErrorCode:    status,
+		ErrorMessage: message,
+		Errors:       violations,
+	})
+}
+
+// parameterValue converts the value of a parameter to the type of its schema, leaving it a string
+// when it cannot be converted.
+func parameterValue(s *Schema, value string) interface{} {
+	if s == nil {
+		return value
+	}
+	for _, t := range s.Type {
+		switch t {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return json.Number(value)
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+// checkResponse returns the ways a response does not match the responses of an operation.
+func (d *Document) checkResponse(op *Operation, rec *recorder) []Violation {
+	status := strconv.Itoa(rec.status)
+	res, ok := op.Responses[status]
+	if !ok {
+		res, ok = op.Responses[status[:1]+"XX"]
+	}
+	if !ok {
+		res, ok = op.Responses["default"]
+	}
+	if !ok {
+		return []Violation{{In: "status", Name: status, Message: "not documented"}}
+	}
+	if rec.truncated || rec.body.Len() == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		return []Violation{{In: "header", Name: "Content-Type", Message: "missing or malformed"}}
+	}
+	i := strings.IndexByte(mediaType, '/')
+	if i <= 0 {
+		return []Violation{{In: "header", Name: "Content-Type", Message: fmt.Sprintf("%s is not a media type", mediaType)}}
+	}
+	content, ok := res.Content[mediaType]
+	if !ok {
+		// files are served in the media type they were uploaded with, documented as a range
+		content, ok = res.Content[mediaType[:i]+"/*"]
+	}
+	if !ok {
+		content, ok = res.Content["*/*"]
+	}
+	if !ok {
+		return []Violation{{In: "header", Name: "Content-Type", Message: fmt.Sprintf("%s not documented for %s", mediaType, status)}}
+	}
+	if !isJSON(mediaType) {
+		return nil
+	}
+	v, err := decodeJSON(rec.body.Bytes())
+	if err != nil {
+		return []Violation{{In: "body", Message: "invalid JSON: " + err.Error()}}
+	}
+	return d.validate(content.Schema, v, "body", "", nil)
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// recorder passes a response on, keeping its status and the start of its body
+type recorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.body.Len()+len(b) > maxRecordedResponse {
+		r.truncated = true
+	} else if !r.truncated {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush lets streams, such as the note change stream, through.
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	res := httptest.NewRecorder()
+	doc.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	var served struct {
+		OpenAPI string `json:"openapi"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &served))
+	assert.Equal(t, "3.1.0", served.OpenAPI)
+
+	op, params := doc.Operation(httptest.NewRequest(http.MethodGet, "/api/v1/notes/changes", nil))
+	require.NotNil(t, op)
+	assert.Equal(t, "getNoteChanges", op.ID, "a literal segment takes precedence over a template")
+	assert.Empty(t, params)
+	op, params = doc.Operation(httptest.NewRequest(http.MethodPut, "/api/v2/notes/42", nil))
+	require.NotNil(t, op)
+	assert.Equal(t, "updateNoteV2", op.ID)
+	assert.Equal(t, map[string]string{"id": "42"}, params)
+	require.Len(t, op.Parameters, 1, "path parameters are shared by the operations")
+	op, _ = doc.Operation(httptest.NewRequest(http.MethodGet, "/api/v1/site/files", nil))
+	assert.Nil(t, op)
+	op, _ = doc.Operation(httptest.NewRequest(http.MethodPatch, "/api/v2/notes/42", nil))
+	assert.Nil(t, op)
+
+	_, err = Parse([]byte(`{"openapi":"3.1.0","paths":{"/x":{"get":{"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/Missing"}}}}}}}}}`))
+	assert.ErrorContains(t, err, "Missing")
+	_, err = Parse([]byte(`{"swagger":"2.0"}`))
+	assert.Error(t, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+	logger, logs := log.NewForTest()
+	var (
+		handled  bool
+		response func(w http.ResponseWriter)
+	)
+	handler := doc.Middleware(logger, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled = true
+		b, _ := io.ReadAll(r.Body)
+		assert.NotNil(t, b, "the handler can read the body")
+		response(w)
+	}))
+	respond := func(status int, body string) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}
+	}
+	serve := func(method, target, contentType, body string) (*httptest.ResponseRecorder, ValidationError) {
+		handled = false
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		var verr ValidationError
+		if res.Code == http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &verr), res.Body.String())
+		}
+		return res, verr
+	}
+
+	response = respond(http.StatusOK, `{"upserts":[],"tombstones":null,"token":"t","more":false,"reset":false}`)
+	res, _ := serve(http.MethodGet, "/api/v1/notes/changes?limit=10", "", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.True(t, handled)
+	res, verr := serve(http.MethodGet, "/api/v1/notes/changes?limit=5000", "", "")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.False(t, handled, "an invalid request is not handled")
+	assert.Equal(t, []Violation{{In: "query", Name: "limit", Message: "expecting at most 1000"}}, verr.Errors)
+	_, verr = serve(http.MethodGet, "/api/v1/notes/changes?limit=ten", "", "")
+	assert.Equal(t, []Violation{{In: "query", Name: "limit", Message: "expecting integer, got string"}}, verr.Errors)
+
+	response = respond(http.StatusCreated, `{"id":"1","title":"zap","description":"","createdAt":"2026-01-02T03:04:05Z","updatedAt":"2026-01-02T03:04:05Z","version":1,"links":{"self":"/api/v2/notes/1","collection":"/api/v2/notes"}}`)
+	res, _ = serve(http.MethodPost, "/api/v2/notes", "application/json", `{"title":"zap","version":1}`)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	_, verr = serve(http.MethodPost, "/api/v2/notes", "", `{"title":"","version":-1,"tags":["go"]}`)
+	assert.Equal(t, []Violation{
+		{In: "body", Name: "/tags", Message: "not allowed"},
+		{In: "body", Name: "/title", Message: "expecting at least 1 characters"},
+		{In: "body", Name: "/version", Message: "expecting at least 0"},
+	}, verr.Errors)
+	assert.Equal(t, http.StatusBadRequest, verr.ErrorCode)
+	_, verr = serve(http.MethodPost, "/api/v2/notes", "application/json", `{"description":"no title"`)
+	require.Len(t, verr.Errors, 1)
+	assert.Contains(t, verr.Errors[0].Message, "invalid JSON")
+	_, verr = serve(http.MethodPut, "/api/v2/notes/1", "application/json", "")
+	assert.Equal(t, []Violation{{In: "body", Message: "required"}}, verr.Errors)
+	serve(http.MethodPost, "/api/v2/notes", "application/yaml", "description: no title")
+	assert.True(t, handled, "only JSON bodies are checked")
+	res, _ = serve(http.MethodPost, "/api/v2/notes", "application/json", `{"title":"zap","description":"`+strings.Repeat("z", maxCheckedRequest)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	assert.False(t, handled)
+
+	response = respond(http.StatusOK, `{"anything":true}`)
+	res, _ = serve(http.MethodPost, "/api/v1/site/exec", "application/json", `{`)
+	assert.Equal(t, http.StatusOK, res.Code, "undocumented requests are passed on")
+	assert.Empty(t, logs.All())
+
+	// drift
+	response = respond(http.StatusOK, `{"status":"ok"}`)
+	serve(http.MethodGet, "/healthz", "", "")
+	response = respond(http.StatusTeapot, `{}`)
+	serve(http.MethodGet, "/readyz", "", "")
+	response = func(w http.ResponseWriter) { http.Error(w, "oops", http.StatusNotFound) }
+	serve(http.MethodGet, "/api/v2/notes/1", "", "")
+	response = func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text")
+		io.WriteString(w, "zap")
+	}
+	res, _ = serve(http.MethodGet, "/api/v1/site/download/1", "", "")
+	assert.Equal(t, http.StatusOK, res.Code, "a content type without a subtype is reported")
+	var drift []string
+	for _, entry := range logs.All() {
+		drift = append(drift, entry.Message)
+	}
+	assert.Equal(t, []string{
+		"The response to GET /healthz does not match the API description: body /checks: required",
+		"The response to GET /readyz does not match the API description: status 418: not documented",
+		"The response to GET /api/v2/notes/1 does not match the API description: header Content-Type: text/plain not documented for 404",
+		"The response to GET /api/v1/site/download/1 does not match the API description: header Content-Type: text is not a media type",
+	}, drift)
+}
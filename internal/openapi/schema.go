@@ -0,0 +1,207 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema used by the document: types, formats, enums, lengths, bounds,
+// patterns, properties, items and references to component schemas.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 Types              `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MaxItems             *int               `json:"maxItems"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+
+	// false rather than a schema: nothing is valid
+	never   bool
+	pattern *regexp.Regexp
+}
+
+// UnmarshalJSON reads a schema, or the boolean schemas true and false.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	switch string(bytes.TrimSpace(b)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{never: true}
+		return nil
+	}
+	type schema Schema
+	if err := json.Unmarshal(b, (*schema)(s)); err != nil {
+		return err
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	return nil
+}
+
+// Types are the types a value may have. OpenAPI 3.1 gives either a type or a list of types,
+// such as ["array", "null"].
+type Types []string
+
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+// Violation is a part of a request or response that does not match the document
+type Violation struct {
+	// path, query, header or body
+	In string
+	// the parameter, or a JSON pointer into the body
+	Name    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s: %s", v.In, v.Name, v.Message)
+}
+
+// validate appends the ways a value decoded with json.Decoder.UseNumber does not match the schema.
+func (d *Document) validate(s *Schema, v interface{}, in, name string, violations []Violation) []Violation {
+	s = d.resolve(s)
+	if s == nil {
+		return violations
+	}
+	fail := func(format string, args ...interface{}) []Violation {
+		return append(violations, Violation{In: in, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.never {
+		return fail("not allowed")
+	}
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(v, t) }) {
+		return fail("expecting %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e interface{}) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		return fail("expecting one of %v", s.Enum)
+	}
+
+	switch v := v.(type) {
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			return fail("expecting at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fail("expecting at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("expecting a match for %s", s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fail("expecting an RFC 3339 date and time")
+			}
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			return fail("expecting at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fail("expecting at most %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fail("expecting at most %d items", *s.MaxItems)
+		}
+		for i, item := range v {
+			violations = d.validate(s.Items, item, in, name+"/"+strconv.Itoa(i), violations)
+		}
+	case map[string]interface{}:
+		for _, required := range s.Required {
+			if _, ok := v[required]; !ok {
+				violations = append(violations, Violation{In: in, Name: name + "/" + required, Message: "required"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// report in a stable order
+		slices.Sort(keys)
+		for _, key := range keys {
+			if property, ok := s.Properties[key]; ok {
+				violations = d.validate(property, v[key], in, name+"/"+key, violations)
+			} else if s.AdditionalProperties != nil {
+				violations = d.validate(s.AdditionalProperties, v[key], in, name+"/"+key, violations)
+			}
+		}
+	}
+	return violations
+}
+
+func hasType(v interface{}, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil {
+			_, err = strconv.ParseUint(n.String(), 10, 64)
+		}
+		return err == nil
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	}
+	return typeOf(v) == t
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// decodeJSON decodes a body keeping the numbers as json.Number.
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -1,31 +1,86 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"os"
+	"time"
 
 	"github.com/fortify-presales/insecure-go-api/pkg/log"
 
 	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/internal/link"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
 	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/internal/webhook"
 )
 
-const fileName = "sqlite.db"
-
-func BuildRepository(logger log.Logger, cfg *config.Config) note.Repository {
-	// Initialize SQLite3 database
-	os.Remove(fileName)
-	db, err := sql.Open("sqlite3", fileName)
+// OpenDatabase opens the SQLite3 database at path shared by the repositories, creating it if needed. The
+// database is kept across restarts so that jobs, monitors, links and the webhook outbox survive them.
+func OpenDatabase(logger log.Logger, path string) *sql.DB {
+	// Create the SQLite3 database if needed, readable by its owner and group only
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o640)
 	if err != nil {
 		logger.Error(err)
 		os.Exit(-1)
 	}
-	repo, err := note.NewSQLiteRepository(db, logger)
+	file.Close()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		logger.Error(err)
+		os.Exit(-1)
+	}
+	return db
+}
+
+func BuildRepository(logger log.Logger, cfg *config.Config, db *sql.DB, opts ...note.Option) note.Repository {
+	repo, err := note.NewSQLiteRepository(db, time.Duration(cfg.QueryTimeout)*time.Second, logger.Named("note"), opts...)
 	if err != nil {
 		logger.Error("Error:", err)
 		os.Exit(-1)
 	}
-	repo.Populate() // Populate the database
+	repo.Populate(context.Background()) // Reset the notes to the initial ones
 
 	return repo
-}
\ No newline at end of file
+}
+
+// BuildJobStore creates the store persisting the state of site diagnostic jobs.
+func BuildJobStore(logger log.Logger, db *sql.DB) job.Store {
+	store, err := job.NewSQLiteStore(context.Background(), db)
+	if err != nil {
+		logger.Error("Error:", err)
+		os.Exit(-1)
+	}
+	return store
+}
+
+// BuildMonitorStore creates the store persisting site monitors and their checks.
+func BuildMonitorStore(logger log.Logger, db *sql.DB) monitor.Store {
+	store, err := monitor.NewSQLiteStore(context.Background(), db)
+	if err != nil {
+		logger.Error("Error:", err)
+		os.Exit(-1)
+	}
+	return store
+}
+
+// BuildLinkStore creates the store persisting signed download links and their redemptions.
+func BuildLinkStore(logger log.Logger, db *sql.DB) link.Store {
+	store, err := link.NewSQLiteStore(context.Background(), db)
+	if err != nil {
+		logger.Error("Error:", err)
+		os.Exit(-1)
+	}
+	return store
+}
+
+// BuildWebhookStore creates the store persisting webhooks, which is also the outbox of note changes.
+func BuildWebhookStore(logger log.Logger, db *sql.DB) webhook.Store {
+	store, err := webhook.NewSQLiteStore(context.Background(), db)
+	if err != nil {
+		logger.Error("Error:", err)
+		os.Exit(-1)
+	}
+	return store
+}
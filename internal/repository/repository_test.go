@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/internal/link"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/internal/webhook"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestOpenDatabase_Restart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	cfg := &config.Config{QueryTimeout: 5}
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	ctx := context.Background()
+
+	db := OpenDatabase(logger, path)
+	repo := BuildRepository(logger, cfg, db)
+	_, err := repo.Create(ctx, note.Note{Title: "zap", Description: "zap is a logging package"})
+	require.NoError(t, err)
+	jobs := BuildJobStore(logger, db)
+	require.NoError(t, jobs.Save(ctx, job.Job{ID: "1", Type: "ping", Status: job.StatusRunning, CreatedOn: time.Now()}))
+	require.NoError(t, db.Close())
+
+	// the server restarts
+	db = OpenDatabase(logger, path)
+	defer db.Close()
+	repo = BuildRepository(logger, cfg, db)
+	notes, err := repo.GetAll(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, notes, 2, "the notes are reset to the initial ones")
+	j, err := BuildJobStore(logger, db).Get(ctx, "1")
+	require.NoError(t, err, "the jobs are kept")
+	assert.Equal(t, job.StatusFailed, j.Status)
+	assert.Equal(t, "interrupted by server restart", j.Error)
+}
+
+// upProbe finds every target up
+type upProbe struct{}
+
+func (upProbe) Prepare(params json.RawMessage) (json.RawMessage, error) {
+	return params, nil
+}
+
+func (upProbe) Check(ctx context.Context, params json.RawMessage) (monitor.Outcome, error) {
+	return monitor.Outcome{Up: true, Latency: 1}, nil
+}
+
+func TestOpenDatabase_RestartMonitors(t *testing.T) {
+	logger, _ := log.NewForTest()
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	ctx := context.Background()
+	probes := map[string]monitor.Probe{"up": upProbe{}}
+	checks := func(m *monitor.Manager, id string) int {
+		h, err := m.History(ctx, id, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), false)
+		require.NoError(t, err)
+		return h.Checks
+	}
+
+	db := OpenDatabase(logger, path)
+	m := monitor.NewManager(logger, BuildMonitorStore(logger, db), probes)
+	mon, err := m.Create(ctx, monitor.Monitor{Name: "site", Type: "up", Params: json.RawMessage(`{"hostname":"localhost"}`)})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return checks(m, mon.ID) == 1 }, 5*time.Second, 10*time.Millisecond)
+	m.Close()
+	require.NoError(t, db.Close())
+
+	// the server restarts
+	db = OpenDatabase(logger, path)
+	defer db.Close()
+	m = monitor.NewManager(logger, BuildMonitorStore(logger, db), probes)
+	defer m.Close()
+	require.NoError(t, m.Start(ctx))
+	monitors, err := m.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, monitors, 1, "the monitor is kept")
+	assert.Equal(t, "site", monitors[0].Name)
+	// the monitor is checked again straight away, adding to its history
+	require.Eventually(t, func() bool { return checks(m, mon.ID) == 2 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOpenDatabase_RestartLinks(t *testing.T) {
+	logger, _ := log.NewForTest()
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	ctx := context.Background()
+	key := []byte("test-key")
+
+	db := OpenDatabase(logger, path)
+	m := link.NewManager(logger, key, BuildLinkStore(logger, db))
+	revoked, err := m.Create(ctx, "12345", "10.0.0.1:1234", link.Request{})
+	require.NoError(t, err)
+	require.NoError(t, m.Revoke(ctx, revoked.ID))
+	used, err := m.Create(ctx, "12345", "10.0.0.1:1234", link.Request{SingleUse: true})
+	require.NoError(t, err)
+	_, err = m.Redeem(ctx, "12345", m.Query(used), "10.0.0.2:1234", false)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// the server restarts
+	db = OpenDatabase(logger, path)
+	defer db.Close()
+	m = link.NewManager(logger, key, BuildLinkStore(logger, db))
+	_, err = m.Redeem(ctx, "12345", m.Query(revoked), "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, link.ErrLinkRevoked, "the revocation is kept")
+	_, err = m.Redeem(ctx, "12345", m.Query(used), "10.0.0.2:1234", false)
+	assert.ErrorIs(t, err, link.ErrLinkUsed, "the use is kept")
+}
+
+func TestOpenDatabase_RestartWebhooks(t *testing.T) {
+	logger, _ := log.NewForTest()
+	cfg := &config.Config{QueryTimeout: 5}
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	ctx := context.Background()
+
+	db := OpenDatabase(logger, path)
+	store := BuildWebhookStore(logger, db)
+	repo := BuildRepository(logger, cfg, db, note.WithOutbox(store))
+	sub := webhook.Subscription{ID: "1", URL: "http://127.0.0.1:1/hooks", Active: true, CreatedOn: time.Now()}
+	require.NoError(t, store.SaveSubscription(ctx, sub))
+	_, err := repo.Create(ctx, note.Note{Title: "zap", Description: "zap is a logging package"})
+	require.NoError(t, err)
+	pending, err := store.Deliveries(ctx, sub.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.NoError(t, db.Close())
+
+	// the server restarts, before the delivery is attempted
+	db = OpenDatabase(logger, path)
+	defer db.Close()
+	store = BuildWebhookStore(logger, db)
+	due, err := store.Due(ctx, time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "the delivery is kept")
+	assert.Equal(t, pending[0].ID, due[0].ID)
+	assert.Equal(t, webhook.StatusPending, due[0].Status)
+	assert.Equal(t, "note.created", due[0].EventType)
+}
+
+func TestOpenDatabase_RestartQueuesNoDeliveries(t *testing.T) {
+	logger, _ := log.NewForTest()
+	cfg := &config.Config{QueryTimeout: 5}
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	ctx := context.Background()
+	var events []note.Event
+	hook := note.OnChange(func(e note.Event) { events = append(events, e) })
+
+	db := OpenDatabase(logger, path)
+	store := BuildWebhookStore(logger, db)
+	BuildRepository(logger, cfg, db, note.WithOutbox(store), hook)
+	sub := webhook.Subscription{ID: "1", URL: "http://127.0.0.1:1/hooks", Active: true, CreatedOn: time.Now()}
+	require.NoError(t, store.SaveSubscription(ctx, sub))
+	require.NoError(t, db.Close())
+
+	// the server restarts with an active webhook, resetting the notes
+	db = OpenDatabase(logger, path)
+	defer db.Close()
+	store = BuildWebhookStore(logger, db)
+	repo := BuildRepository(logger, cfg, db, note.WithOutbox(store), hook)
+	notes, err := repo.GetAll(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, notes, 2)
+	deliveries, err := store.Deliveries(ctx, sub.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "the initial notes are not delivered as created")
+	assert.Empty(t, events, "nor published to the change hooks")
+}
@@ -2,34 +2,95 @@ package site
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
-	"os/exec"
+	"path/filepath"
+	"strconv"
 
 	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/internal/link"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
 	"github.com/fortify-presales/insecure-go-api/pkg/log"
 )
 
+// Services holds the optional components used by the site API.
+// Endpoints for nil components are not registered.
+type Services struct {
+	Jobs     *job.Manager
+	History  *History
+	Monitors *monitor.Manager
+	// runs the commands of the site API; a runner with default bounds is used when nil
+	Commands *CommandRunner
+	// the downloads catalogue; the configured downloads directory is used when nil
+	Downloads *Downloads
+	Links     *link.Manager
+}
+
 // SiteHandler is a struct that contains the logger and configuration for the site API
 type SiteHandler struct {
-	logger log.Logger
-	cfg    *config.Config
+	logger    log.Logger
+	cfg       *config.Config
+	jobs      *job.Manager
+	history   *History
+	monitors  *monitor.Manager
+	commands  *CommandRunner
+	downloads *Downloads
+	links     *link.Manager
 }
 
-func MakeHTTPHandler(logger log.Logger, cfg *config.Config) http.Handler {
+func MakeHTTPHandler(logger log.Logger, cfg *config.Config, services Services) http.Handler {
 
 	// Initialize handlers
 	siteHandler := &SiteHandler{
-		logger: logger,
-		cfg:    cfg,
+		logger:    logger,
+		cfg:       cfg,
+		jobs:      services.Jobs,
+		history:   services.History,
+		monitors:  services.Monitors,
+		commands:  services.Commands,
+		downloads: services.Downloads,
+		links:     services.Links,
+	}
+	if siteHandler.commands == nil {
+		siteHandler.commands = NewCommandRunner(CommandRunnerConfig{})
+	}
+	if siteHandler.downloads == nil {
+		siteHandler.downloads = NewDownloads(cfg.DataPath(cfg.DownloadsDir), 0)
 	}
 
 	router := http.NewServeMux()
 	router.HandleFunc("GET /api/v1/site/ping", siteHandler.PingSiteByQuery)
 	router.HandleFunc("POST /api/v1/site/ping", siteHandler.PingSiteByBody)
+	router.HandleFunc("GET /api/v1/site/dns", siteHandler.LookupDNS)
+	router.HandleFunc("GET /api/v1/site/port", siteHandler.CheckPort)
+	router.HandleFunc("GET /api/v1/site/tls", siteHandler.CheckTLS)
+	router.HandleFunc("GET /api/v1/site/http", siteHandler.ProbeHTTP)
 	router.HandleFunc("GET /api/v1/site/download/{id}", siteHandler.DownloadFileById)
+	router.HandleFunc("POST /api/v1/site/downloads", siteHandler.UploadFile)
+	router.HandleFunc("GET /api/v1/site/downloads", siteHandler.ListDownloads)
+	router.HandleFunc("GET /api/v1/site/downloads/{id}", siteHandler.GetDownload)
+	if services.Links != nil {
+		router.HandleFunc("POST /api/v1/site/downloads/{id}/links", siteHandler.CreateLink)
+		router.HandleFunc("GET /api/v1/site/links/{id}", siteHandler.GetLink)
+		router.HandleFunc("DELETE /api/v1/site/links/{id}", siteHandler.RevokeLink)
+	}
+	if services.Jobs != nil {
+		router.HandleFunc("POST /api/v1/site/jobs", siteHandler.SubmitJob)
+		router.HandleFunc("GET /api/v1/site/jobs/{id}", siteHandler.GetJob)
+		router.HandleFunc("DELETE /api/v1/site/jobs/{id}", siteHandler.CancelJob)
+	}
+	if services.History != nil {
+		router.HandleFunc("GET /api/v1/site/history", siteHandler.GetHistory)
+	}
+	if services.Monitors != nil {
+		router.HandleFunc("POST /api/v1/site/monitors", siteHandler.CreateMonitor)
+		router.HandleFunc("GET /api/v1/site/monitors", siteHandler.ListMonitors)
+		router.HandleFunc("GET /api/v1/site/monitors/{id}", siteHandler.GetMonitor)
+		router.HandleFunc("DELETE /api/v1/site/monitors/{id}", siteHandler.DeleteMonitor)
+		router.HandleFunc("GET /api/v1/site/monitors/{id}/history", siteHandler.GetMonitorHistory)
+	}
 
 	return router
 }
@@ -37,96 +98,149 @@ func MakeHTTPHandler(logger log.Logger, cfg *config.Config) http.Handler {
 // GET request with data flow taint source in URL path
 //
 // @Summary      Ping Site by Query
-// @Description  Ping a Site using URL query parameter
+// @Description  Ping a Site using URL query parameters
+// @Description  Send "Accept: text/event-stream" or set "stream=true" to receive each line of output as it is printed,
+// @Description  followed by a "summary" event with the parsed statistics
 // @Tags         site
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/event-stream,application/x-ndjson
 // @Param		 hostname	query		string				true	"hostname"	example("localhost")
-// @Success      200  {string}  "string"
+// @Param		 count		query		int					false	"number of echo requests (1-20)"	default(4)
+// @Param		 timeout	query		int					false	"seconds to wait for each reply (1-30)"	default(5)
+// @Param		 interval	query		number				false	"seconds between echo requests (0.2-10)"	default(1)
+// @Param		 stream		query		bool				false	"stream the output as newline-delimited JSON"
+// @Success      200  {object}  PingResult
 // @Failure      400  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /site/ping [get]
 func (s *SiteHandler) PingSiteByQuery(w http.ResponseWriter, r *http.Request) {
 	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
 	//
-	// Get hostname from query parameter
+	// Get hostname and ping parameters from query parameters
 	//
-	host := r.URL.Query().Get("hostname")
-	if host == "" {
-		http.Error(w, "Hostname not provided", http.StatusBadRequest)
-		return
+	query := r.URL.Query()
+	site := Site{Hostname: query.Get("hostname")}
+	var err error
+	if v := query.Get("count"); v != "" {
+		if site.Count, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid count", http.StatusBadRequest)
+			return
+		}
 	}
-	//
-	// Command Injection : dataflow
-	//
-	cmd := exec.Command("ping", "-c", "4", host)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusInternalServerError)
-		return
+	if v := query.Get("timeout"); v != "" {
+		if site.Timeout, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid timeout", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("interval"); v != "" {
+		if site.Interval, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Invalid interval", http.StatusBadRequest)
+			return
+		}
 	}
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write(output)
+	s.pingSite(w, r, site)
 }
 
 // POST request with data flow taint source in body
 //
 // @Summary      Ping Site by Body
 // @Description  Ping a Site using JSON Body
-// @Description  JSON Body should contain a "hostname" field
-// @Description  Example: {"hostname": "localhost"}
+// @Description  JSON Body should contain a "hostname" field and may contain "count", "timeout" and "interval"
+// @Description  Example: {"hostname": "localhost", "count": 4}
 // @Description  This is a JSON Injection vulnerability example
 // @Tags         site
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/event-stream,application/x-ndjson
 // @Param		 Site	body		Site				true	"Site"
-// @Success      200  {string}  "string"
+// @Param		 stream	query		bool				false	"stream the output as newline-delimited JSON"
+// @Success      200  {object}  PingResult
 // @Failure      400  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /site/ping [post]
 func (s *SiteHandler) PingSiteByBody(w http.ResponseWriter, r *http.Request) {
 	s.logger.Infof("Handling POST at %s\n", r.URL.Path)
-	type JsonString struct {
-		Hostname string `json:"hostname"`
-	}
-	var jsonDataToRead JsonString
-	//err2 := json.Unmarshal(body, &jsonData)
-	err := json.NewDecoder(r.Body).Decode(&jsonDataToRead)
+	var site Site
+	err := json.NewDecoder(r.Body).Decode(&site)
 	if err != nil {
 		http.Error(w, "Hostname not provided", http.StatusBadRequest)
 		return
 	}
+	s.pingSite(w, r, site)
+}
+
+// pingSite pings the site, records the command in the command history and writes the result as JSON,
+// or streams the output if the client asked for it.
+func (s *SiteHandler) pingSite(w http.ResponseWriter, r *http.Request, site Site) {
+	if err := site.normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if mode := streamMode(r); mode != "" {
+		s.streamPing(w, r, site, mode)
+		return
+	}
+	result, exe, err := ping(r.Context(), s.commands, site, nil)
+	s.recordCommand(r, exe)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), commandErrorStatus(err, exe))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// commandErrorStatus maps the failure of a command to a status code.
+func commandErrorStatus(err error, exe Execution) int {
+	switch {
+	case errors.Is(err, ErrCommandBusy):
+		return http.StatusServiceUnavailable
+	case exe.TimedOut:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// recordCommand appends a command run for the request to the command history, if there is one.
+func (s *SiteHandler) recordCommand(r *http.Request, exe Execution) {
+	if s.history == nil {
+		return
+	}
 	//
 	// JSON Injection : dataflow
 	//
-	jsonDataToWrite := map[string]string{
-		"command":  "ping",
-		"hostname": jsonDataToRead.Hostname,
-		"output":   "", // Placeholder for actual output
+	entry, err := s.history.Record(r.Context(), r.RemoteAddr, exe)
+	if err != nil {
+		s.logger.With(r.Context()).Errorf("Unable to record command in history: %s", err)
+		return
 	}
-	s.logger.Infof("Creating file 'command_log.json' with contents: %+v\n", jsonDataToWrite)
-	file, _ := os.OpenFile("command_log.json", os.O_CREATE|os.O_WRONLY, os.ModePerm)
-	defer file.Close()
-	jsonEncoder := json.NewEncoder(file)
-	jsonEncoder.SetIndent("", "  ") // Optional: Pretty-print the JSON
-	jsonEncoder.Encode(jsonDataToWrite)
-	// TODO: actual ping logic can be added here and output placed in "jsonDataToWrite"
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	s.logger.With(r.Context(), "history_id", entry.ID).Debugf("Recorded %s command with exit code %d", entry.Command, entry.ExitCode)
 }
 
 // GET request with data flow taint source in URL path
 //
 // @Summary      Download File
 // @Description  Download a file by ID
+// @Description  Range requests and conditional requests using If-Modified-Since or If-None-Match are supported
+// @Description  Links created with POST /site/downloads/{id}/links carry a signature that is checked before the file is served
+// @Description  When signed links are enabled, files are only served through a valid link
+// @Description  A single use link serves a few range requests after the start of the file to the client that used it, so that the download can be resumed
 // @Tags         site
 // @Accept       json
-// @Produce      json
-// @Param		 id	path		string				true	"id"	example("12345")
-// @Success      200  {string}  "string"
+// @Produce      octet-stream
+// @Param		 id		path		string				true	"id"	example("12345")
+// @Param		 link	query		string				false	"signed link id"
+// @Param		 expires	query		int					false	"signed link expiry, in seconds since the epoch"
+// @Param		 once	query		int					false	"1 if the signed link can be used once only"
+// @Param		 ip		query		string				false	"the address the signed link is bound to"
+// @Param		 sig	query		string				false	"signed link signature"
+// @Success      200  {file}    file
+// @Success      206  {file}    file
+// @Success      304  {string}  string
 // @Failure      400  {object}  model.APIError
+// @Failure      403  {object}  model.APIError
 // @Failure      404  {object}  model.APIError
+// @Failure      410  {object}  model.APIError
+// @Failure      416  {object}  model.APIError
 // @Failure      500  {object}  model.APIError
 // @Router       /site/download/{id} [get]
 func (s *SiteHandler) DownloadFileById(w http.ResponseWriter, r *http.Request) {
@@ -140,16 +254,37 @@ func (s *SiteHandler) DownloadFileById(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Id not provided", http.StatusBadRequest)
 		return
 	}
+	// with signed links enabled, files are only served through them, checked before the file is looked up
+	switch {
+	case s.links != nil:
+		if _, err := s.links.Redeem(r.Context(), id, r.URL.Query(), r.RemoteAddr, resumes(r)); err != nil {
+			http.Error(w, fmt.Sprintf("Error: %s", err), linkErrorStatus(err))
+			return
+		}
+		if !catalogueID(id) {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+	case link.Signed(r.URL.Query()):
+		http.Error(w, "Signed links are not enabled", http.StatusForbidden)
+		return
+	}
 	//
 	// Path Manipulation : dataflow
 	//
-	filename := fmt.Sprintf("%s%c%s%c%s", os.Getenv("PWD"), os.PathSeparator, "downloads", os.PathSeparator, id)
-	s.logger.Infof("Retrieving contents of file path: %s\n", filename)
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
+	s.logger.Infof("Retrieving contents of file path: %s\n", filepath.Join(s.downloads.dir, id))
+	file, download, err := s.downloads.Open(id)
+	if errors.Is(err, ErrDownloadNotFound) {
 		http.Error(w, "File not found", http.StatusNotFound)
 		return
 	}
-	data, _ := ioutil.ReadFile(filename)
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write(data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", download.ContentType)
+	w.Header().Set("Content-Disposition", contentDisposition(download))
+	w.Header().Set("ETag", `"`+download.SHA256+`"`)
+	http.ServeContent(w, r, download.Name, download.Uploaded, file)
 }
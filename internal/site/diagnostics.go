@@ -0,0 +1,421 @@
+package site
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxProbeBody is the number of bytes of a response body read by an HTTP probe
+const maxProbeBody = 1 << 20
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// lookupDNS looks up the requested record types of a hostname. Failed lookups are reported by record type
+// in the result, so that a missing record type doesn't hide the others.
+func lookupDNS(ctx context.Context, lookup DNSLookup) (DNSResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(lookup.Timeout)*time.Second)
+	defer cancel()
+
+	result := DNSResult{Hostname: lookup.Hostname, Resolver: "system"}
+	resolver := net.DefaultResolver
+	if lookup.Resolver != "" {
+		result.Resolver = lookup.Resolver
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, lookup.Resolver)
+			},
+		}
+	}
+	start := time.Now()
+	for _, t := range lookup.Types {
+		var err error
+		switch t {
+		case "A", "AAAA":
+			network := "ip4"
+			if t == "AAAA" {
+				network = "ip6"
+			}
+			var ips []net.IP
+			if ips, err = resolver.LookupIP(ctx, network, lookup.Hostname); err == nil {
+				addrs := make([]string, len(ips))
+				for i, ip := range ips {
+					addrs[i] = ip.String()
+				}
+				if t == "A" {
+					result.A = addrs
+				} else {
+					result.AAAA = addrs
+				}
+			}
+		case "CNAME":
+			var cname string
+			// the canonical name of a host without a CNAME record is the host itself
+			if cname, err = resolver.LookupCNAME(ctx, lookup.Hostname); err == nil && strings.TrimSuffix(cname, ".") != strings.TrimSuffix(lookup.Hostname, ".") {
+				result.CNAME = cname
+			}
+		case "MX":
+			var mxs []*net.MX
+			if mxs, err = resolver.LookupMX(ctx, lookup.Hostname); err == nil {
+				for _, mx := range mxs {
+					result.MX = append(result.MX, MXRecord{Host: mx.Host, Pref: mx.Pref})
+				}
+			}
+		case "TXT":
+			result.TXT, err = resolver.LookupTXT(ctx, lookup.Hostname)
+		}
+		if err != nil {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return result, ctx.Err()
+			}
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[t] = err.Error()
+		}
+	}
+	result.Duration = milliseconds(time.Since(start))
+	return result, nil
+}
+
+// checkPort tries to open a TCP connection to the port. A refused or timed out connection is
+// reported in the result rather than as an error.
+func checkPort(ctx context.Context, check PortCheck) (PortResult, error) {
+	result := PortResult{Hostname: check.Hostname, Port: check.Port}
+	dialer := net.Dialer{Timeout: time.Duration(check.Timeout) * time.Second}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(check.Hostname, strconv.Itoa(check.Port)))
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer conn.Close()
+	result.Open = true
+	result.Address = conn.RemoteAddr().String()
+	result.Latency = milliseconds(time.Since(start))
+	return result, nil
+}
+
+// checkTLS completes a TLS handshake with the server and describes the certificate chain it presents.
+// The chain is inspected even when it can't be verified, which is reported in the result.
+func checkTLS(ctx context.Context, check TLSCheck) (TLSResult, error) {
+	result := TLSResult{Hostname: check.Hostname, Port: check.Port, ServerName: check.ServerName}
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: time.Duration(check.Timeout) * time.Second},
+		Config: &tls.Config{
+			ServerName: check.ServerName,
+			NextProtos: []string{"h2", "http/1.1"},
+			// verified below, so that untrusted and expired certificates can be described too
+			InsecureSkipVerify: true,
+		},
+	}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(check.Hostname, strconv.Itoa(check.Port)))
+	result.Duration = milliseconds(time.Since(start))
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	result.Address = conn.RemoteAddr().String()
+	result.Version = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	result.ALPN = state.NegotiatedProtocol
+	now := time.Now()
+	for _, cert := range state.PeerCertificates {
+		c := Certificate{
+			Subject:            cert.Subject.String(),
+			Issuer:             cert.Issuer.String(),
+			SerialNumber:       fmt.Sprintf("%X", cert.SerialNumber),
+			NotBefore:          cert.NotBefore,
+			NotAfter:           cert.NotAfter,
+			ExpiresInDays:      int(cert.NotAfter.Sub(now).Hours() / 24),
+			DNSNames:           cert.DNSNames,
+			SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+			IsCA:               cert.IsCA,
+		}
+		for _, ip := range cert.IPAddresses {
+			c.IPAddresses = append(c.IPAddresses, ip.String())
+		}
+		result.Chain = append(result.Chain, c)
+	}
+	if len(state.PeerCertificates) > 0 {
+		opts := x509.VerifyOptions{DNSName: check.ServerName, Intermediates: x509.NewCertPool()}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+			result.VerifyError = err.Error()
+		} else {
+			result.Verified = true
+		}
+	}
+	return result, nil
+}
+
+// probeHTTP sends a request to the URL, following redirects one at a time so that each is reported.
+// Connection failures and too many redirects are reported in the result.
+func probeHTTP(ctx context.Context, probe HTTPProbe) (result HTTPResult, err error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(probe.Timeout)*time.Second)
+	defer cancel()
+
+	result = HTTPResult{URL: probe.URL, FinalURL: probe.URL}
+	client := &http.Client{
+		// a fresh connection for every request so that each is timed from the start
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	// the result is named so that the duration set here is returned
+	defer func() { result.Duration = milliseconds(time.Since(start)) }()
+	target := probe.URL
+	for {
+		//
+		// Server-Side Request Forgery : dataflow
+		//
+		res, timing, err := timedRequest(ctx, client, probe.Method, target)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return result, ctx.Err()
+			}
+			result.Error = err.Error()
+			return result, nil
+		}
+		result.FinalURL = target
+		result.StatusCode = res.StatusCode
+		result.Status = res.Status
+		result.Proto = res.Proto
+		result.Headers = res.Header
+		result.BodyBytes = timing.bodyBytes
+		result.Timing = timing.HTTPTiming
+
+		location := res.Header.Get("Location")
+		if res.StatusCode < 300 || res.StatusCode >= 400 || location == "" {
+			return result, nil
+		}
+		next, err := url.Parse(target)
+		if err == nil {
+			next, err = next.Parse(location)
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid redirect location %q", location)
+			return result, nil
+		}
+		result.Redirects = append(result.Redirects, Redirect{URL: target, StatusCode: res.StatusCode, Location: next.String()})
+		if len(result.Redirects) > *probe.MaxRedirects {
+			result.Error = fmt.Sprintf("stopped after %d redirects", *probe.MaxRedirects)
+			return result, nil
+		}
+		target = next.String()
+	}
+}
+
+type requestTiming struct {
+	HTTPTiming
+	bodyBytes int64
+}
+
+// timedRequest sends a single request, reads up to maxProbeBody bytes of the response and times each phase.
+func timedRequest(ctx context.Context, client *http.Client, method, target string) (*http.Response, requestTiming, error) {
+	var (
+		timing                                          requestTiming
+		start, dnsStart, connectStart, tlsStart         time.Time
+		dnsDone, connectDone, tlsDone, firstByte, total time.Duration
+	)
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { dnsDone = time.Since(dnsStart) },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { connectDone = time.Since(connectStart) },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Since(tlsStart) },
+		GotFirstResponseByte: func() { firstByte = time.Since(start) },
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, target, nil)
+	if err != nil {
+		return nil, timing, err
+	}
+	start = time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, timing, err
+	}
+	defer res.Body.Close()
+	timing.bodyBytes, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxProbeBody))
+	total = time.Since(start)
+	timing.HTTPTiming = HTTPTiming{
+		DNS:       milliseconds(dnsDone),
+		Connect:   milliseconds(connectDone),
+		TLS:       milliseconds(tlsDone),
+		FirstByte: milliseconds(firstByte),
+		Total:     milliseconds(total),
+	}
+	return res, timing, nil
+}
+
+// queryInt parses an optional integer query parameter into v.
+func queryInt(query url.Values, name string, v *int) error {
+	if s := query.Get(name); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid %s", name)
+		}
+		*v = n
+	}
+	return nil
+}
+
+// diagnose normalizes the parameters of a diagnostic, runs it and writes its result as JSON.
+func diagnose[P any, PP interface {
+	*P
+	normalizer
+}, R any](s *SiteHandler, w http.ResponseWriter, r *http.Request, params P, run func(context.Context, P) (R, error)) {
+	if err := PP(&params).normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := run(r.Context(), params)
+	if err != nil {
+		s.logger.With(r.Context()).Warnf("Diagnostic failed: %s", err)
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GET request with data flow taint source in URL query
+//
+// @Summary      DNS Lookup
+// @Description  Look up the DNS records of a hostname, optionally querying a specific DNS server
+// @Tags         site
+// @Produce      json
+// @Param		 hostname	query		string		true	"hostname"	example("localhost")
+// @Param		 type		query		[]string	false	"record types: A, AAAA, CNAME, MX, TXT"	collectionFormat(csv)
+// @Param		 resolver	query		string		false	"DNS server address with optional port"	example("8.8.8.8:53")
+// @Param		 timeout	query		int			false	"seconds to wait (1-30)"	default(5)
+// @Success      200  {object}  DNSResult
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/dns [get]
+func (s *SiteHandler) LookupDNS(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	query := r.URL.Query()
+	lookup := DNSLookup{Hostname: query.Get("hostname"), Resolver: query.Get("resolver")}
+	for _, types := range query["type"] {
+		lookup.Types = append(lookup.Types, strings.Split(types, ",")...)
+	}
+	if err := queryInt(query, "timeout", &lookup.Timeout); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	diagnose(s, w, r, lookup, lookupDNS)
+}
+
+// GET request with data flow taint source in URL query
+//
+// @Summary      Check Port
+// @Description  Check whether a TCP port accepts connections and time the connection
+// @Tags         site
+// @Produce      json
+// @Param		 hostname	query		string		true	"hostname"	example("localhost")
+// @Param		 port		query		int			true	"port"	example(8080)
+// @Param		 timeout	query		int			false	"seconds to wait (1-30)"	default(5)
+// @Success      200  {object}  PortResult
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/port [get]
+func (s *SiteHandler) CheckPort(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	query := r.URL.Query()
+	check := PortCheck{Hostname: query.Get("hostname")}
+	for name, v := range map[string]*int{"port": &check.Port, "timeout": &check.Timeout} {
+		if err := queryInt(query, name, v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	diagnose(s, w, r, check, checkPort)
+}
+
+// GET request with data flow taint source in URL query
+//
+// @Summary      Check TLS
+// @Description  Describe the TLS protocol, certificate chain, expiry and subject alternative names of a server
+// @Tags         site
+// @Produce      json
+// @Param		 hostname		query		string		true	"hostname"	example("example.com")
+// @Param		 port			query		int			false	"port"	default(443)
+// @Param		 server_name	query		string		false	"name sent in the handshake and verified (default the hostname)"
+// @Param		 timeout		query		int			false	"seconds to wait (1-30)"	default(5)
+// @Success      200  {object}  TLSResult
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/tls [get]
+func (s *SiteHandler) CheckTLS(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	query := r.URL.Query()
+	check := TLSCheck{Hostname: query.Get("hostname"), ServerName: query.Get("server_name")}
+	for name, v := range map[string]*int{"port": &check.Port, "timeout": &check.Timeout} {
+		if err := queryInt(query, name, v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	diagnose(s, w, r, check, checkTLS)
+}
+
+// GET request with data flow taint source in URL query
+//
+// @Summary      Probe HTTP
+// @Description  Send a request to a URL and report the status, headers, redirect chain and timing breakdown
+// @Tags         site
+// @Produce      json
+// @Param		 url			query		string		true	"absolute http or https URL"	example("http://localhost:8080/swagger/index.html")
+// @Param		 method			query		string		false	"GET or HEAD"	default(GET)
+// @Param		 max_redirects	query		int			false	"redirects to follow (0-10)"	default(5)
+// @Param		 timeout		query		int			false	"seconds to wait (1-30)"	default(5)
+// @Success      200  {object}  HTTPResult
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/http [get]
+func (s *SiteHandler) ProbeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	query := r.URL.Query()
+	probe := HTTPProbe{URL: query.Get("url"), Method: query.Get("method")}
+	if query.Has("max_redirects") {
+		probe.MaxRedirects = new(int)
+		if err := queryInt(query, "max_redirects", probe.MaxRedirects); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := queryInt(query, "timeout", &probe.Timeout); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	diagnose(s, w, r, probe, probeHTTP)
+}
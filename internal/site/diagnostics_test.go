@@ -0,0 +1,284 @@
+package site
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// DNS record types answered by the stub server
+const (
+	dnsTypeA     = 1
+	dnsTypeCNAME = 5
+	dnsTypeMX    = 15
+	dnsTypeTXT   = 16
+	dnsTypeAAAA  = 28
+)
+
+type stubRecord struct {
+	name  string
+	rtype uint16
+	data  []byte
+}
+
+// startStubDNS serves a fixed zone over UDP: www.example.test is an alias of example.test,
+// which has A, AAAA, MX and TXT records. Other names don't exist.
+func startStubDNS(t *testing.T) string {
+	zone := []stubRecord{
+		{"www.example.test.", dnsTypeCNAME, encodeDNSName("example.test.")},
+		{"example.test.", dnsTypeA, net.ParseIP("192.0.2.10").To4()},
+		{"example.test.", dnsTypeAAAA, net.ParseIP("2001:db8::10")},
+		{"example.test.", dnsTypeMX, append([]byte{0, 10}, encodeDNSName("mail.example.test.")...)},
+		{"example.test.", dnsTypeTXT, append([]byte{byte(len("v=spf1 -all"))}, "v=spf1 -all"...)},
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if res := answerDNS(zone, buf[:n]); res != nil {
+				conn.WriteTo(res, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func encodeDNSName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// answerDNS builds the response to a query with a single question, following CNAME records.
+func answerDNS(zone []stubRecord, query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	// read the question name
+	var labels []string
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		l := int(query[i])
+		if i+1+l > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[i+1:i+1+l]))
+		i += 1 + l
+	}
+	if i+5 > len(query) {
+		return nil
+	}
+	question := query[12 : i+5]
+	qtype := binary.BigEndian.Uint16(query[i+1:])
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+
+	var answers [][]byte
+	known := false
+	for found := true; found; {
+		found = false
+		for _, rr := range zone {
+			if rr.name != name {
+				continue
+			}
+			known = true
+			if rr.rtype == qtype || rr.rtype == dnsTypeCNAME {
+				answer := append(encodeDNSName(rr.name), 0, 0, 0, 1, 0, 0, 0, 60, 0, 0)
+				binary.BigEndian.PutUint16(answer[len(answer)-10:], rr.rtype)
+				binary.BigEndian.PutUint16(answer[len(answer)-2:], uint16(len(rr.data)))
+				answers = append(answers, append(answer, rr.data...))
+				if rr.rtype == dnsTypeCNAME && qtype != dnsTypeCNAME {
+					name = decodeStubName(rr.data)
+					found = true
+					break
+				}
+			}
+		}
+	}
+
+	res := make([]byte, 12, 512)
+	copy(res, query[:2])
+	flags := uint16(0x8180) // response, recursion desired and available
+	if !known {
+		flags |= 3 // NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(res[2:], flags)
+	binary.BigEndian.PutUint16(res[4:], 1)
+	binary.BigEndian.PutUint16(res[6:], uint16(len(answers)))
+	res = append(res, question...)
+	for _, answer := range answers {
+		res = append(res, answer...)
+	}
+	return res
+}
+
+func decodeStubName(b []byte) string {
+	var labels []string
+	for i := 0; i < len(b) && b[i] != 0; i += 1 + int(b[i]) {
+		labels = append(labels, string(b[i+1:i+1+int(b[i])]))
+	}
+	return strings.Join(labels, ".") + "."
+}
+
+func getJSON(t *testing.T, handler http.Handler, target string, v interface{}) int {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, target, nil))
+	if res.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), v), res.Body.String())
+	}
+	return res.Code
+}
+
+func newDiagnosticsHandler() http.Handler {
+	logger, _ := log.NewForTest()
+	return MakeHTTPHandler(logger, &config.Config{}, Services{})
+}
+
+func TestLookupDNS(t *testing.T) {
+	handler := newDiagnosticsHandler()
+	resolver := startStubDNS(t)
+
+	var result DNSResult
+	code := getJSON(t, handler, "/api/v1/site/dns?hostname=example.test.&type=A,AAAA&type=mx,TXT&resolver="+resolver, &result)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, resolver, result.Resolver)
+	assert.Equal(t, []string{"192.0.2.10"}, result.A)
+	assert.Equal(t, []string{"2001:db8::10"}, result.AAAA)
+	assert.Equal(t, []MXRecord{{Host: "mail.example.test.", Pref: 10}}, result.MX)
+	assert.Equal(t, []string{"v=spf1 -all"}, result.TXT)
+	assert.Empty(t, result.Errors)
+
+	result = DNSResult{}
+	code = getJSON(t, handler, "/api/v1/site/dns?hostname=www.example.test.&resolver="+resolver, &result)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "example.test.", result.CNAME)
+	assert.Equal(t, []string{"192.0.2.10"}, result.A)
+
+	result = DNSResult{}
+	code = getJSON(t, handler, "/api/v1/site/dns?hostname=missing.example.test.&type=A&resolver="+resolver, &result)
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, result.A)
+	assert.Contains(t, result.Errors["A"], "no such host")
+
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, handler, "/api/v1/site/dns?hostname=example.test&type=SRV", nil))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, handler, "/api/v1/site/dns", nil))
+}
+
+func TestCheckPort(t *testing.T) {
+	handler := newDiagnosticsHandler()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	var result PortResult
+	require.Equal(t, http.StatusOK, getJSON(t, handler, fmt.Sprintf("/api/v1/site/port?hostname=127.0.0.1&port=%d", port), &result))
+	assert.True(t, result.Open)
+	assert.Equal(t, ln.Addr().String(), result.Address)
+
+	ln.Close()
+	result = PortResult{}
+	require.Equal(t, http.StatusOK, getJSON(t, handler, fmt.Sprintf("/api/v1/site/port?hostname=127.0.0.1&port=%d", port), &result))
+	assert.False(t, result.Open)
+	assert.Contains(t, result.Error, "refused")
+
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, handler, "/api/v1/site/port?hostname=127.0.0.1&port=70000", nil))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, handler, "/api/v1/site/port?hostname=127.0.0.1&port=http", nil))
+}
+
+func TestCheckTLS(t *testing.T) {
+	handler := newDiagnosticsHandler()
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	var result TLSResult
+	target := fmt.Sprintf("/api/v1/site/tls?hostname=127.0.0.1&port=%d&server_name=example.com", addr.Port)
+	require.Equal(t, http.StatusOK, getJSON(t, handler, target, &result))
+	assert.Empty(t, result.Error)
+	assert.Equal(t, "example.com", result.ServerName)
+	assert.Equal(t, "TLS 1.3", result.Version)
+	assert.Equal(t, "h2", result.ALPN)
+	require.NotEmpty(t, result.Chain)
+	leaf := result.Chain[0]
+	assert.Contains(t, leaf.DNSNames, "example.com")
+	assert.Contains(t, leaf.IPAddresses, "127.0.0.1")
+	assert.Greater(t, leaf.ExpiresInDays, 0)
+	// the test certificate isn't signed by a trusted authority
+	assert.False(t, result.Verified)
+	assert.NotEmpty(t, result.VerifyError)
+
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	result = TLSResult{}
+	target = fmt.Sprintf("/api/v1/site/tls?hostname=127.0.0.1&port=%d&timeout=2", plain.Listener.Addr().(*net.TCPAddr).Port)
+	require.Equal(t, http.StatusOK, getJSON(t, handler, target, &result))
+	assert.NotEmpty(t, result.Error)
+	assert.Empty(t, result.Chain)
+}
+
+func TestProbeHTTP(t *testing.T) {
+	handler := newDiagnosticsHandler()
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/new", http.StatusMovedPermanently))
+	mux.Handle("/new", http.RedirectHandler("/final", http.StatusFound))
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Probe", "yes")
+		fmt.Fprint(w, "hello")
+	})
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var result HTTPResult
+	require.Equal(t, http.StatusOK, getJSON(t, handler, "/api/v1/site/http?url="+url.QueryEscape(srv.URL+"/old"), &result))
+	assert.Empty(t, result.Error)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, srv.URL+"/final", result.FinalURL)
+	assert.Equal(t, "yes", result.Headers.Get("X-Probe"))
+	assert.Equal(t, int64(5), result.BodyBytes)
+	assert.Equal(t, []Redirect{
+		{URL: srv.URL + "/old", StatusCode: http.StatusMovedPermanently, Location: srv.URL + "/new"},
+		{URL: srv.URL + "/new", StatusCode: http.StatusFound, Location: srv.URL + "/final"},
+	}, result.Redirects)
+	assert.Greater(t, result.Timing.Total, 0.0)
+	assert.GreaterOrEqual(t, result.Timing.Total, result.Timing.FirstByte)
+	assert.Greater(t, result.Duration, 0.0)
+	assert.GreaterOrEqual(t, result.Duration, result.Timing.Total, "the duration covers every redirect")
+
+	result = HTTPResult{}
+	require.Equal(t, http.StatusOK, getJSON(t, handler, "/api/v1/site/http?max_redirects=0&method=head&url="+url.QueryEscape(srv.URL+"/old"), &result))
+	assert.Equal(t, http.StatusMovedPermanently, result.StatusCode)
+	assert.Equal(t, "stopped after 0 redirects", result.Error)
+
+	result = HTTPResult{}
+	require.Equal(t, http.StatusOK, getJSON(t, handler, "/api/v1/site/http?url="+url.QueryEscape(srv.URL+"/loop"), &result))
+	assert.Len(t, result.Redirects, 6)
+	assert.Equal(t, "stopped after 5 redirects", result.Error)
+
+	for _, query := range []string{"url=ftp://example.com", "url=/relative", "method=POST&url=http://example.com", "max_redirects=11&url=http://example.com"} {
+		assert.Equal(t, http.StatusBadRequest, getJSON(t, handler, "/api/v1/site/http?"+query, nil), query)
+	}
+}
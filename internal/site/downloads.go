@@ -0,0 +1,407 @@
+package site
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// defaultMaxUploadSize is the largest file accepted when no limit is configured
+const defaultMaxUploadSize = 10 << 20
+
+// metadataDir is the subdirectory of the downloads directory that holds the metadata of each file
+const metadataDir = ".meta"
+
+var (
+	// ErrDownloadNotFound is returned for a file that is not in the catalogue.
+	ErrDownloadNotFound = errors.New("file not found")
+	// ErrDownloadTooLarge is returned when an upload exceeds the size limit.
+	ErrDownloadTooLarge = errors.New("file too large")
+)
+
+// Download describes a file in the downloads catalogue
+type Download struct {
+	ID string `json:"id" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`
+	// the name the file was uploaded with, offered to clients when it is downloaded
+	Name        string `json:"name" example:"report.pdf"`
+	Size        int64  `json:"size" example:"1024"`
+	ContentType string `json:"content_type" example:"application/pdf"`
+	// hex encoded SHA-256 of the contents
+	SHA256   string    `json:"sha256"`
+	Uploaded time.Time `json:"uploaded"`
+}
+
+// Downloads is a catalogue of files kept in a directory. The metadata of each file is kept
+// next to it, and is computed for files copied into the directory by other means.
+type Downloads struct {
+	dir     string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+// NewDownloads returns a catalogue of the files in dir accepting uploads of up to maxSize bytes.
+// The directory is created by the first upload.
+func NewDownloads(dir string, maxSize int64) *Downloads {
+	if maxSize <= 0 {
+		maxSize = defaultMaxUploadSize
+	}
+	return &Downloads{dir: dir, maxSize: maxSize}
+}
+
+// MaxSize returns the size of the largest file accepted, in bytes.
+func (d *Downloads) MaxSize() int64 {
+	return d.maxSize
+}
+
+// Save adds the contents of r to the catalogue under a new ID.
+// ErrDownloadTooLarge is returned if r holds more than the size limit.
+func (d *Downloads) Save(name string, r io.Reader) (Download, error) {
+	if err := os.MkdirAll(d.dir, 0o750); err != nil {
+		return Download{}, err
+	}
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return Download{}, err
+	}
+	file, err := os.CreateTemp(d.dir, ".upload-*")
+	if err != nil {
+		return Download{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	sniff := &sniffer{}
+	size, err := io.Copy(io.MultiWriter(file, hash, sniff), io.LimitReader(r, d.maxSize+1))
+	if err != nil {
+		return Download{}, err
+	}
+	if size > d.maxSize {
+		return Download{}, ErrDownloadTooLarge
+	}
+	if err = file.Close(); err != nil {
+		return Download{}, err
+	}
+
+	download := Download{
+		ID:          uid.String(),
+		Name:        downloadName(name, uid.String()),
+		Size:        size,
+		ContentType: sniff.contentType(name),
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	path := filepath.Join(d.dir, download.ID)
+	if err = os.Rename(file.Name(), path); err != nil {
+		return Download{}, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Download{}, err
+	}
+	download.Uploaded = fi.ModTime().UTC()
+	if err = d.writeMetadata(download); err != nil {
+		os.Remove(path)
+		return Download{}, err
+	}
+	return download, nil
+}
+
+// List returns the files in the catalogue, most recently uploaded first.
+func (d *Downloads) List() ([]Download, error) {
+	entries, err := os.ReadDir(d.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Download{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	downloads := make([]Download, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		download, cached, err := d.stat(entry.Name())
+		if errors.Is(err, ErrDownloadNotFound) {
+			// removed since the directory was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !cached {
+			// the metadata is computed again next time if it cannot be kept
+			d.writeMetadata(download)
+		}
+		downloads = append(downloads, download)
+	}
+	sort.SliceStable(downloads, func(i, j int) bool {
+		return downloads[i].Uploaded.After(downloads[j].Uploaded)
+	})
+	return downloads, nil
+}
+
+// Stat returns the metadata of the file with the given ID.
+func (d *Downloads) Stat(id string) (Download, error) {
+	download, _, err := d.stat(id)
+	return download, err
+}
+
+// Open returns the file with the given ID for reading, along with its metadata.
+func (d *Downloads) Open(id string) (*os.File, Download, error) {
+	download, _, err := d.stat(id)
+	if err != nil {
+		return nil, Download{}, err
+	}
+	file, err := os.Open(filepath.Join(d.dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Download{}, ErrDownloadNotFound
+	}
+	return file, download, err
+}
+
+// stat reads the metadata of a file, computing it if it is missing or out of date.
+// It reports whether the metadata was read from disk.
+func (d *Downloads) stat(id string) (Download, bool, error) {
+	fi, err := os.Stat(filepath.Join(d.dir, id))
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !fi.Mode().IsRegular()) {
+		return Download{}, false, ErrDownloadNotFound
+	}
+	if err != nil {
+		return Download{}, false, err
+	}
+	var download Download
+	if data, err := os.ReadFile(d.metadataPath(id)); err == nil && json.Unmarshal(data, &download) == nil &&
+		download.Size == fi.Size() && download.Uploaded.Equal(fi.ModTime()) {
+		return download, true, nil
+	}
+
+	file, err := os.Open(filepath.Join(d.dir, id))
+	if err != nil {
+		return Download{}, false, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	sniff := &sniffer{}
+	if _, err = io.Copy(io.MultiWriter(hash, sniff), file); err != nil {
+		return Download{}, false, err
+	}
+	return Download{
+		ID:          id,
+		Name:        downloadName(download.Name, id),
+		Size:        fi.Size(),
+		ContentType: sniff.contentType(download.Name),
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Uploaded:    fi.ModTime().UTC(),
+	}, false, nil
+}
+
+func (d *Downloads) writeMetadata(download Download) error {
+	data, err := json.Marshal(download)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Join(d.dir, metadataDir), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(d.metadataPath(download.ID), data, 0o640)
+}
+
+func (d *Downloads) metadataPath(id string) string {
+	return filepath.Join(d.dir, metadataDir, id+".json")
+}
+
+// catalogueID reports whether id names a file in the catalogue directory rather than elsewhere.
+func catalogueID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && !strings.HasPrefix(id, ".")
+}
+
+// downloadName returns the base of an uploaded file name, or id if there is none.
+func downloadName(name, id string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == "" {
+		return id
+	}
+	return name
+}
+
+// sniffer keeps the first bytes written to it for content type detection
+type sniffer struct {
+	head []byte
+}
+
+func (s *sniffer) Write(p []byte) (int, error) {
+	if n := 512 - len(s.head); n > 0 {
+		s.head = append(s.head, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// contentType detects the content type from the contents, falling back to the extension of name
+// when the contents are not recognised.
+func (s *sniffer) contentType(name string) string {
+	detected := http.DetectContentType(s.head)
+	if detected == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" {
+			return byExt
+		}
+	}
+	return detected
+}
+
+// contentDisposition returns a Content-Disposition header offering the file as an attachment.
+func contentDisposition(download Download) string {
+	if v := mime.FormatMediaType("attachment", map[string]string{"filename": download.Name}); v != "" {
+		return v
+	}
+	return fmt.Sprintf("attachment; filename=%q", download.ID)
+}
+
+// resumes reports whether a request only asks for ranges after the start of the file, as a client
+// resuming an interrupted download does. Suffix ranges, which may cover the whole file, do not count.
+func resumes(r *http.Request) bool {
+	spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes=")
+	if !ok {
+		return false
+	}
+	for _, rng := range strings.Split(spec, ",") {
+		start, _, _ := strings.Cut(strings.TrimSpace(rng), "-")
+		if n, err := strconv.ParseInt(start, 10, 64); err != nil || n <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// POST request uploading a file to the downloads catalogue
+//
+// @Summary      Upload File
+// @Description  Add a file to the downloads catalogue using a multipart form with a "file" field
+// @Tags         site
+// @Accept       mpfd
+// @Produce      json
+// @Param		 file	formData	file				true	"file"
+// @Success      201  {object}  Download
+// @Failure      400  {object}  model.APIError
+// @Failure      413  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/downloads [post]
+func (s *SiteHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling POST at %s\n", r.URL.Path)
+	// allow for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, s.downloads.MaxSize()+64<<10)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Multipart form expected", http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "File not provided", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %s", err), uploadErrorStatus(err))
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		download, err := s.downloads.Save(part.FileName(), part)
+		part.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %s", err), uploadErrorStatus(err))
+			return
+		}
+		s.logger.With(r.Context(), "download_id", download.ID).Infof("Uploaded %s (%d bytes)", download.Name, download.Size)
+		w.Header().Set("Location", "/api/v1/site/downloads/"+download.ID)
+		writeJSON(w, http.StatusCreated, download)
+		return
+	}
+}
+
+// uploadErrorStatus maps the failure of an upload to a status code.
+func uploadErrorStatus(err error) int {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrDownloadTooLarge), errors.As(err, &maxBytes):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// downloadErrorStatus maps a downloads catalogue error to a status code.
+func downloadErrorStatus(err error) int {
+	if errors.Is(err, ErrDownloadNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// GET request listing the downloads catalogue
+//
+// @Summary      List Downloads
+// @Description  List the files that can be downloaded, most recently uploaded first
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   Download
+// @Failure      500  {object}  model.APIError
+// @Router       /site/downloads [get]
+func (s *SiteHandler) ListDownloads(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	downloads, err := s.downloads.List()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, downloads)
+}
+
+// GET request for the metadata of a file in the downloads catalogue
+//
+// @Summary      Get Download
+// @Description  Get the size, content type, checksum and upload time of a file
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Param		 id	path		string				true	"id"
+// @Success      200  {object}  Download
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/downloads/{id} [get]
+func (s *SiteHandler) GetDownload(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	id := r.PathValue("id")
+	if !catalogueID(id) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	download, err := s.downloads.Stat(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), downloadErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, download)
+}
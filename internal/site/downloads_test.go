@@ -0,0 +1,183 @@
+package site
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/link"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func upload(handler http.Handler, name string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("description", "ignored")
+	part, _ := form.CreateFormFile("file", name)
+	part.Write(data)
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/site/downloads", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestDownloads(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "12345"), []byte("legacy file\n"), 0o644))
+	logger, _ := log.NewForTest()
+	handler := MakeHTTPHandler(logger, &config.Config{}, Services{Downloads: NewDownloads(dir, 1024)})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	data := []byte("0123456789abcdefghij")
+	res := upload(handler, `C:\reports\numbers.txt`, data)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var download Download
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &download))
+	sum := sha256.Sum256(data)
+	assert.Equal(t, "numbers.txt", download.Name)
+	assert.Equal(t, int64(len(data)), download.Size)
+	assert.Equal(t, "text/plain; charset=utf-8", download.ContentType)
+	assert.Equal(t, hex.EncodeToString(sum[:]), download.SHA256)
+	assert.Equal(t, "/api/v1/site/downloads/"+download.ID, res.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(handler, "big.bin", make([]byte, 1025)).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(httptest.NewRequest(http.MethodPost, "/api/v1/site/downloads", strings.NewReader("x"))).Code)
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/v1/site/downloads", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var downloads []Download
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &downloads))
+	require.Len(t, downloads, 2)
+	ids := []string{downloads[0].ID, downloads[1].ID}
+	assert.ElementsMatch(t, []string{"12345", download.ID}, ids)
+	assert.FileExists(t, filepath.Join(dir, metadataDir, "12345.json"))
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/v1/site/downloads/"+download.ID, nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), download.SHA256)
+	assert.Equal(t, http.StatusNotFound, serve(httptest.NewRequest(http.MethodGet, "/api/v1/site/downloads/missing", nil)).Code)
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/v1/site/download/"+download.ID, nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, data, res.Body.Bytes())
+	assert.Equal(t, `attachment; filename=numbers.txt`, res.Header().Get("Content-Disposition"))
+	assert.Equal(t, "bytes", res.Header().Get("Accept-Ranges"))
+	assert.NotEmpty(t, res.Header().Get("Last-Modified"))
+	etag := res.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/download/"+download.ID, nil)
+	req.Header.Set("Range", "bytes=10-14")
+	res = serve(req)
+	require.Equal(t, http.StatusPartialContent, res.Code)
+	assert.Equal(t, "abcde", res.Body.String())
+	assert.Equal(t, "bytes 10-14/20", res.Header().Get("Content-Range"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/site/download/"+download.ID, nil)
+	req.Header.Set("If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, serve(req).Code)
+
+	res = serve(httptest.NewRequest(http.MethodGet, "/api/v1/site/download/12345", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "legacy file\n", res.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(httptest.NewRequest(http.MethodGet, "/api/v1/site/download/67890", nil)).Code)
+}
+
+func TestDownloadLinks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "12345"), []byte("shared file\n"), 0o644))
+	logger, _ := log.NewForTest()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	store, err := link.NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	handler := MakeHTTPHandler(logger, &config.Config{}, Services{
+		Downloads: NewDownloads(dir, 0),
+		Links:     link.NewManager(logger, []byte("test-key"), store),
+	})
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return res
+	}
+
+	res := serve(http.MethodPost, "/api/v1/site/downloads/12345/links", `{"single_use":true}`)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var l link.Link
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &l))
+	assert.True(t, strings.HasPrefix(l.URL, "/api/v1/site/download/12345?"))
+	assert.Equal(t, "/api/v1/site/links/"+l.ID, res.Header().Get("Location"))
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/v1/site/downloads/missing/links", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/site/downloads/12345/links", `{"expires_in":-1}`).Code)
+
+	res = serve(http.MethodGet, l.URL, "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "shared file\n", res.Body.String())
+	assert.Equal(t, http.StatusGone, serve(http.MethodGet, l.URL, "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, strings.Replace(l.URL, "sig=", "sig=x", 1), "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/site/download/12345", "").Code, "files are only served through links")
+
+	// a single use link serves the rest of the file to the client that used it a few times
+	res = serve(http.MethodPost, "/api/v1/site/downloads/12345/links", `{"single_use":true}`)
+	require.Equal(t, http.StatusCreated, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &l))
+	ranged := func(rng, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, l.URL, nil)
+		req.Header.Set("Range", rng)
+		req.RemoteAddr = remoteAddr
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+	res = ranged("bytes=0-5", "192.0.2.7:1234")
+	require.Equal(t, http.StatusPartialContent, res.Code)
+	assert.Equal(t, "shared", res.Body.String())
+	res = ranged("bytes=6-", "192.0.2.7:5678")
+	require.Equal(t, http.StatusPartialContent, res.Code)
+	assert.Equal(t, " file\n", res.Body.String())
+	assert.Equal(t, http.StatusGone, ranged("bytes=6-", "192.0.2.8:1234").Code)
+	assert.Equal(t, http.StatusGone, serve(http.MethodGet, l.URL, "").Code)
+	assert.Equal(t, http.StatusGone, ranged("bytes=0-", "192.0.2.7:1234").Code, "the whole file is not served again")
+	assert.Equal(t, http.StatusGone, ranged("bytes=-12", "192.0.2.7:1234").Code)
+
+	// the existence of a file is not revealed without a valid link
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/site/download/missing", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/site/download/..%2Fsecret", "").Code)
+
+	res = serve(http.MethodPost, "/api/v1/site/downloads/12345/links", "")
+	require.Equal(t, http.StatusCreated, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &l))
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/v1/site/links/"+l.ID, "").Code)
+	assert.Equal(t, http.StatusGone, serve(http.MethodGet, l.URL, "").Code)
+
+	res = serve(http.MethodGet, "/api/v1/site/links/"+l.ID, "")
+	require.Equal(t, http.StatusOK, res.Code)
+	var audit link.Audit
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &audit))
+	assert.NotNil(t, audit.RevokedOn)
+	require.Len(t, audit.Redemptions, 1)
+	assert.Equal(t, link.ErrLinkRevoked.Error(), audit.Redemptions[0].Error)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/site/links/missing", "").Code)
+}
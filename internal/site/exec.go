@@ -0,0 +1,171 @@
+package site
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Defaults of a CommandRunner
+const (
+	defaultCommandTimeout     = 60 * time.Second
+	defaultCommandMaxOutput   = 64 << 10
+	defaultCommandConcurrency = 4
+	// how long a command waits for a free slot before ErrCommandBusy is returned
+	commandQueueTimeout = 5 * time.Second
+)
+
+// commandPath is the only environment variable passed on to commands besides the locale
+const commandPath = "/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin"
+
+// ErrCommandBusy is returned when every command slot stays taken for too long
+var ErrCommandBusy = errors.New("too many commands running, try again later")
+
+// Execution describes a run of an external command
+type Execution struct {
+	Command string
+	Args    []string
+	// the exit code, or -1 if the command could not be started or was killed
+	ExitCode int
+	// the signal that killed the command, if any
+	Signal string
+	// whether the command was killed because it ran for longer than its timeout
+	TimedOut bool
+	Duration time.Duration
+	// combined standard output and standard error, up to the runner's limit
+	Output string
+	// whether output beyond the limit was discarded
+	Truncated bool
+}
+
+// CommandRunnerConfig bounds the commands run by a CommandRunner. Zero values select the defaults.
+type CommandRunnerConfig struct {
+	// the longest a command may run (default 60 seconds)
+	Timeout time.Duration
+	// the number of bytes of output captured (default 64 KiB)
+	MaxOutput int
+	// the number of commands allowed to run at the same time (default 4)
+	Concurrency int
+	// the working directory of commands (default the system temporary directory)
+	Dir string
+}
+
+// CommandRunner runs external commands with a timeout, a cap on captured output, a limit on concurrent
+// executions, a minimal environment and a fixed working directory.
+type CommandRunner struct {
+	timeout   time.Duration
+	maxOutput int
+	dir       string
+	env       []string
+	slots     chan struct{}
+}
+
+// NewCommandRunner creates a runner with the given bounds.
+func NewCommandRunner(cfg CommandRunnerConfig) *CommandRunner {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultCommandTimeout
+	}
+	if cfg.MaxOutput <= 0 {
+		cfg.MaxOutput = defaultCommandMaxOutput
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultCommandConcurrency
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = os.TempDir()
+	}
+	return &CommandRunner{
+		timeout:   cfg.Timeout,
+		maxOutput: cfg.MaxOutput,
+		dir:       cfg.Dir,
+		// the C locale keeps the output of commands parseable
+		env:   []string{"PATH=" + commandPath, "LANG=C", "LC_ALL=C"},
+		slots: make(chan struct{}, cfg.Concurrency),
+	}
+}
+
+// Run runs a command until it exits, ctx is done or timeout elapses. The timeout is capped by the
+// runner's; zero selects it. onLine, if not nil, is called with each line of output as it is printed,
+// including lines beyond the capture limit. The returned error is the one reported by exec for a failed
+// command, ErrCommandBusy, or the context error if the command was stopped.
+func (r *CommandRunner) Run(ctx context.Context, timeout time.Duration, name string, args []string, onLine func(line string)) (Execution, error) {
+	exe := Execution{Command: name, Args: args, ExitCode: -1}
+
+	queued, cancel := context.WithTimeout(ctx, commandQueueTimeout)
+	select {
+	case r.slots <- struct{}{}:
+		cancel()
+		defer func() { <-r.slots }()
+	case <-queued.Done():
+		cancel()
+		if ctx.Err() != nil {
+			return exe, ctx.Err()
+		}
+		return exe, ErrCommandBusy
+	}
+
+	if timeout <= 0 || timeout > r.timeout {
+		timeout = r.timeout
+	}
+	ctx, cancel = context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	//
+	// Command Injection : dataflow
+	//
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = r.env
+	cmd.Dir = r.dir
+	// don't wait forever for output from stray child processes once the command has been killed
+	cmd.WaitDelay = time.Second
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		exe.Duration = time.Since(start)
+		return exe, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		done <- err
+	}()
+	var output strings.Builder
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if output.Len()+len(line)+1 <= r.maxOutput {
+			output.WriteString(line)
+			output.WriteByte('\n')
+		} else {
+			exe.Truncated = true
+		}
+		if onLine != nil {
+			onLine(line)
+		}
+	}
+	// drain whatever the scanner couldn't handle so that the command doesn't block on a full pipe
+	if n, _ := io.Copy(io.Discard, pr); n > 0 {
+		exe.Truncated = true
+	}
+	err := <-done
+	exe.Duration = time.Since(start)
+	exe.Output = output.String()
+	exe.ExitCode = cmd.ProcessState.ExitCode()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		exe.Signal = status.Signal().String()
+	}
+	if ctx.Err() != nil {
+		exe.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+		return exe, ctx.Err()
+	}
+	return exe, err
+}
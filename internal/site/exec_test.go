@@ -0,0 +1,80 @@
+package site
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandRunner(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SECRET_TOKEN", "do-not-leak")
+	commands := NewCommandRunner(CommandRunnerConfig{Timeout: 2 * time.Second, MaxOutput: 64, Dir: dir})
+	ctx := context.Background()
+
+	var lines []string
+	exe, err := commands.Run(ctx, 0, "sh", []string{"-c", "pwd; env; exit 3"}, func(line string) {
+		lines = append(lines, line)
+	})
+	require.Error(t, err)
+	assert.Equal(t, 3, exe.ExitCode)
+	assert.Empty(t, exe.Signal)
+	assert.False(t, exe.TimedOut)
+	assert.Equal(t, dir, lines[0])
+	assert.NotContains(t, strings.Join(lines, "\n"), "SECRET_TOKEN")
+	assert.Contains(t, lines, "LANG=C")
+
+	exe, err = commands.Run(ctx, 0, "sh", []string{"-c", "for i in 1 2 3 4 5 6 7 8 9 10; do echo line $i; done"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, exe.ExitCode)
+	assert.True(t, exe.Truncated)
+	assert.LessOrEqual(t, len(exe.Output), 64)
+	assert.True(t, strings.HasPrefix(exe.Output, "line 1\nline 2\n"))
+
+	// the runner's timeout caps the one asked for
+	start := time.Now()
+	exe, err = commands.Run(ctx, time.Hour, "sleep", []string{"30"}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.True(t, exe.TimedOut)
+	assert.Equal(t, -1, exe.ExitCode)
+	assert.Equal(t, "killed", exe.Signal)
+
+	_, err = commands.Run(ctx, 0, "no-such-command", nil, nil)
+	assert.Error(t, err)
+	_, err = os.Stat(dir)
+	assert.NoError(t, err)
+}
+
+func TestCommandRunner_Concurrency(t *testing.T) {
+	commands := NewCommandRunner(CommandRunnerConfig{Concurrency: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	started := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		commands.Run(ctx, 0, "sh", []string{"-c", "echo started; exec sleep 30"}, func(string) { close(started) })
+	}()
+	<-started
+
+	// a command waiting for the slot gives up with its context
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	exe, err := commands.Run(waitCtx, 0, "true", nil, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, -1, exe.ExitCode)
+
+	cancel()
+	wg.Wait()
+	exe, err = commands.Run(context.Background(), 0, "true", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exe.ExitCode)
+}
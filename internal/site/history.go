@@ -0,0 +1,303 @@
+package site
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// Bounds of a page of command history
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// HistoryEntry records a command run on behalf of a client
+type HistoryEntry struct {
+	// sequence number, increasing with every command run
+	ID      int64     `json:"id" example:"1"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command" example:"ping"`
+	Args    []string  `json:"args"`
+	// the remote address of the client that requested the command
+	Client    string `json:"client" example:"127.0.0.1:51234"`
+	RequestID string `json:"request_id,omitempty"`
+	// -1 if the command could not be started or was killed
+	ExitCode int `json:"exit_code"`
+	// the signal that killed the command, if any
+	Signal   string `json:"signal,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+	// run time in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Output   string  `json:"output"`
+	// whether output beyond the capture limit was discarded
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// HistoryFilter selects command history entries. Zero values match every entry.
+type HistoryFilter struct {
+	Command string
+	// matches entries with an argument containing the text, such as a hostname
+	Arg       string
+	Client    string
+	RequestID string
+	ExitCode  *int
+	Since     time.Time
+	Until     time.Time
+}
+
+// Match reports whether the entry is selected by the filter.
+func (f HistoryFilter) Match(e HistoryEntry) bool {
+	if f.Command != "" && e.Command != f.Command {
+		return false
+	}
+	if f.Arg != "" && !containsArg(e.Args, f.Arg) {
+		return false
+	}
+	if f.Client != "" && !strings.HasPrefix(e.Client, f.Client) {
+		return false
+	}
+	if f.RequestID != "" && e.RequestID != f.RequestID {
+		return false
+	}
+	if f.ExitCode != nil && e.ExitCode != *f.ExitCode {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+func containsArg(args []string, text string) bool {
+	for _, arg := range args {
+		if strings.Contains(arg, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryPage is a page of command history, newest first
+type HistoryPage struct {
+	// number of entries matching the filter
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// History is an append-only command history kept in a file with one JSON entry per line.
+type History struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	lastID int64
+}
+
+// NewHistory opens the command history file, creating it if needed, and continues its numbering. A last
+// line cut short by a crash is ended, so that the next entry starts on a line of its own.
+func NewHistory(path string) (*History, error) {
+	h := &History{path: path}
+	err := h.scan(func(e HistoryEntry) {
+		h.lastID = e.ID
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	h.file, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	if err := endLastLine(h.file); err != nil {
+		h.file.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// endLastLine appends a newline to a file that doesn't end with one.
+func endLastLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+// Close closes the command history file.
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.file.Close()
+}
+
+// Record appends an execution requested by a client to the history.
+func (h *History) Record(ctx context.Context, client string, exe Execution) (HistoryEntry, error) {
+	entry := HistoryEntry{
+		Time:      time.Now().UTC(),
+		Command:   exe.Command,
+		Args:      exe.Args,
+		Client:    client,
+		RequestID: log.RequestID(ctx),
+		ExitCode:  exe.ExitCode,
+		Signal:    exe.Signal,
+		TimedOut:  exe.TimedOut,
+		Truncated: exe.Truncated,
+		Duration:  float64(exe.Duration.Microseconds()) / 1000,
+		Output:    exe.Output,
+	}
+	if entry.Args == nil {
+		entry.Args = []string{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry.ID = h.lastID + 1
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	if _, err := h.file.Write(append(data, '\n')); err != nil {
+		return entry, err
+	}
+	h.lastID = entry.ID
+	return entry, nil
+}
+
+// Query returns a page of the entries matching the filter, newest first. There is no index: every query
+// reads the whole history file, so queries slow down as the history grows and the file should be rotated
+// or trimmed when it gets large.
+func (h *History) Query(f HistoryFilter, offset, limit int) (HistoryPage, error) {
+	var matches []HistoryEntry
+	err := h.scan(func(e HistoryEntry) {
+		if f.Match(e) {
+			matches = append(matches, e)
+		}
+	})
+	if err != nil {
+		return HistoryPage{}, err
+	}
+	page := HistoryPage{Total: len(matches), Offset: offset, Limit: limit, Entries: []HistoryEntry{}}
+	for i := len(matches) - 1 - offset; i >= 0 && len(page.Entries) < limit; i-- {
+		page.Entries = append(page.Entries, matches[i])
+	}
+	return page, nil
+}
+
+// scan calls fn with every entry in the history file, oldest first. Lines that can't be decoded,
+// such as one cut short by a crash, are skipped.
+func (h *History) scan(fn func(HistoryEntry)) error {
+	file, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var e HistoryEntry
+			if json.Unmarshal(line, &e) == nil {
+				fn(e)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// GET request returning the command history
+//
+// @Summary      Get Command History
+// @Description  List the commands run by the site API, newest first
+// @Tags         site
+// @Produce      json
+// @Param		 command	query		string		false	"command name"	example("ping")
+// @Param		 hostname	query		string		false	"text contained in one of the arguments, such as a hostname"
+// @Param		 client		query		string		false	"client address or address prefix"
+// @Param		 request_id	query		string		false	"request ID"
+// @Param		 exit_code	query		int			false	"exit code"
+// @Param		 since		query		string		false	"RFC 3339 time of the oldest entry"
+// @Param		 until		query		string		false	"RFC 3339 time after the newest entry"
+// @Param		 offset		query		int			false	"number of matching entries to skip"	default(0)
+// @Param		 limit		query		int			false	"maximum number of entries (1-500)"	default(50)
+// @Success      200  {object}  HistoryPage
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/history [get]
+func (s *SiteHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f := HistoryFilter{
+		Command:   query.Get("command"),
+		Arg:       query.Get("hostname"),
+		Client:    query.Get("client"),
+		RequestID: query.Get("request_id"),
+	}
+	var err error
+	if v := query.Get("exit_code"); v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid exit_code", http.StatusBadRequest)
+			return
+		}
+		f.ExitCode = &code
+	}
+	if v := query.Get("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if f.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid until", http.StatusBadRequest)
+			return
+		}
+	}
+	offset, limit := 0, defaultHistoryLimit
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxHistoryLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := s.history.Query(f, offset, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,98 @@
+package site
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/middleware"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestHistory(t *testing.T) {
+	history := newTestHistory(t)
+	ctx := context.Background()
+	for _, host := range []string{"alpha", "beta", "alpha"} {
+		_, err := history.Record(ctx, "10.0.0.1:1234", Execution{Command: "ping", Args: []string{"-c", "1", host}, Duration: time.Millisecond})
+		require.NoError(t, err)
+	}
+	_, err := history.Record(ctx, "10.0.0.2:1234", Execution{Command: "nslookup", Args: []string{"gamma"}, ExitCode: 1})
+	require.NoError(t, err)
+
+	page, err := history.Query(HistoryFilter{}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 4, page.Total)
+	require.Len(t, page.Entries, 4)
+	assert.Equal(t, int64(4), page.Entries[0].ID)
+	assert.Equal(t, int64(1), page.Entries[3].ID)
+	assert.Equal(t, 1.0, page.Entries[3].Duration)
+
+	page, err = history.Query(HistoryFilter{Command: "ping", Arg: "alpha"}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, int64(1), page.Entries[0].ID)
+
+	exitCode := 1
+	page, err = history.Query(HistoryFilter{Client: "10.0.0.2", ExitCode: &exitCode}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, "nslookup", page.Entries[0].Command)
+
+	// a torn last line is skipped and numbering carries on after a restart
+	require.NoError(t, history.Close())
+	file, err := os.OpenFile(history.path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	file.WriteString(`{"id":5,"comm`)
+	file.Close()
+	history, err = NewHistory(history.path)
+	require.NoError(t, err)
+	defer history.Close()
+	entry, err := history.Record(ctx, "10.0.0.1:1234", Execution{Command: "ping"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), entry.ID)
+	page, err = history.Query(HistoryFilter{}, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5, page.Total, "the entry recorded after the torn line is kept")
+	assert.Equal(t, []HistoryEntry{entry}, page.Entries)
+}
+
+func TestGetHistory(t *testing.T) {
+	installFakePing(t)
+	logger, _ := log.NewForTest()
+	handler := middleware.RequestID()(MakeHTTPHandler(logger, &config.Config{}, Services{History: newTestHistory(t)}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/site/ping?hostname=localhost&count=1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "req-1", res.Header().Get("X-Request-ID"))
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/site/history?request_id=req-1&command=ping", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var page HistoryPage
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &page))
+	require.Equal(t, 1, page.Total)
+	entry := page.Entries[0]
+	assert.Equal(t, []string{"-c", "1", "-i", "1", "-W", "5", "localhost"}, entry.Args)
+	assert.Equal(t, "192.0.2.1:1234", entry.Client)
+	assert.Equal(t, 0, entry.ExitCode)
+	assert.Contains(t, entry.Output, "1 packets transmitted")
+	assert.Equal(t, 50, page.Limit)
+
+	for _, query := range []string{"limit=0", "limit=1000", "offset=-1", "since=yesterday", "exit_code=x"} {
+		res = httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/site/history?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, res.Code, query)
+	}
+}
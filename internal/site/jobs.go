@@ -0,0 +1,166 @@
+package site
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+)
+
+// normalizer is implemented by the parameters of each diagnostic
+type normalizer interface {
+	normalize() error
+}
+
+// runner adapts a diagnostic to a job.Runner
+type runner[P any, PP interface {
+	*P
+	normalizer
+}] func(ctx context.Context, params P, progress func(int)) (interface{}, error)
+
+func (fn runner[P, PP]) Prepare(raw json.RawMessage) (json.RawMessage, error) {
+	var params P
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, err
+		}
+	}
+	if err := PP(&params).normalize(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(params)
+}
+
+func (fn runner[P, PP]) Run(ctx context.Context, raw json.RawMessage, progress func(int)) (interface{}, error) {
+	var params P
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	return fn(ctx, params, progress)
+}
+
+// Runners returns the site diagnostics that can be run as jobs, by job type. Commands are run by commands.
+func Runners(commands *CommandRunner) map[string]job.Runner {
+	return map[string]job.Runner{
+		"ping": runner[Site, *Site](func(ctx context.Context, site Site, progress func(int)) (interface{}, error) {
+			replies := 0
+			result, _, err := ping(ctx, commands, site, func(line string) {
+				if pingReplyRe.MatchString(line) && replies < site.Count {
+					replies++
+					progress(99 * replies / site.Count)
+				}
+			})
+			return result, err
+		}),
+		"dns": runner[DNSLookup, *DNSLookup](func(ctx context.Context, lookup DNSLookup, _ func(int)) (interface{}, error) {
+			return lookupDNS(ctx, lookup)
+		}),
+		"port": runner[PortCheck, *PortCheck](func(ctx context.Context, check PortCheck, _ func(int)) (interface{}, error) {
+			return checkPort(ctx, check)
+		}),
+		"tls": runner[TLSCheck, *TLSCheck](func(ctx context.Context, check TLSCheck, _ func(int)) (interface{}, error) {
+			return checkTLS(ctx, check)
+		}),
+		"http": runner[HTTPProbe, *HTTPProbe](func(ctx context.Context, probe HTTPProbe, _ func(int)) (interface{}, error) {
+			return probeHTTP(ctx, probe)
+		}),
+	}
+}
+
+// POST request submitting a site diagnostic job
+//
+// @Summary      Submit Job
+// @Description  Run a site diagnostic in the background
+// @Description  Example: {"type": "ping", "params": {"hostname": "localhost", "count": 10}}
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Param		 JobRequest	body		JobRequest			true	"JobRequest"
+// @Success      202  {object}  job.Job
+// @Header       202  {string}  Location  "URL of the job"
+// @Failure      400  {object}  model.APIError
+// @Failure      503  {object}  model.APIError
+// @Router       /site/jobs [post]
+func (s *SiteHandler) SubmitJob(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling POST at %s\n", r.URL.Path)
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	j, err := s.jobs.Submit(r.Context(), req.Type, req.Params)
+	if err != nil {
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/site/jobs/%s", j.ID))
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+// GET request returning the state of a job
+//
+// @Summary      Get Job
+// @Description  Get the status, progress and result of a site diagnostic job
+// @Tags         site
+// @Produce      json
+// @Param		 id	path		string				true	"id"
+// @Success      200  {object}  job.Job
+// @Failure      404  {object}  model.APIError
+// @Router       /site/jobs/{id} [get]
+func (s *SiteHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	j, err := s.jobs.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+// DELETE request cancelling a job
+//
+// @Summary      Cancel Job
+// @Description  Cancel a queued or running site diagnostic job
+// @Tags         site
+// @Produce      json
+// @Param		 id	path		string				true	"id"
+// @Success      200  {object}  job.Job	"the job was cancelled before it started"
+// @Success      202  {object}  job.Job	"the running job is being cancelled"
+// @Failure      404  {object}  model.APIError
+// @Failure      409  {object}  model.APIError
+// @Router       /site/jobs/{id} [delete]
+func (s *SiteHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling DELETE at %s\n", r.URL.Path)
+	j, err := s.jobs.Cancel(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), jobErrorStatus(err))
+		return
+	}
+	status := http.StatusOK
+	if !j.Status.Finished() {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, j)
+}
+
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, job.ErrUnknownType), errors.Is(err, job.ErrInvalidParams):
+		return http.StatusBadRequest
+	case errors.Is(err, job.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, job.ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, job.ErrQueueFull), errors.Is(err, job.ErrManagerStopped):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,70 @@
+package site
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestJobs(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	store, err := job.NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	jobs := job.NewManager(logger, store, 2, 10, Runners(NewCommandRunner(CommandRunnerConfig{})))
+	defer jobs.Close()
+	handler := MakeHTTPHandler(logger, &config.Config{}, Services{Jobs: jobs})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return res
+	}
+
+	res := serve(http.MethodPost, "/api/v1/site/jobs", fmt.Sprintf(`{"type":"port","params":{"hostname":"127.0.0.1","port":%d}}`, port))
+	require.Equal(t, http.StatusAccepted, res.Code)
+	var submitted job.Job
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &submitted))
+	assert.Equal(t, "/api/v1/site/jobs/"+submitted.ID, res.Header().Get("Location"))
+	assert.JSONEq(t, fmt.Sprintf(`{"hostname":"127.0.0.1","port":%d,"timeout":5}`, port), string(submitted.Params))
+
+	var done job.Job
+	require.Eventually(t, func() bool {
+		res := serve(http.MethodGet, res.Header().Get("Location"), "")
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &done))
+		return done.Status.Finished()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, job.StatusSucceeded, done.Status)
+	var result PortResult
+	require.NoError(t, json.Unmarshal(done.Result, &result))
+	assert.True(t, result.Open)
+
+	assert.Equal(t, http.StatusConflict, serve(http.MethodDelete, "/api/v1/site/jobs/"+submitted.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/site/jobs/missing", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/site/jobs", `{"type":"traceroute"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/site/jobs", `{"type":"ping","params":{"hostname":"localhost","count":500}}`).Code)
+}
@@ -0,0 +1,111 @@
+package site
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/fortify-presales/insecure-go-api/internal/link"
+)
+
+// POST request minting a signed link to a file in the downloads catalogue
+//
+// @Summary      Create Download Link
+// @Description  Create a signed link to a file that expires, and may be used once only or from one address only
+// @Description  Example: {"expires_in": 600, "single_use": true}
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Param		 id			path		string				true	"file id"	example("12345")
+// @Param		 Request	body		link.Request		false	"Request"
+// @Success      201  {object}  link.Link
+// @Failure      400  {object}  model.APIError
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/downloads/{id}/links [post]
+func (s *SiteHandler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling POST at %s\n", r.URL.Path)
+	id := r.PathValue("id")
+	if !catalogueID(id) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	if _, err := s.downloads.Stat(id); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), downloadErrorStatus(err))
+		return
+	}
+	var req link.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid link request", http.StatusBadRequest)
+		return
+	}
+	l, err := s.links.Create(r.Context(), id, r.RemoteAddr, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), linkErrorStatus(err))
+		return
+	}
+	l.URL = "/api/v1/site/download/" + url.PathEscape(id) + "?" + s.links.Query(l).Encode()
+	w.Header().Set("Location", "/api/v1/site/links/"+l.ID)
+	writeJSON(w, http.StatusCreated, l)
+}
+
+// GET request for a download link and its redemptions
+//
+// @Summary      Get Download Link
+// @Description  Get a signed download link with the audit record of each attempt to use it
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Param		 id	path		string				true	"link id"
+// @Success      200  {object}  link.Audit
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/links/{id} [get]
+func (s *SiteHandler) GetLink(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling GET at %s\n", r.URL.Path)
+	audit, err := s.links.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), linkErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, audit)
+}
+
+// DELETE request revoking a download link
+//
+// @Summary      Revoke Download Link
+// @Description  Stop a signed download link from being used
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Param		 id	path		string				true	"link id"
+// @Success      204
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/links/{id} [delete]
+func (s *SiteHandler) RevokeLink(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling DELETE at %s\n", r.URL.Path)
+	if err := s.links.Revoke(r.Context(), r.PathValue("id")); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), linkErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// linkErrorStatus maps a link error to a status code.
+func linkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, link.ErrLinkNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, link.ErrInvalidExpiry), errors.Is(err, link.ErrInvalidAddress):
+		return http.StatusBadRequest
+	case errors.Is(err, link.ErrBadSignature), errors.Is(err, link.ErrAddressMismatch):
+		return http.StatusForbidden
+	case errors.Is(err, link.ErrLinkExpired), errors.Is(err, link.ErrLinkRevoked), errors.Is(err, link.ErrLinkUsed):
+		return http.StatusGone
+	}
+	return http.StatusInternalServerError
+}
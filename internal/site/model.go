@@ -1,5 +1,333 @@
 package site
 
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Bounds and defaults of the ping parameters
+const (
+	defaultPingCount    = 4
+	maxPingCount        = 20
+	defaultPingTimeout  = 5 // seconds
+	maxPingTimeout      = 30
+	defaultPingInterval = 1.0 // seconds
+	minPingInterval     = 0.2
+	maxPingInterval     = 10.0
+)
+
 type Site struct {
 	Hostname string `json:"hostname"`
+	// number of echo requests to send (1-20, default 4)
+	Count int `json:"count,omitempty" example:"4"`
+	// seconds to wait for each reply (1-30, default 5)
+	Timeout int `json:"timeout,omitempty" example:"5"`
+	// seconds between echo requests (0.2-10, default 1)
+	Interval float64 `json:"interval,omitempty" example:"1"`
+}
+
+// normalize applies the defaults to unset ping parameters and checks that they are within bounds.
+func (s *Site) normalize() error {
+	if s.Hostname == "" {
+		return fmt.Errorf("hostname not provided")
+	}
+	if s.Count == 0 {
+		s.Count = defaultPingCount
+	}
+	if s.Timeout == 0 {
+		s.Timeout = defaultPingTimeout
+	}
+	if s.Interval == 0 {
+		s.Interval = defaultPingInterval
+	}
+	if s.Count < 1 || s.Count > maxPingCount {
+		return fmt.Errorf("count must be between 1 and %d", maxPingCount)
+	}
+	if s.Timeout < 1 || s.Timeout > maxPingTimeout {
+		return fmt.Errorf("timeout must be between 1 and %d seconds", maxPingTimeout)
+	}
+	if s.Interval < minPingInterval || s.Interval > maxPingInterval {
+		return fmt.Errorf("interval must be between %g and %g seconds", minPingInterval, maxPingInterval)
+	}
+	return nil
+}
+
+// PingResult holds the statistics reported by ping
+type PingResult struct {
+	Hostname string `json:"hostname"`
+	// the address that was pinged, if ping resolved the hostname
+	Address         string `json:"address,omitempty"`
+	PacketsSent     int    `json:"packets_sent"`
+	PacketsReceived int    `json:"packets_received"`
+	// percentage of echo requests that were not answered
+	PacketLoss float64 `json:"packet_loss"`
+	// round-trip times, omitted when no replies were received
+	RTT    *RTT   `json:"rtt,omitempty"`
+	Output string `json:"output"`
+}
+
+// RTT holds round-trip time statistics in milliseconds
+type RTT struct {
+	Min  float64 `json:"min_ms"`
+	Avg  float64 `json:"avg_ms"`
+	Max  float64 `json:"max_ms"`
+	Mdev float64 `json:"mdev_ms"`
+}
+
+// DNSLookup holds the parameters of a DNS lookup
+type DNSLookup struct {
+	Hostname string `json:"hostname" example:"localhost"`
+	// record types to look up: A, AAAA, CNAME, MX and TXT (default A, AAAA and CNAME)
+	Types []string `json:"types,omitempty" example:"A,MX"`
+	// address of the DNS server to query, with an optional port (default the system resolver)
+	Resolver string `json:"resolver,omitempty" example:"8.8.8.8"`
+	// seconds to wait for the answers (1-30, default 5)
+	Timeout int `json:"timeout,omitempty" example:"5"`
+}
+
+func (d *DNSLookup) normalize() error {
+	if d.Hostname == "" {
+		return fmt.Errorf("hostname not provided")
+	}
+	if len(d.Types) == 0 {
+		d.Types = []string{"A", "AAAA", "CNAME"}
+	}
+	for i, t := range d.Types {
+		d.Types[i] = strings.ToUpper(strings.TrimSpace(t))
+		if !slices.Contains(dnsRecordTypes, d.Types[i]) {
+			return fmt.Errorf("record type must be one of %s", strings.Join(dnsRecordTypes, ", "))
+		}
+	}
+	if d.Resolver != "" {
+		if _, _, err := net.SplitHostPort(d.Resolver); err != nil {
+			d.Resolver = net.JoinHostPort(strings.Trim(d.Resolver, "[]"), "53")
+		}
+	}
+	return normalizeTimeout(&d.Timeout)
+}
+
+// dnsRecordTypes are the record types a DNSLookup can ask for
+var dnsRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT"}
+
+// DNSResult holds the records found for a hostname
+type DNSResult struct {
+	Hostname string `json:"hostname"`
+	// the DNS server that was queried, or "system"
+	Resolver string     `json:"resolver"`
+	A        []string   `json:"a,omitempty"`
+	AAAA     []string   `json:"aaaa,omitempty"`
+	CNAME    string     `json:"cname,omitempty"`
+	MX       []MXRecord `json:"mx,omitempty"`
+	TXT      []string   `json:"txt,omitempty"`
+	// lookup errors by record type
+	Errors map[string]string `json:"errors,omitempty"`
+	// time taken in milliseconds
+	Duration float64 `json:"duration_ms"`
+}
+
+// MXRecord is a mail exchanger of a domain
+type MXRecord struct {
+	Host string `json:"host"`
+	Pref uint16 `json:"pref"`
+}
+
+// PortCheck holds the parameters of a TCP port check
+type PortCheck struct {
+	Hostname string `json:"hostname" example:"localhost"`
+	Port     int    `json:"port" example:"8080"`
+	// seconds to wait for the connection (1-30, default 5)
+	Timeout int `json:"timeout,omitempty" example:"5"`
+}
+
+func (p *PortCheck) normalize() error {
+	if p.Hostname == "" {
+		return fmt.Errorf("hostname not provided")
+	}
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	return normalizeTimeout(&p.Timeout)
+}
+
+// PortResult reports whether a TCP port accepts connections
+type PortResult struct {
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+	Open     bool   `json:"open"`
+	// the address that accepted the connection
+	Address string `json:"address,omitempty"`
+	// time taken to connect in milliseconds
+	Latency float64 `json:"latency_ms,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// TLSCheck holds the parameters of a TLS certificate check
+type TLSCheck struct {
+	Hostname string `json:"hostname" example:"example.com"`
+	// default 443
+	Port int `json:"port,omitempty" example:"443"`
+	// the name sent in the TLS handshake and verified against the certificate (default the hostname)
+	ServerName string `json:"server_name,omitempty"`
+	// seconds to wait for the handshake (1-30, default 5)
+	Timeout int `json:"timeout,omitempty" example:"5"`
+}
+
+func (c *TLSCheck) normalize() error {
+	if c.Hostname == "" {
+		return fmt.Errorf("hostname not provided")
+	}
+	if c.Port == 0 {
+		c.Port = 443
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if c.ServerName == "" {
+		c.ServerName = c.Hostname
+	}
+	return normalizeTimeout(&c.Timeout)
+}
+
+// TLSResult describes the TLS connection and certificate chain presented by a server
+type TLSResult struct {
+	Hostname   string `json:"hostname"`
+	Port       int    `json:"port"`
+	ServerName string `json:"server_name"`
+	Address    string `json:"address,omitempty"`
+	// negotiated protocol version, e.g. "TLS 1.3"
+	Version     string `json:"version,omitempty"`
+	CipherSuite string `json:"cipher_suite,omitempty"`
+	// negotiated application protocol, e.g. "h2"
+	ALPN string `json:"alpn,omitempty"`
+	// whether the chain is trusted and valid for the server name
+	Verified    bool          `json:"verified"`
+	VerifyError string        `json:"verify_error,omitempty"`
+	Chain       []Certificate `json:"chain,omitempty"`
+	// time taken to connect and complete the handshake in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Certificate describes a certificate of a TLS chain, leaf first
+type Certificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	ExpiresInDays      int       `json:"expires_in_days"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+}
+
+// HTTPProbe holds the parameters of an HTTP request
+type HTTPProbe struct {
+	URL string `json:"url" example:"http://localhost:8080/swagger/index.html"`
+	// GET or HEAD (default GET)
+	Method string `json:"method,omitempty" example:"GET"`
+	// maximum number of redirects to follow (0-10, default 5)
+	MaxRedirects *int `json:"max_redirects,omitempty" example:"5"`
+	// seconds to wait for the whole probe (1-30, default 5)
+	Timeout int `json:"timeout,omitempty" example:"5"`
+}
+
+func (p *HTTPProbe) normalize() error {
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	p.Method = strings.ToUpper(p.Method)
+	if p.Method == "" {
+		p.Method = http.MethodGet
+	}
+	if p.Method != http.MethodGet && p.Method != http.MethodHead {
+		return fmt.Errorf("method must be GET or HEAD")
+	}
+	if p.MaxRedirects == nil {
+		n := defaultMaxRedirects
+		p.MaxRedirects = &n
+	}
+	if *p.MaxRedirects < 0 || *p.MaxRedirects > maxRedirects {
+		return fmt.Errorf("max_redirects must be between 0 and %d", maxRedirects)
+	}
+	return normalizeTimeout(&p.Timeout)
+}
+
+// HTTPResult describes the response to an HTTP probe
+type HTTPResult struct {
+	URL string `json:"url"`
+	// the URL of the last response, after following redirects
+	FinalURL   string      `json:"final_url"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Proto      string      `json:"proto,omitempty"`
+	Headers    http.Header `json:"headers,omitempty" swaggertype:"object"`
+	Redirects  []Redirect  `json:"redirects,omitempty"`
+	// number of body bytes read, up to 1 MiB
+	BodyBytes int64 `json:"body_bytes"`
+	// timing of the last request
+	Timing HTTPTiming `json:"timing"`
+	// time taken by all requests in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Redirect is a response that redirected the probe
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// HTTPTiming breaks down the time taken by a request in milliseconds. Phases that didn't
+// happen, such as the TLS handshake of a plain HTTP request, are zero.
+type HTTPTiming struct {
+	DNS       float64 `json:"dns_ms"`
+	Connect   float64 `json:"connect_ms"`
+	TLS       float64 `json:"tls_ms"`
+	FirstByte float64 `json:"first_byte_ms"`
+	Total     float64 `json:"total_ms"`
+}
+
+// Bounds and defaults of the diagnostics parameters
+const (
+	defaultProbeTimeout = 5 // seconds
+	maxProbeTimeout     = 30
+	defaultMaxRedirects = 5
+	maxRedirects        = 10
+)
+
+func normalizeTimeout(timeout *int) error {
+	if *timeout == 0 {
+		*timeout = defaultProbeTimeout
+	}
+	if *timeout < 1 || *timeout > maxProbeTimeout {
+		return fmt.Errorf("timeout must be between 1 and %d seconds", maxProbeTimeout)
+	}
+	return nil
+}
+
+// JobRequest submits a site diagnostic to run in the background
+type JobRequest struct {
+	// one of "ping", "dns", "port", "tls" or "http"
+	Type   string          `json:"type" example:"ping"`
+	Params json.RawMessage `json:"params" swaggertype:"object"`
+}
+
+// MonitorRequest registers a site diagnostic to run on an interval
+type MonitorRequest struct {
+	Name string `json:"name" example:"API"`
+	// one of "ping", "dns", "port", "tls" or "http"
+	Type   string          `json:"type" example:"http"`
+	Params json.RawMessage `json:"params" swaggertype:"object"`
+	// seconds between checks (5-86400, default 60)
+	Interval int `json:"interval,omitempty" example:"60"`
 }
@@ -0,0 +1,223 @@
+package site
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/internal/job"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
+)
+
+// defaultHistoryPeriod is the period summarized by the monitor history when no start is given
+const defaultHistoryPeriod = 24 * time.Hour
+
+// probe adapts the runner of a diagnostic to a monitor.Probe, deciding from its result whether the target is up
+type probe struct {
+	job.Runner
+	outcome func(result interface{}) monitor.Outcome
+}
+
+func (p probe) Check(ctx context.Context, params json.RawMessage) (monitor.Outcome, error) {
+	result, err := p.Run(ctx, params, func(int) {})
+	if err != nil {
+		return monitor.Outcome{}, err
+	}
+	outcome := p.outcome(result)
+	outcome.Result = result
+	return outcome, nil
+}
+
+// Probes returns the site diagnostics that can be used by monitors, by monitor type. Commands are run by commands.
+func Probes(commands *CommandRunner) map[string]monitor.Probe {
+	runners := Runners(commands)
+	return map[string]monitor.Probe{
+		"ping": probe{runners["ping"], func(result interface{}) monitor.Outcome {
+			r := result.(PingResult)
+			o := monitor.Outcome{Up: r.PacketsReceived > 0}
+			if r.RTT != nil {
+				o.Latency = r.RTT.Avg
+			}
+			if !o.Up {
+				o.Error = fmt.Sprintf("%g%% packet loss", r.PacketLoss)
+			}
+			return o
+		}},
+		"dns": probe{runners["dns"], func(result interface{}) monitor.Outcome {
+			r := result.(DNSResult)
+			o := monitor.Outcome{Up: len(r.Errors) == 0, Latency: r.Duration}
+			if !o.Up {
+				var errs []string
+				for t, err := range r.Errors {
+					errs = append(errs, t+": "+err)
+				}
+				sort.Strings(errs)
+				o.Error = strings.Join(errs, "; ")
+			}
+			return o
+		}},
+		"port": probe{runners["port"], func(result interface{}) monitor.Outcome {
+			r := result.(PortResult)
+			return monitor.Outcome{Up: r.Open, Latency: r.Latency, Error: r.Error}
+		}},
+		"tls": probe{runners["tls"], func(result interface{}) monitor.Outcome {
+			r := result.(TLSResult)
+			o := monitor.Outcome{Up: r.Error == "" && r.Verified, Latency: r.Duration, Error: r.Error}
+			if o.Error == "" {
+				o.Error = r.VerifyError
+			}
+			return o
+		}},
+		"http": probe{runners["http"], func(result interface{}) monitor.Outcome {
+			r := result.(HTTPResult)
+			o := monitor.Outcome{Up: r.Error == "" && r.StatusCode < 400, Latency: r.Duration, Error: r.Error}
+			if o.Error == "" && !o.Up {
+				o.Error = r.Status
+			}
+			return o
+		}},
+	}
+}
+
+// POST request registering a monitor
+//
+// @Summary      Create Monitor
+// @Description  Check a host or URL on an interval with one of the site diagnostics
+// @Description  Example: {"name": "API", "type": "http", "params": {"url": "http://localhost:8080/swagger/index.html"}, "interval": 60}
+// @Tags         site
+// @Accept       json
+// @Produce      json
+// @Param		 MonitorRequest	body		MonitorRequest		true	"MonitorRequest"
+// @Success      201  {object}  monitor.Monitor
+// @Header       201  {string}  Location  "URL of the monitor"
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /site/monitors [post]
+func (s *SiteHandler) CreateMonitor(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling POST at %s\n", r.URL.Path)
+	var req MonitorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mon, err := s.monitors.Create(r.Context(), monitor.Monitor{Name: req.Name, Type: req.Type, Params: req.Params, Interval: req.Interval})
+	if err != nil {
+		http.Error(w, err.Error(), monitorErrorStatus(err))
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/site/monitors/%s", mon.ID))
+	writeJSON(w, http.StatusCreated, mon)
+}
+
+// GET request listing the monitors
+//
+// @Summary      List Monitors
+// @Description  List the monitors with their current state
+// @Tags         site
+// @Produce      json
+// @Success      200  {array}   monitor.Monitor
+// @Failure      500  {object}  model.APIError
+// @Router       /site/monitors [get]
+func (s *SiteHandler) ListMonitors(w http.ResponseWriter, r *http.Request) {
+	monitors, err := s.monitors.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), monitorErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, monitors)
+}
+
+// GET request returning a monitor
+//
+// @Summary      Get Monitor
+// @Description  Get a monitor with its current state
+// @Tags         site
+// @Produce      json
+// @Param		 id	path		string				true	"id"
+// @Success      200  {object}  monitor.Monitor
+// @Failure      404  {object}  model.APIError
+// @Router       /site/monitors/{id} [get]
+func (s *SiteHandler) GetMonitor(w http.ResponseWriter, r *http.Request) {
+	mon, err := s.monitors.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), monitorErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, mon)
+}
+
+// DELETE request removing a monitor
+//
+// @Summary      Delete Monitor
+// @Description  Stop checking a monitor and delete its history
+// @Tags         site
+// @Param		 id	path		string				true	"id"
+// @Success      204
+// @Failure      404  {object}  model.APIError
+// @Router       /site/monitors/{id} [delete]
+func (s *SiteHandler) DeleteMonitor(w http.ResponseWriter, r *http.Request) {
+	s.logger.Infof("Handling DELETE at %s\n", r.URL.Path)
+	if err := s.monitors.Delete(r.Context(), r.PathValue("id")); err != nil {
+		http.Error(w, err.Error(), monitorErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET request returning the uptime history of a monitor
+//
+// @Summary      Get Monitor History
+// @Description  Summarize the uptime percentage, latency percentiles and incidents of a monitor over a period
+// @Tags         site
+// @Produce      json
+// @Param		 id			path		string		true	"id"
+// @Param		 from		query		string		false	"RFC 3339 start of the period (default 24 hours before the end)"
+// @Param		 to			query		string		false	"RFC 3339 end of the period (default now)"
+// @Param		 entries	query		bool		false	"include the checks made in the period"
+// @Success      200  {object}  monitor.History
+// @Failure      400  {object}  model.APIError
+// @Failure      404  {object}  model.APIError
+// @Router       /site/monitors/{id}/history [get]
+func (s *SiteHandler) GetMonitorHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	to := time.Now()
+	var err error
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.Add(-defaultHistoryPeriod)
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	history, err := s.monitors.History(r.Context(), r.PathValue("id"), from, to, query.Get("entries") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), monitorErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func monitorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, monitor.ErrUnknownType), errors.Is(err, monitor.ErrInvalidParams), errors.Is(err, monitor.ErrInvalidInterval):
+		return http.StatusBadRequest
+	case errors.Is(err, monitor.ErrMonitorNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
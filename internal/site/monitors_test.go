@@ -0,0 +1,111 @@
+package site
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/monitor"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestMonitors(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	store, err := monitor.NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	monitors := monitor.NewManager(logger, store, Probes(NewCommandRunner(CommandRunnerConfig{})))
+	defer monitors.Close()
+	handler := MakeHTTPHandler(logger, &config.Config{}, Services{Monitors: monitors})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return res
+	}
+
+	res := serve(http.MethodPost, "/api/v1/site/monitors", fmt.Sprintf(`{"name":"local","type":"port","params":{"hostname":"127.0.0.1","port":%d}}`, port))
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var mon monitor.Monitor
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &mon))
+	assert.Equal(t, "/api/v1/site/monitors/"+mon.ID, res.Header().Get("Location"))
+	assert.Equal(t, 60, mon.Interval)
+
+	require.Eventually(t, func() bool {
+		res := serve(http.MethodGet, "/api/v1/site/monitors/"+mon.ID, "")
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &mon))
+		return mon.State == monitor.StateUp
+	}, 5*time.Second, 10*time.Millisecond)
+
+	res = serve(http.MethodGet, "/api/v1/site/monitors/"+mon.ID+"/history?entries=true", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	var history monitor.History
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &history))
+	assert.Equal(t, 1, history.Checks)
+	assert.Equal(t, 100.0, history.Uptime)
+	require.NotNil(t, history.Latency)
+	require.Len(t, history.Entries, 1)
+	var result PortResult
+	require.NoError(t, json.Unmarshal(history.Entries[0].Result, &result))
+	assert.True(t, result.Open)
+
+	res = serve(http.MethodGet, "/api/v1/site/monitors", "")
+	assert.Contains(t, res.Body.String(), mon.ID)
+
+	for _, body := range []string{
+		`{"type":"traceroute","params":{}}`,
+		`{"type":"port","params":{"hostname":"127.0.0.1"}}`,
+		`{"type":"http","params":{"url":"http://localhost"},"interval":1}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/site/monitors", body).Code, body)
+	}
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/site/monitors/"+mon.ID+"/history?from=2030-01-01T00:00:00Z&to=2020-01-01T00:00:00Z", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/v1/site/monitors/"+mon.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/site/monitors/"+mon.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/site/monitors/"+mon.ID+"/history", "").Code)
+}
+
+func TestProbes(t *testing.T) {
+	probes := Probes(nil)
+	for _, tc := range []struct {
+		probe  string
+		result interface{}
+		up     bool
+		err    string
+	}{
+		{"ping", PingResult{PacketsSent: 4, PacketsReceived: 1, RTT: &RTT{Avg: 3}}, true, ""},
+		{"ping", PingResult{PacketsSent: 4, PacketLoss: 100}, false, "100% packet loss"},
+		{"dns", DNSResult{A: []string{"192.0.2.1"}}, true, ""},
+		{"dns", DNSResult{Errors: map[string]string{"MX": "no such host", "A": "timeout"}}, false, "A: timeout; MX: no such host"},
+		{"tls", TLSResult{Verified: true}, true, ""},
+		{"tls", TLSResult{VerifyError: "certificate has expired"}, false, "certificate has expired"},
+		{"http", HTTPResult{StatusCode: 204}, true, ""},
+		{"http", HTTPResult{StatusCode: 503, Status: "503 Service Unavailable"}, false, "503 Service Unavailable"},
+	} {
+		o := probes[tc.probe].(probe).outcome(tc.result)
+		assert.Equal(t, tc.up, o.Up, "%s %+v", tc.probe, tc.result)
+		assert.Equal(t, tc.err, o.Error, "%s %+v", tc.probe, tc.result)
+	}
+}
@@ -0,0 +1,84 @@
+package site
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	pingAddressRe = regexp.MustCompile(`(?m)^PING \S+ \(([^)]+)\)`)
+	pingPacketsRe = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	pingLossRe    = regexp.MustCompile(`([\d.]+)% packet loss`)
+	// iputils prints min/avg/max/mdev, BSD min/avg/max/stddev and BusyBox only min/avg/max
+	pingRTTRe = regexp.MustCompile(`= ([\d.]+)/([\d.]+)/([\d.]+)(?:/([\d.]+))? ms`)
+	// a line reporting an echo reply
+	pingReplyRe = regexp.MustCompile(`bytes from .*seq=\d+`)
+)
+
+// errNoStatistics is returned when the ping output doesn't contain a statistics summary
+var errNoStatistics = errors.New("no statistics in ping output")
+
+// ping sends site.Count echo requests to site.Hostname and parses the statistics from the output.
+// The site must have been normalized. onLine, if not nil, is called with each line of output as it is printed.
+// The execution of ping is returned for the command history.
+func ping(ctx context.Context, commands *CommandRunner, site Site, onLine func(line string)) (PingResult, Execution, error) {
+	// allow for every interval and the wait for the last reply, plus some slack for name resolution
+	timeout := time.Duration(float64(site.Count-1)*site.Interval*float64(time.Second)) +
+		time.Duration(site.Timeout)*time.Second + 5*time.Second
+
+	args := []string{
+		"-c", strconv.Itoa(site.Count),
+		"-i", strconv.FormatFloat(site.Interval, 'f', -1, 64),
+		"-W", strconv.Itoa(site.Timeout),
+		site.Hostname,
+	}
+	exe, err := commands.Run(ctx, timeout, "ping", args, onLine)
+	if exe.ExitCode < 0 {
+		// not started, or killed before printing its statistics
+		return PingResult{Hostname: site.Hostname, Output: exe.Output}, exe, err
+	}
+	// ping exits with status 1 when no replies were received, which still yields statistics
+	result, parseErr := parsePingOutput(site.Hostname, exe.Output)
+	if parseErr != nil {
+		if err != nil {
+			return result, exe, fmt.Errorf("%w: %s", err, strings.TrimSpace(exe.Output))
+		}
+		return result, exe, parseErr
+	}
+	return result, exe, nil
+}
+
+// parsePingOutput extracts the packet and round-trip time statistics from the output of ping.
+func parsePingOutput(hostname, output string) (PingResult, error) {
+	result := PingResult{Hostname: hostname, Output: output}
+	if m := pingAddressRe.FindStringSubmatch(output); m != nil {
+		result.Address = m[1]
+	}
+	m := pingPacketsRe.FindStringSubmatch(output)
+	if m == nil {
+		return result, errNoStatistics
+	}
+	result.PacketsSent, _ = strconv.Atoi(m[1])
+	result.PacketsReceived, _ = strconv.Atoi(m[2])
+	if m := pingLossRe.FindStringSubmatch(output); m != nil {
+		result.PacketLoss, _ = strconv.ParseFloat(m[1], 64)
+	} else if result.PacketsSent > 0 {
+		result.PacketLoss = 100 * float64(result.PacketsSent-result.PacketsReceived) / float64(result.PacketsSent)
+	}
+	if m := pingRTTRe.FindStringSubmatch(output); m != nil {
+		rtt := &RTT{}
+		rtt.Min, _ = strconv.ParseFloat(m[1], 64)
+		rtt.Avg, _ = strconv.ParseFloat(m[2], 64)
+		rtt.Max, _ = strconv.ParseFloat(m[3], 64)
+		if m[4] != "" {
+			rtt.Mdev, _ = strconv.ParseFloat(m[4], 64)
+		}
+		result.RTT = rtt
+	}
+	return result, nil
+}
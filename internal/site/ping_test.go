@@ -0,0 +1,91 @@
+package site
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+func TestParsePingOutput(t *testing.T) {
+	iputils := `PING localhost (127.0.0.1) 56(84) bytes of data.
+64 bytes from localhost (127.0.0.1): icmp_seq=1 ttl=64 time=0.031 ms
+64 bytes from localhost (127.0.0.1): icmp_seq=2 ttl=64 time=0.045 ms
+
+--- localhost ping statistics ---
+2 packets transmitted, 2 received, 0% packet loss, time 1001ms
+rtt min/avg/max/mdev = 0.031/0.038/0.045/0.007 ms
+`
+	result, err := parsePingOutput("localhost", iputils)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", result.Address)
+	assert.Equal(t, 2, result.PacketsSent)
+	assert.Equal(t, 2, result.PacketsReceived)
+	assert.Equal(t, 0.0, result.PacketLoss)
+	assert.Equal(t, &RTT{Min: 0.031, Avg: 0.038, Max: 0.045, Mdev: 0.007}, result.RTT)
+	assert.Equal(t, iputils, result.Output)
+
+	busybox := `PING example.com (93.184.216.34): 56 data bytes
+
+--- example.com ping statistics ---
+4 packets transmitted, 3 packets received, 25% packet loss
+round-trip min/avg/max = 10.1/12.5/15.0 ms
+`
+	result, err = parsePingOutput("example.com", busybox)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.PacketsSent)
+	assert.Equal(t, 3, result.PacketsReceived)
+	assert.Equal(t, 25.0, result.PacketLoss)
+	assert.Equal(t, &RTT{Min: 10.1, Avg: 12.5, Max: 15.0}, result.RTT)
+
+	unreachable := `PING 10.255.255.1 (10.255.255.1) 56(84) bytes of data.
+
+--- 10.255.255.1 ping statistics ---
+3 packets transmitted, 0 received, 100% packet loss, time 2030ms
+`
+	result, err = parsePingOutput("10.255.255.1", unreachable)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, result.PacketLoss)
+	assert.Nil(t, result.RTT)
+
+	_, err = parsePingOutput("nohost", "ping: nohost: Name or service not known\n")
+	assert.ErrorIs(t, err, errNoStatistics)
+}
+
+func TestSite_normalize(t *testing.T) {
+	site := Site{Hostname: "localhost"}
+	require.NoError(t, site.normalize())
+	assert.Equal(t, Site{Hostname: "localhost", Count: 4, Timeout: 5, Interval: 1}, site)
+
+	for _, invalid := range []Site{
+		{},
+		{Hostname: "localhost", Count: 21},
+		{Hostname: "localhost", Count: -1},
+		{Hostname: "localhost", Timeout: 31},
+		{Hostname: "localhost", Interval: 0.1},
+	} {
+		assert.Error(t, invalid.normalize(), "%+v", invalid)
+	}
+}
+
+func TestPingSite_InvalidParameters(t *testing.T) {
+	logger, _ := log.NewForTest()
+	handler := MakeHTTPHandler(logger, &config.Config{}, Services{})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/site/ping", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/site/ping?hostname=localhost&count=abc", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/site/ping?hostname=localhost&count=100", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/site/ping", strings.NewReader(`{"hostname":"localhost","interval":0.01}`)),
+	} {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusBadRequest, res.Code, req.URL.String())
+	}
+}
@@ -0,0 +1,81 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// streamEvent is a line of a newline-delimited JSON ping stream
+type streamEvent struct {
+	Line    string      `json:"line,omitempty"`
+	Summary *PingResult `json:"summary,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// streamMode returns the format in which the client asked to receive ping output as it is printed:
+// "sse" for Server-Sent Events, "ndjson" for a chunked newline-delimited JSON response, or "" when
+// the client wants the result once ping has finished.
+func streamMode(r *http.Request) string {
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return "sse"
+	}
+	if r.URL.Query().Get("stream") == "true" {
+		return "ndjson"
+	}
+	return ""
+}
+
+// streamPing writes each line of ping output as soon as it is printed, followed by the parsed
+// statistics. ping is killed when the client disconnects. The site must have been normalized.
+func (s *SiteHandler) streamPing(w http.ResponseWriter, r *http.Request, site Site, mode string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var send func(event string, v streamEvent)
+	lines := 0
+	if mode == "sse" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Connection", "keep-alive")
+		send = func(event string, v streamEvent) {
+			if event == "line" {
+				lines++
+				fmt.Fprintf(w, "id: %d\nevent: line\ndata: %s\n\n", lines, v.Line)
+				return
+			}
+			data, _ := json.Marshal(v)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		send = func(_ string, v streamEvent) {
+			encoder.Encode(v)
+		}
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	// stop reverse proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	result, exe, err := ping(r.Context(), s.commands, site, func(line string) {
+		send("line", streamEvent{Line: line})
+		flusher.Flush()
+	})
+	s.recordCommand(r, exe)
+	if r.Context().Err() != nil {
+		s.logger.With(r.Context()).Infof("Client disconnected, stopped pinging %s", site.Hostname)
+		return
+	}
+	if err != nil {
+		send("error", streamEvent{Error: err.Error()})
+	} else {
+		send("summary", streamEvent{Summary: &result})
+	}
+	flusher.Flush()
+}
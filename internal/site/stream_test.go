@@ -0,0 +1,112 @@
+package site
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// fakePing replies once to any host except "hang", which never answers
+const fakePing = `#!/bin/sh
+for host; do :; done
+echo "PING $host (127.0.0.1) 56(84) bytes of data."
+echo "64 bytes from $host (127.0.0.1): icmp_seq=1 ttl=64 time=0.031 ms"
+if [ "$host" = "hang" ]; then exec sleep 30; fi
+echo ""
+echo "--- $host ping statistics ---"
+echo "1 packets transmitted, 1 received, 0% packet loss, time 0ms"
+echo "rtt min/avg/max/mdev = 0.031/0.031/0.031/0.000 ms"
+`
+
+// installFakePing puts a fake ping first on the PATH.
+func installFakePing(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ping"), []byte(fakePing), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newTestHistory(t *testing.T) *History {
+	history, err := NewHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { history.Close() })
+	return history
+}
+
+func TestPingSite_Stream(t *testing.T) {
+	installFakePing(t)
+	logger, _ := log.NewForTest()
+	srv := httptest.NewServer(MakeHTTPHandler(logger, &config.Config{}, Services{}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/site/ping?hostname=localhost&count=1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	body := new(strings.Builder)
+	_, err = bufio.NewReader(res.Body).WriteTo(body)
+	require.NoError(t, err)
+	assert.Contains(t, body.String(), "id: 2\nevent: line\ndata: 64 bytes from localhost (127.0.0.1): icmp_seq=1 ttl=64 time=0.031 ms\n\n")
+	assert.Contains(t, body.String(), `event: summary`+"\n"+`data: {"summary":{"hostname":"localhost","address":"127.0.0.1","packets_sent":1,"packets_received":1,`)
+
+	res, err = http.Post(srv.URL+"/api/v1/site/ping?stream=true", "application/json", strings.NewReader(`{"hostname":"localhost","count":1}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+	var events []streamEvent
+	decoder := json.NewDecoder(res.Body)
+	for decoder.More() {
+		var event streamEvent
+		require.NoError(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 7)
+	assert.Equal(t, "PING localhost (127.0.0.1) 56(84) bytes of data.", events[0].Line)
+	require.NotNil(t, events[6].Summary)
+	assert.Equal(t, 1, events[6].Summary.PacketsReceived)
+}
+
+func TestPingSite_StreamDisconnect(t *testing.T) {
+	installFakePing(t)
+	logger, _ := log.NewForTest()
+	history := newTestHistory(t)
+	srv := httptest.NewServer(MakeHTTPHandler(logger, &config.Config{}, Services{History: history}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/site/ping?hostname=hang&stream=true", nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	reader := bufio.NewReader(res.Body)
+	for i := 0; i < 2; i++ {
+		_, err := reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+
+	// the fake ping sleeps for 30 seconds unless it is killed when the client goes away
+	start := time.Now()
+	cancel()
+	res.Body.Close()
+	srv.Close()
+	assert.Less(t, time.Since(start), 10*time.Second)
+	page, err := history.Query(HistoryFilter{Arg: "hang"}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, -1, page.Entries[0].ExitCode)
+}
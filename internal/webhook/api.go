@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// WebhookHandler organizes HTTP handler functions for webhooks and their deliveries
+type WebhookHandler struct {
+	logger  log.Logger
+	manager *Manager
+}
+
+func MakeHTTPHandler(logger log.Logger, manager *Manager) http.Handler {
+	webhookHandler := &WebhookHandler{logger: logger, manager: manager}
+
+	router := http.NewServeMux()
+	router.HandleFunc("POST /api/v1/webhooks", webhookHandler.Create)
+	router.HandleFunc("GET /api/v1/webhooks", webhookHandler.List)
+	router.HandleFunc("GET /api/v1/webhooks/{id}", webhookHandler.Get)
+	router.HandleFunc("PUT /api/v1/webhooks/{id}", webhookHandler.Update)
+	router.HandleFunc("DELETE /api/v1/webhooks/{id}", webhookHandler.Delete)
+	router.HandleFunc("GET /api/v1/webhooks/{id}/deliveries", webhookHandler.Deliveries)
+	router.HandleFunc("POST /api/v1/webhooks/{id}/deliveries/{delivery}/redeliver", webhookHandler.Redeliver)
+
+	return router
+}
+
+// Create handles HTTP Post
+//
+// @Summary      Create Webhook
+// @Description  Subscribe a URL to note.created, note.updated and note.deleted events
+// @Description  Each delivery is signed with the webhook secret in the X-Webhook-Signature header
+// @Description  Example: {"url": "https://example.com/hooks/notes", "events": ["note.created"], "active": true}
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param		 Subscription	body		Subscription		true	"Subscription"
+// @Success      201  {object}  Subscription
+// @Failure      400  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks [post]
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var sub Subscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, "Invalid webhook", http.StatusBadRequest)
+		return
+	}
+	sub, err := h.manager.Create(r.Context(), sub)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	w.Header().Set("Location", "/api/v1/webhooks/"+sub.ID)
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// List handles HTTP Get with no Id
+//
+// @Summary      List Webhooks
+// @Description  List the webhooks, oldest first
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   Subscription
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks [get]
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.manager.List(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, subscriptions)
+}
+
+// Get handles HTTP Get with Id
+//
+// @Summary      Get Webhook
+// @Description  Get a webhook
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param		 id	path		string				true	"webhook id"
+// @Success      200  {object}  Subscription
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks/{id} [get]
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	sub, err := h.manager.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// Update handles HTTP Put with Id
+//
+// @Summary      Update Webhook
+// @Description  Replace the URL, events and active flag of a webhook, and its secret if one is given
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param		 id				path		string				true	"webhook id"
+// @Param		 Subscription	body		Subscription		true	"Subscription"
+// @Success      200  {object}  Subscription
+// @Failure      400  {object}  model.APIError
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks/{id} [put]
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var sub Subscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, "Invalid webhook", http.StatusBadRequest)
+		return
+	}
+	sub, err := h.manager.Update(r.Context(), r.PathValue("id"), sub)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// Delete handles HTTP Delete with Id
+//
+// @Summary      Delete Webhook
+// @Description  Delete a webhook and its delivery log
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param		 id	path		string				true	"webhook id"
+// @Success      204
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.Delete(r.Context(), r.PathValue("id")); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries handles HTTP Get of the delivery log
+//
+// @Summary      List Webhook Deliveries
+// @Description  List the latest deliveries to a webhook with the outcome of their attempts, newest first
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param		 id	path		string				true	"webhook id"
+// @Success      200  {array}   Delivery
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.manager.Deliveries(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver handles HTTP Post of a redelivery
+//
+// @Summary      Redeliver Webhook Event
+// @Description  Queue a new delivery of the event of an earlier delivery
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param		 id			path		string				true	"webhook id"
+// @Param		 delivery	path		string				true	"delivery id"
+// @Success      202  {object}  Delivery
+// @Failure      404  {object}  model.APIError
+// @Failure      500  {object}  model.APIError
+// @Router       /webhooks/{id}/deliveries/{delivery}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	d, err := h.manager.Redeliver(r.Context(), r.PathValue("id"), r.PathValue("delivery"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
+
+// errorStatus maps a webhook error to a status code.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrUnknownEvent):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// Delivery schedule
+const (
+	// how often the outbox is checked for due deliveries
+	defaultPollInterval = time.Second
+	// the delay before the first retry, doubled for every further retry
+	defaultRetryDelay = 10 * time.Second
+	maxRetryDelay     = time.Hour
+	// attempts before a delivery is marked as failed
+	maxAttempts = 8
+	// deliveries attempted per poll
+	batchSize       = 20
+	deliveryTimeout = 10 * time.Second
+	// deliveries returned by the delivery log
+	deliveryLogLimit = 100
+)
+
+// Headers sent with each delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// "sha256=" followed by the hex encoded HMAC-SHA256 of the timestamp, a dot and the body, keyed with the webhook secret
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Manager manages webhooks and delivers the events recorded in the outbox, retrying failed
+// deliveries with exponential backoff.
+type Manager struct {
+	logger log.Logger
+	store  Store
+	client *http.Client
+
+	pollInterval time.Duration
+	retryDelay   time.Duration
+	now          func() time.Time
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a manager delivering with client, or a client with a 10 second timeout when nil.
+// Call Start to begin delivering.
+func NewManager(logger log.Logger, store Store, client *http.Client) *Manager {
+	if client == nil {
+		client = &http.Client{Timeout: deliveryTimeout}
+	}
+	return &Manager{
+		logger:       logger,
+		store:        store,
+		client:       client,
+		pollInterval: defaultPollInterval,
+		retryDelay:   defaultRetryDelay,
+		now:          time.Now,
+	}
+}
+
+// Start delivers due events in the background until Close is called.
+func (m *Manager) Start() {
+	ctx, stop := context.WithCancel(context.Background())
+	m.stop = stop
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.deliverDue(ctx); err != nil && ctx.Err() == nil {
+					m.logger.Errorf("Unable to deliver webhooks: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops delivering and waits for deliveries in progress to end.
+func (m *Manager) Close() {
+	if m.stop != nil {
+		m.stop()
+	}
+	m.wg.Wait()
+}
+
+// Create validates and stores a new webhook, generating its secret if none is given.
+func (m *Manager) Create(ctx context.Context, sub Subscription) (Subscription, error) {
+	if err := validate(sub); err != nil {
+		return Subscription{}, err
+	}
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return Subscription{}, err
+	}
+	if sub.Secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return Subscription{}, err
+		}
+		sub.Secret = hex.EncodeToString(key)
+	}
+	sub.ID = uid.String()
+	sub.CreatedOn = m.now().UTC()
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+	if err := m.store.SaveSubscription(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	m.logger.With(ctx, "webhook_id", sub.ID).Infof("Created webhook to %s", sub.URL)
+	return sub, nil
+}
+
+// Get returns a webhook without its secret.
+func (m *Manager) Get(ctx context.Context, id string) (Subscription, error) {
+	sub, err := m.store.GetSubscription(ctx, id)
+	sub.Secret = ""
+	return sub, err
+}
+
+// List returns the webhooks without their secrets.
+func (m *Manager) List(ctx context.Context) ([]Subscription, error) {
+	subscriptions, err := m.store.ListSubscriptions(ctx)
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, err
+}
+
+// Update replaces the URL, events and active flag of a webhook, and its secret if one is given.
+func (m *Manager) Update(ctx context.Context, id string, sub Subscription) (Subscription, error) {
+	if err := validate(sub); err != nil {
+		return Subscription{}, err
+	}
+	current, err := m.store.GetSubscription(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	current.URL = sub.URL
+	current.Events = sub.Events
+	if current.Events == nil {
+		current.Events = []string{}
+	}
+	current.Active = sub.Active
+	if sub.Secret != "" {
+		current.Secret = sub.Secret
+	}
+	if err := m.store.SaveSubscription(ctx, current); err != nil {
+		return Subscription{}, err
+	}
+	current.Secret = ""
+	return current, nil
+}
+
+// Delete removes a webhook and its deliveries.
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	return m.store.DeleteSubscription(ctx, id)
+}
+
+// Deliveries returns the latest deliveries to a webhook, newest first.
+func (m *Manager) Deliveries(ctx context.Context, id string) ([]Delivery, error) {
+	if _, err := m.store.GetSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return m.store.Deliveries(ctx, id, deliveryLogLimit)
+}
+
+// Redeliver queues a new delivery of the event of an earlier delivery to the webhook.
+func (m *Manager) Redeliver(ctx context.Context, id, deliveryID string) (Delivery, error) {
+	d, err := m.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if d.SubscriptionID != id {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return Delivery{}, err
+	}
+	now := m.now().UTC()
+	redelivery := Delivery{
+		ID:             uid.String(),
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         StatusPending,
+		NextAttempt:    &now,
+		CreatedOn:      now,
+		Payload:        d.Payload,
+	}
+	if err := m.store.SaveDelivery(ctx, redelivery); err != nil {
+		return Delivery{}, err
+	}
+	m.logger.With(ctx, "webhook_id", id).Infof("Queued redelivery of event %s", d.EventID)
+	return redelivery, nil
+}
+
+func validate(sub Subscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidURL, sub.URL)
+	}
+	for _, e := range sub.Events {
+		if !slices.Contains(Events, e) {
+			return fmt.Errorf("%w: %q", ErrUnknownEvent, e)
+		}
+	}
+	return nil
+}
+
+// deliverDue attempts the deliveries that are due, one batch at a time.
+func (m *Manager) deliverDue(ctx context.Context) error {
+	for {
+		due, err := m.store.Due(ctx, m.now(), batchSize)
+		if err != nil {
+			return err
+		}
+		subscriptions := make(map[string]Subscription)
+		for _, d := range due {
+			sub, ok := subscriptions[d.SubscriptionID]
+			if !ok {
+				if sub, err = m.store.GetSubscription(ctx, d.SubscriptionID); err != nil {
+					return err
+				}
+				subscriptions[d.SubscriptionID] = sub
+			}
+			if err := m.store.SaveDelivery(ctx, m.attempt(ctx, sub, d)); err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt posts a delivery once and returns it updated with the outcome.
+func (m *Manager) attempt(ctx context.Context, sub Subscription, d Delivery) Delivery {
+	now := m.now()
+	d.Attempts++
+	d.LastAttempt = &now
+	d.ResponseStatus = 0
+	d.Error = ""
+	if sub.Active {
+		d.ResponseStatus, d.Error = m.post(ctx, sub, d)
+	} else {
+		d.Error = "webhook is not active"
+	}
+	logger := m.logger.With(ctx, "webhook_id", sub.ID, "delivery_id", d.ID)
+	switch {
+	case d.Error == "":
+		d.Status = StatusDelivered
+		d.NextAttempt = nil
+		d.DeliveredOn = &now
+		logger.Debugf("Delivered %s event %s", d.EventType, d.EventID)
+	case d.Attempts >= maxAttempts:
+		d.Status = StatusFailed
+		d.NextAttempt = nil
+		logger.Warnf("Gave up delivering %s event %s after %d attempts: %s", d.EventType, d.EventID, d.Attempts, d.Error)
+	default:
+		next := now.Add(m.backoff(d.Attempts))
+		d.NextAttempt = &next
+		logger.Infof("Delivery of %s event %s failed, retrying at %s: %s", d.EventType, d.EventID, next.Format(time.RFC3339), d.Error)
+	}
+	return d
+}
+
+// backoff returns the delay before the next attempt after the given number of attempts.
+func (m *Manager) backoff(attempts int) time.Duration {
+	delay := m.retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// post sends a delivery, returning the response status code and why it failed, if it did.
+func (m *Manager) post(ctx context.Context, sub Subscription, d Delivery) (int, string) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	//
+	// Server-Side Request Forgery : dataflow
+	//
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	timestamp := strconv.FormatInt(m.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "insecure-go-api-webhooks")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, d.Payload))
+	res, err := m.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Sprintf("unexpected status %s", res.Status)
+	}
+	return res.StatusCode, ""
+}
+
+// Sign returns the signature header value of a delivery body sent at the given Unix timestamp.
+// Receivers compute it the same way to check that a delivery came from this service.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// receiver records the deliveries it is sent, failing the first failures of them
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func newTestManager(t *testing.T) (*Manager, note.Repository) {
+	logger, _ := log.NewForTest()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSQLiteStore(context.Background(), db)
+	require.NoError(t, err)
+	repo, err := note.NewSQLiteRepository(db, time.Second, logger, note.WithOutbox(store))
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	m := NewManager(logger, store, nil)
+	m.pollInterval = 10 * time.Millisecond
+	m.retryDelay = 20 * time.Millisecond
+	return m, repo
+}
+
+func TestManager_Deliver(t *testing.T) {
+	m, repo := newTestManager(t)
+	ctx := context.Background()
+	rc := &receiver{failures: 2}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	sub, err := m.Create(ctx, Subscription{URL: srv.URL, Events: []string{note.EventCreated, note.EventDeleted}, Active: true})
+	require.NoError(t, err)
+	assert.Len(t, sub.Secret, 64)
+
+	id, err := repo.Create(ctx, newNote("zap"))
+	require.NoError(t, err)
+	require.NoError(t, repo.Update(ctx, id, newNote("zap")), "updates are not subscribed to")
+	_, err = repo.Create(ctx, newNote("zap"))
+	assert.ErrorIs(t, err, note.ErrNoteExists, "nothing is delivered for a change that is rolled back")
+
+	m.Start()
+	defer m.Close()
+	require.Eventually(t, func() bool { return rc.count() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	rc.mu.Lock()
+	req, body := rc.requests[2], rc.bodies[2]
+	rc.mu.Unlock()
+	assert.Equal(t, note.EventCreated, req.Header.Get(HeaderEvent))
+	assert.Equal(t, Sign(sub.Secret, req.Header.Get(HeaderTimestamp), body), req.Header.Get(HeaderSignature))
+	var payload Payload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, id, payload.Data.NoteID)
+	assert.Equal(t, "zap", payload.Data.Title)
+
+	require.Eventually(t, func() bool {
+		deliveries, err := m.Deliveries(ctx, sub.ID)
+		require.NoError(t, err)
+		return len(deliveries) == 1 && deliveries[0].Status == StatusDelivered
+	}, 5*time.Second, 10*time.Millisecond)
+	deliveries, _ := m.Deliveries(ctx, sub.ID)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus)
+
+	redelivery, err := m.Redeliver(ctx, sub.ID, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, deliveries[0].EventID, redelivery.EventID)
+	require.Eventually(t, func() bool { return rc.count() == 4 }, 5*time.Second, 10*time.Millisecond)
+	rc.mu.Lock()
+	assert.Equal(t, body, rc.bodies[3], "a redelivery carries the same event")
+	rc.mu.Unlock()
+
+	require.NoError(t, repo.Delete(ctx, id))
+	require.Eventually(t, func() bool { return rc.count() == 5 }, 5*time.Second, 10*time.Millisecond)
+	rc.mu.Lock()
+	assert.Equal(t, note.EventDeleted, rc.requests[4].Header.Get(HeaderEvent))
+	rc.mu.Unlock()
+}
+
+func TestManager_GiveUp(t *testing.T) {
+	m, repo := newTestManager(t)
+	ctx := context.Background()
+	rc := &receiver{failures: maxAttempts}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	sub, err := m.Create(ctx, Subscription{URL: srv.URL, Active: true})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newNote("zap"))
+	require.NoError(t, err)
+
+	// step through the attempts rather than waiting for the backoff
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	for i := 0; i < maxAttempts; i++ {
+		require.NoError(t, m.deliverDue(ctx))
+		now = now.Add(maxRetryDelay)
+	}
+	assert.Equal(t, maxAttempts, rc.count())
+	deliveries, err := m.Deliveries(ctx, sub.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, StatusFailed, deliveries[0].Status)
+	assert.Equal(t, http.StatusBadGateway, deliveries[0].ResponseStatus)
+	assert.Nil(t, deliveries[0].NextAttempt)
+
+	assert.Equal(t, 20*time.Millisecond, m.backoff(1))
+	assert.Equal(t, 80*time.Millisecond, m.backoff(3))
+	m.retryDelay = defaultRetryDelay
+	assert.Equal(t, maxRetryDelay, m.backoff(maxAttempts+10))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	m, _ := newTestManager(t)
+	logger, _ := log.NewForTest()
+	handler := MakeHTTPHandler(logger, m)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return res
+	}
+
+	res := serve(http.MethodPost, "/api/v1/webhooks", `{"url":"https://example.com/hook","events":["note.created"],"secret":"s3cret","active":true}`)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var sub Subscription
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &sub))
+	assert.Equal(t, "s3cret", sub.Secret)
+	assert.Equal(t, "/api/v1/webhooks/"+sub.ID, res.Header().Get("Location"))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/webhooks", `{"url":"file:///etc/passwd"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/webhooks", `{"url":"https://example.com","events":["note.read"]}`).Code)
+
+	res = serve(http.MethodGet, "/api/v1/webhooks/"+sub.ID, "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.NotContains(t, res.Body.String(), "s3cret")
+
+	res = serve(http.MethodPut, "/api/v1/webhooks/"+sub.ID, `{"url":"https://example.com/other","active":false}`)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &sub))
+	assert.Equal(t, "https://example.com/other", sub.URL)
+	assert.Empty(t, sub.Events)
+	assert.False(t, sub.Active)
+
+	res = serve(http.MethodGet, "/api/v1/webhooks", "")
+	var subscriptions []Subscription
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &subscriptions))
+	assert.Len(t, subscriptions, 1)
+
+	res = serve(http.MethodGet, "/api/v1/webhooks/"+sub.ID+"/deliveries", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[]`, res.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/v1/webhooks/"+sub.ID+"/deliveries/missing/redeliver", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/v1/webhooks/"+sub.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/webhooks/"+sub.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/webhooks/"+sub.ID+"/deliveries", "").Code)
+}
+
+// newNote returns a note with the given title.
+func newNote(title string) note.Note {
+	return note.Note{Title: title, Description: title + " is a package"}
+}
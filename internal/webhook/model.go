@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("delivery not found")
+	ErrInvalidURL           = errors.New("invalid webhook URL")
+	ErrUnknownEvent         = errors.New("unknown event type")
+)
+
+// Events are the types of event a webhook can subscribe to
+var Events = []string{note.EventCreated, note.EventUpdated, note.EventDeleted}
+
+// Status of a delivery
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	// the delivery was attempted the maximum number of times without success
+	StatusFailed Status = "failed"
+)
+
+// Subscription posts the events of the subscribed types to a URL
+type Subscription struct {
+	ID  string `json:"id" example:"8c4b0f2e-1d7a-4a55-b7f3-3e9d2c6a1b04"`
+	URL string `json:"url" example:"https://example.com/hooks/notes"`
+	// the event types delivered, all of them when empty
+	Events []string `json:"events" example:"note.created,note.deleted"`
+	// the key of the HMAC-SHA256 signature of each delivery. Generated when not given,
+	// and only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+	// deliveries are only made to active webhooks
+	Active    bool      `json:"active"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+// Subscribes reports whether the subscription delivers events of the given type.
+func (s Subscription) Subscribes(eventType string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, eventType)
+}
+
+// Payload is the body of a delivery
+type Payload struct {
+	// the event ID, the same for every delivery of the event
+	ID        string    `json:"id"`
+	Type      string    `json:"type" example:"note.created"`
+	CreatedOn time.Time `json:"created_on"`
+	Data      note.Note `json:"data"`
+}
+
+// Delivery is an event to post to a webhook, and the outcome of the attempts so far
+type Delivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"webhook_id"`
+	EventID        string `json:"event_id"`
+	EventType      string `json:"event_type" example:"note.created"`
+	Status         Status `json:"status" example:"delivered"`
+	Attempts       int    `json:"attempts"`
+	// when the delivery is next attempted, while pending
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	// the status code returned by the last attempt, if it got a response
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedOn      time.Time       `json:"created_on"`
+	DeliveredOn    *time.Time      `json:"delivered_on,omitempty"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// Store persists webhooks and their deliveries. It records note changes as the outbox of the note repository.
+type Store interface {
+	note.Outbox
+	SaveSubscription(ctx context.Context, s Subscription) error
+	GetSubscription(ctx context.Context, id string) (Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	// DeleteSubscription removes a webhook and its deliveries
+	DeleteSubscription(ctx context.Context, id string) error
+	SaveDelivery(ctx context.Context, d Delivery) error
+	GetDelivery(ctx context.Context, id string) (Delivery, error)
+	// Deliveries returns the latest deliveries to a webhook, newest first
+	Deliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error)
+	// Due returns the pending deliveries to attempt at t, oldest first
+	Due(ctx context.Context, t time.Time, limit int) ([]Delivery, error)
+}
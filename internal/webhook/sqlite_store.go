@@ -0,0 +1,286 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+)
+
+// SQLiteStore stores webhooks, the events to deliver and their deliveries in a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates the webhooks, webhook_events and webhook_deliveries tables if needed.
+// Times are stored in UTC so that they can be compared as text.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (Store, error) {
+	query := `
+    CREATE TABLE IF NOT EXISTS webhooks (
+        id TEXT PRIMARY KEY,
+        url TEXT NOT NULL,
+        events TEXT NOT NULL,
+        secret TEXT NOT NULL,
+        active BOOLEAN NOT NULL,
+        created_on DATETIME NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS webhook_events (
+        id TEXT PRIMARY KEY,
+        type TEXT NOT NULL,
+        payload TEXT NOT NULL,
+        created_on DATETIME NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS webhook_deliveries (
+        id TEXT PRIMARY KEY,
+        webhook_id TEXT NOT NULL,
+        event_id TEXT NOT NULL,
+        status TEXT NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt DATETIME,
+        last_attempt DATETIME,
+        response_status INTEGER NOT NULL DEFAULT 0,
+        error TEXT NOT NULL DEFAULT '',
+        created_on DATETIME NOT NULL,
+        delivered_on DATETIME
+    );
+    CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt);
+    CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_on);
+    `
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Enqueue records the event and a pending delivery to every active webhook subscribed to it,
+// in the transaction of the note change.
+func (s *SQLiteStore) Enqueue(ctx context.Context, tx *sql.Tx, event note.Event) error {
+	subscriptions, err := listSubscriptions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	var subscribed []Subscription
+	for _, sub := range subscriptions {
+		if sub.Active && sub.Subscribes(event.Type) {
+			subscribed = append(subscribed, sub)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	created := event.Time.UTC()
+	payload, err := json.Marshal(Payload{ID: uid.String(), Type: event.Type, CreatedOn: created, Data: event.Note})
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO webhook_events (id, type, payload, created_on) VALUES (?, ?, ?, ?)",
+		uid.String(), event.Type, string(payload), created); err != nil {
+		return err
+	}
+	for _, sub := range subscribed {
+		did, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		if err := saveDelivery(ctx, tx, Delivery{
+			ID:             did.String(),
+			SubscriptionID: sub.ID,
+			EventID:        uid.String(),
+			Status:         StatusPending,
+			NextAttempt:    &created,
+			CreatedOn:      created,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (s *SQLiteStore) SaveSubscription(ctx context.Context, sub Subscription) error {
+	events, err := json.Marshal(sub.Events)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+    INSERT INTO webhooks (id, url, events, secret, active, created_on) VALUES (?, ?, ?, ?, ?, ?)
+    ON CONFLICT(id) DO UPDATE SET url = excluded.url, events = excluded.events, secret = excluded.secret, active = excluded.active
+    `, sub.ID, sub.URL, string(events), sub.Secret, sub.Active, sub.CreatedOn.UTC())
+	return err
+}
+
+const subscriptionColumns = "id, url, events, secret, active, created_on"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row scanner) (Subscription, error) {
+	var (
+		sub    Subscription
+		events string
+	)
+	if err := row.Scan(&sub.ID, &sub.URL, &events, &sub.Secret, &sub.Active, &sub.CreatedOn); err != nil {
+		return Subscription{}, err
+	}
+	if err := json.Unmarshal([]byte(events), &sub.Events); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *SQLiteStore) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	sub, err := scanSubscription(s.db.QueryRowContext(ctx, "SELECT "+subscriptionColumns+" FROM webhooks WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (s *SQLiteStore) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	return listSubscriptions(ctx, s.db)
+}
+
+func listSubscriptions(ctx context.Context, q queryer) ([]Subscription, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+subscriptionColumns+" FROM webhooks ORDER BY created_on")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subscriptions := []Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteSubscription(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) SaveDelivery(ctx context.Context, d Delivery) error {
+	return saveDelivery(ctx, s.db, d)
+}
+
+func saveDelivery(ctx context.Context, e execer, d Delivery) error {
+	_, err := e.ExecContext(ctx, `
+    INSERT INTO webhook_deliveries (id, webhook_id, event_id, status, attempts, next_attempt, last_attempt, response_status, error, created_on, delivered_on)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(id) DO UPDATE SET
+        status = excluded.status, attempts = excluded.attempts, next_attempt = excluded.next_attempt,
+        last_attempt = excluded.last_attempt, response_status = excluded.response_status, error = excluded.error,
+        delivered_on = excluded.delivered_on
+    `, d.ID, d.SubscriptionID, d.EventID, d.Status, d.Attempts, utc(d.NextAttempt), utc(d.LastAttempt),
+		d.ResponseStatus, d.Error, d.CreatedOn.UTC(), utc(d.DeliveredOn))
+	return err
+}
+
+// utc converts an optional time to UTC for storage.
+func utc(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt, d.last_attempt,
+    d.response_status, d.error, d.created_on, d.delivered_on, e.payload`
+
+const deliveryTables = "webhook_deliveries d JOIN webhook_events e ON e.id = d.event_id"
+
+func scanDelivery(row scanner) (Delivery, error) {
+	var (
+		d           Delivery
+		nextAttempt sql.NullTime
+		lastAttempt sql.NullTime
+		deliveredOn sql.NullTime
+		payload     string
+	)
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &nextAttempt, &lastAttempt,
+		&d.ResponseStatus, &d.Error, &d.CreatedOn, &deliveredOn, &payload)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if nextAttempt.Valid {
+		d.NextAttempt = &nextAttempt.Time
+	}
+	if lastAttempt.Valid {
+		d.LastAttempt = &lastAttempt.Time
+	}
+	if deliveredOn.Valid {
+		d.DeliveredOn = &deliveredOn.Time
+	}
+	d.Payload = json.RawMessage(payload)
+	return d, nil
+}
+
+func (s *SQLiteStore) GetDelivery(ctx context.Context, id string) (Delivery, error) {
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM "+deliveryTables+" WHERE d.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+func (s *SQLiteStore) Deliveries(ctx context.Context, subscriptionID string, limit int) ([]Delivery, error) {
+	return s.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM "+deliveryTables+`
+    WHERE d.webhook_id = ? ORDER BY d.created_on DESC, d.rowid DESC LIMIT ?`, subscriptionID, limit)
+}
+
+func (s *SQLiteStore) Due(ctx context.Context, t time.Time, limit int) ([]Delivery, error) {
+	return s.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM "+deliveryTables+`
+    WHERE d.status = ? AND d.next_attempt <= ? ORDER BY d.next_attempt, d.rowid LIMIT ?`, StatusPending, t.UTC(), limit)
+}
+
+func (s *SQLiteStore) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,220 @@
+// Package client is a typed client for the API. It sends a request ID with every request, retries the
+// requests answered with 429 Too Many Requests or 503 Service Unavailable, and returns the error responses
+// as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultMinBackoff = 200 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+)
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with an HTTP client other than http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken sends a bearer token in the Authorization header of every request.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries changes how often a request answered with 429 or 503 is sent again, 3 times by default,
+// and the bounds of the exponential backoff between attempts, used when the response has no Retry-After
+// header. The maximum also caps the wait a Retry-After header asks for. 0 retries disables retrying.
+func WithRetries(retries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = retries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New creates a client of the API served at baseURL, such as http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: expecting an http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "insecure-go-api-client",
+		maxRetries: defaultMaxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// WithRequestID returns a context whose requests are sent with the request ID, for example to pass on
+// the ID of the request being handled. Otherwise each call is sent with a new request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// request is a call to the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// encoded as JSON
+	body interface{}
+	// sent as is rather than body
+	raw         []byte
+	contentType string
+}
+
+// do sends a request, retrying it while the API is rate limiting or unavailable, and decodes the JSON response
+// into out, or copies it to out when out is an io.Writer. The response is returned for its headers; its body
+// is closed.
+func (c *Client) do(ctx context.Context, req request, out interface{}) (*http.Response, error) {
+	target := *c.baseURL
+	target.Path += req.path
+	target.RawQuery = req.query.Encode()
+	body, contentType := req.raw, req.contentType
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+		contentType = "application/json"
+	}
+	// the same ID is sent with every attempt
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Accept", "application/json")
+		if body != nil {
+			httpReq.Header.Set("Content-Type", contentType)
+		}
+		httpReq.Header.Set("X-Request-ID", requestID)
+		httpReq.Header.Set("User-Agent", c.userAgent)
+		if c.token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		res, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		if retryable(res.StatusCode) && attempt < c.maxRetries {
+			delay := c.backoff(attempt, res.Header.Get("Retry-After"))
+			// give up straight away rather than wait past the deadline
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > delay {
+				io.Copy(io.Discard, io.LimitReader(res.Body, maxErrorBody))
+				res.Body.Close()
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				case <-timer.C:
+				}
+				continue
+			}
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode >= http.StatusBadRequest {
+			return res, newError(res, requestID)
+		}
+		if out == nil || res.StatusCode == http.StatusNoContent {
+			return res, nil
+		}
+		if w, ok := out.(io.Writer); ok {
+			_, err = io.Copy(w, res.Body)
+			return res, err
+		}
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res, fmt.Errorf("invalid response to %s %s: %w", req.method, req.path, err)
+		}
+		return res, nil
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// backoff returns how long to wait before another attempt: as long as the Retry-After header asks, in
+// seconds or until a date, but no longer than the maximum backoff, or else exponentially longer with
+// every attempt, with jitter.
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	if retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			if seconds > int(c.maxBackoff/time.Second) {
+				return c.maxBackoff
+			}
+			return time.Duration(seconds) * time.Second
+		}
+		if t, err := http.ParseTime(retryAfter); err == nil {
+			return min(max(time.Until(t), 0), c.maxBackoff)
+		}
+	}
+	d := c.minBackoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	// between half and all of d
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fortify-presales/insecure-go-api/internal/config"
+	"github.com/fortify-presales/insecure-go-api/internal/handler"
+	"github.com/fortify-presales/insecure-go-api/internal/middleware"
+	"github.com/fortify-presales/insecure-go-api/internal/note"
+	"github.com/fortify-presales/insecure-go-api/internal/site"
+	"github.com/fortify-presales/insecure-go-api/pkg/log"
+)
+
+// newTestServer serves the API with an in-memory repository, passing the requests through wrap first.
+func newTestServer(t *testing.T, services site.Services, wrap middleware.Middleware) *httptest.Server {
+	logger, _ := log.NewForTest()
+	repo, err := note.NewInmemoryRepository(logger)
+	require.NoError(t, err)
+	require.NoError(t, repo.Populate(context.Background()))
+	cfg := &config.Config{DataDir: t.TempDir()}
+	h := middleware.RequestID()(handler.BuildHandler(logger, cfg, repo, handler.Components{Site: services}))
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server, opts ...Option) *Client {
+	c, err := New(srv.URL, append([]Option{WithRetries(3, time.Millisecond, 10*time.Millisecond)}, opts...)...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_Notes(t *testing.T) {
+	c := newTestClient(t, newTestServer(t, site.Services{}, nil))
+	ctx := context.Background()
+
+	created, err := c.CreateNote(ctx, Note{Title: "zap", Description: "zap is a logging package"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.NoteID)
+	assert.NotZero(t, created.Version)
+	_, err = c.CreateNote(ctx, Note{Title: "zap"})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	got, err := c.GetNote(ctx, created.NoteID)
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+	notes, err := c.ListNotes(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, notes, 3)
+	assert.Contains(t, notes, created)
+
+	created.Description = "updated"
+	updated, err := c.UpdateNote(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", updated.Description)
+	_, err = c.UpdateNote(ctx, created)
+	assert.ErrorIs(t, err, ErrConflict, "the note changed since the version")
+
+	_, err = c.CreateNote(ctx, Note{Description: "no title"})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, []Violation{{In: "body", Name: "/title", Message: "expecting at least 1 characters"}}, apiErr.Violations)
+	assert.NotEmpty(t, apiErr.RequestID)
+
+	require.NoError(t, c.DeleteNote(ctx, created.NoteID))
+	_, err = c.GetNote(ctx, created.NoteID)
+	require.ErrorAs(t, err, &apiErr)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotEmpty(t, apiErr.Message)
+}
+
+func TestClient_NoteChanges(t *testing.T) {
+	c := newTestClient(t, newTestServer(t, site.Services{}, nil))
+	ctx := context.Background()
+
+	var (
+		upserts int
+		last    Changes
+	)
+	for page, err := range c.NoteChanges(ctx, "", 1) {
+		require.NoError(t, err)
+		upserts += len(page.Upserts)
+		last = page
+	}
+	assert.Equal(t, 2, upserts, "every note is paged through")
+
+	results, err := c.SyncNotes(ctx, SyncChange{Title: "cobra", Description: "cobra is a CLI package"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, SyncApplied, results[0].Status)
+	var pages []Changes
+	for page, err := range c.NoteChanges(ctx, last.Token, 0) {
+		require.NoError(t, err)
+		pages = append(pages, page)
+	}
+	require.Len(t, pages, 1)
+	require.Len(t, pages[0].Upserts, 1)
+	assert.Equal(t, "cobra", pages[0].Upserts[0].Title)
+
+	for _, err := range c.NoteChanges(ctx, "garbage", 0) {
+		assert.ErrorIs(t, err, ErrBadRequest)
+	}
+}
+
+func TestClient_Site(t *testing.T) {
+	history, err := site.NewHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { history.Close() })
+	ctx := context.Background()
+	for i := range 5 {
+		_, err := history.Record(ctx, "10.0.0.1:1234", site.Execution{Command: "ping", Args: []string{fmt.Sprintf("host%d", i)}})
+		require.NoError(t, err)
+	}
+	_, err = history.Record(ctx, "10.0.0.1:1234", site.Execution{Command: "nslookup", Args: []string{"host0"}})
+	require.NoError(t, err)
+	srv := newTestServer(t, site.Services{History: history}, nil)
+	c := newTestClient(t, srv)
+
+	var ids []int64
+	for e, err := range c.History(ctx, HistoryFilter{Command: "ping"}, 2) {
+		require.NoError(t, err)
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, ids, "the pages are followed, newest first")
+	for e, err := range c.History(ctx, HistoryFilter{Arg: "host0"}, 0) {
+		require.NoError(t, err)
+		ids = append(ids[:0], e.ID)
+		break
+	}
+	assert.Equal(t, []int64{6}, ids, "the iteration can stop early")
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	result, err := c.CheckPort(ctx, PortCheck{Hostname: u.Hostname(), Port: port, Timeout: 1})
+	require.NoError(t, err)
+	assert.True(t, result.Open)
+
+	_, err = c.CheckPort(ctx, PortCheck{Hostname: u.Hostname()})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	assert.Equal(t, "port must be between 1 and 65535", apiErr.Message, "a text error is kept as the message")
+	uploaded, err := c.UploadFile(ctx, "report.txt", strings.NewReader("all systems go"))
+	require.NoError(t, err)
+	assert.Equal(t, "report.txt", uploaded.Name)
+	downloads, err := c.ListDownloads(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Download{uploaded}, downloads)
+	var contents bytes.Buffer
+	require.NoError(t, c.DownloadFile(ctx, uploaded.ID, &contents))
+	assert.Equal(t, "all systems go", contents.String())
+	assert.ErrorIs(t, c.DownloadFile(ctx, "missing", &contents), ErrNotFound)
+
+	_, err = c.GetJob(ctx, "42")
+	assert.ErrorIs(t, err, ErrNotFound, "the jobs API is not served without a job manager")
+}
+
+func TestClient_Retries(t *testing.T) {
+	var (
+		attempts   atomic.Int32
+		requestIDs = make(chan string, 10)
+	)
+	// the first two attempts of each request are turned away
+	busy := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestIDs <- r.Header.Get("X-Request-ID")
+			assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+			switch attempts.Add(1) {
+			case 1:
+				if r.Method == http.MethodPost {
+					b, _ := io.ReadAll(r.Body)
+					assert.NotEmpty(t, b)
+				}
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+	c := newTestClient(t, newTestServer(t, site.Services{}, busy), WithToken("s3cret"))
+
+	n, err := c.CreateNote(WithRequestID(context.Background(), "req-1"), Note{Title: "zap"})
+	require.NoError(t, err, "the body is sent again")
+	assert.Equal(t, "zap", n.Title)
+	assert.Equal(t, int32(3), attempts.Load())
+	for range 3 {
+		assert.Equal(t, "req-1", <-requestIDs)
+	}
+
+	attempts.Store(0)
+	c = newTestClient(t, newTestServer(t, site.Services{}, busy), WithToken("s3cret"), WithRetries(0, 0, 0))
+	_, err = c.ListNotes(context.Background(), "")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient_Backoff(t *testing.T) {
+	c, err := New("http://localhost:8080", WithRetries(3, 100*time.Millisecond, time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, c.backoff(0, "1"))
+	assert.Zero(t, c.backoff(0, "0"))
+	assert.Zero(t, c.backoff(0, "Thu, 01 Jan 1970 00:00:00 GMT"))
+	// Retry-After is capped by the maximum backoff
+	assert.Equal(t, time.Second, c.backoff(0, "2"))
+	assert.Equal(t, time.Second, c.backoff(0, "9223372036854775807"))
+	assert.Equal(t, time.Second, c.backoff(0, time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat)))
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second} {
+		d := c.backoff(attempt, "")
+		assert.GreaterOrEqual(t, d, want/2)
+		assert.LessOrEqual(t, d, want)
+	}
+
+	// a Retry-After beyond the deadline is not waited for
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "8")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	c, err = New(srv.URL)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = c.ListNotes(ctx, "")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, errors.Is(err, ErrUnavailable))
+
+	_, err = New("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestClient_Imports(t *testing.T) {
+	pkg, err := build.ImportDir(".", 0)
+	require.NoError(t, err)
+	for _, path := range pkg.Imports {
+		if strings.Contains(path, ".") {
+			assert.Equal(t, "github.com/google/uuid", path, "the client only depends on the standard library and uuid")
+		}
+	}
+}
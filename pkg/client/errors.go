@@ -0,0 +1,102 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody bounds the part of an error response that is read
+const maxErrorBody = 64 << 10
+
+// The kinds of error responses, matched by errors.Is with an *Error
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnavailable  = errors.New("unavailable")
+)
+
+// Violation is a part of a request that does not match the API description
+type Violation struct {
+	// path, query, header or body
+	In string
+	// the parameter, or a JSON pointer into the body
+	Name    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s: %s", v.In, v.Name, v.Message)
+}
+
+// apiError is the JSON error response of the API
+type apiError struct {
+	ErrorCode    int
+	ErrorMessage string
+	Errors       []Violation
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	// the error message of the API, or the body of the response when it is not a JSON error
+	Message string
+	// the ID the request was sent with, to look the request up in the logs of the API
+	RequestID string
+	// what does not match the API description, when the request was rejected as invalid
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	for _, v := range e.Violations {
+		msg += "; " + v.String()
+	}
+	return msg
+}
+
+// Is matches the error with the kind of its status code, such as ErrNotFound for 404 Not Found.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// newError reads an error response. The notes API answers with a JSON model.APIError, listing the violations
+// when the request does not match the API description; the other APIs answer with text.
+func newError(res *http.Response, requestID string) *Error {
+	e := &Error{StatusCode: res.StatusCode, RequestID: requestID}
+	if id := res.Header.Get("X-Request-ID"); id != "" {
+		e.RequestID = id
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	var apiErr apiError
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") && json.Unmarshal(body, &apiErr) == nil {
+		e.Message = apiErr.ErrorMessage
+		e.Violations = apiErr.Errors
+		return e
+	}
+	e.Message = strings.TrimSpace(string(body))
+	return e
+}
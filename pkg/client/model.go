@@ -0,0 +1,364 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Site holds the parameters of a ping
+type Site struct {
+	Hostname string `json:"hostname"`
+	// number of echo requests to send (1-20, default 4)
+	Count int `json:"count,omitempty"`
+	// seconds to wait for each reply (1-30, default 5)
+	Timeout int `json:"timeout,omitempty"`
+	// seconds between echo requests (0.2-10, default 1)
+	Interval float64 `json:"interval,omitempty"`
+}
+
+// PingResult holds the statistics reported by ping
+type PingResult struct {
+	Hostname string `json:"hostname"`
+	// the address that was pinged, if ping resolved the hostname
+	Address         string `json:"address,omitempty"`
+	PacketsSent     int    `json:"packets_sent"`
+	PacketsReceived int    `json:"packets_received"`
+	// percentage of echo requests that were not answered
+	PacketLoss float64 `json:"packet_loss"`
+	// round-trip times, omitted when no replies were received
+	RTT    *RTT   `json:"rtt,omitempty"`
+	Output string `json:"output"`
+}
+
+// RTT holds round-trip time statistics in milliseconds
+type RTT struct {
+	Min  float64 `json:"min_ms"`
+	Avg  float64 `json:"avg_ms"`
+	Max  float64 `json:"max_ms"`
+	Mdev float64 `json:"mdev_ms"`
+}
+
+// DNSLookup holds the parameters of a DNS lookup
+type DNSLookup struct {
+	Hostname string `json:"hostname"`
+	// record types to look up: A, AAAA, CNAME, MX and TXT (default A, AAAA and CNAME)
+	Types []string `json:"types,omitempty"`
+	// address of the DNS server to query, with an optional port (default the system resolver)
+	Resolver string `json:"resolver,omitempty"`
+	// seconds to wait for the answers (1-30, default 5)
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// DNSResult holds the records found for a hostname
+type DNSResult struct {
+	Hostname string `json:"hostname"`
+	// the DNS server that was queried, or "system"
+	Resolver string     `json:"resolver"`
+	A        []string   `json:"a,omitempty"`
+	AAAA     []string   `json:"aaaa,omitempty"`
+	CNAME    string     `json:"cname,omitempty"`
+	MX       []MXRecord `json:"mx,omitempty"`
+	TXT      []string   `json:"txt,omitempty"`
+	// lookup errors by record type
+	Errors map[string]string `json:"errors,omitempty"`
+	// time taken in milliseconds
+	Duration float64 `json:"duration_ms"`
+}
+
+// MXRecord is a mail exchanger of a domain
+type MXRecord struct {
+	Host string `json:"host"`
+	Pref uint16 `json:"pref"`
+}
+
+// PortCheck holds the parameters of a TCP port check
+type PortCheck struct {
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+	// seconds to wait for the connection (1-30, default 5)
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// PortResult reports whether a TCP port accepts connections
+type PortResult struct {
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+	Open     bool   `json:"open"`
+	// the address that accepted the connection
+	Address string `json:"address,omitempty"`
+	// time taken to connect in milliseconds
+	Latency float64 `json:"latency_ms,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// TLSCheck holds the parameters of a TLS certificate check
+type TLSCheck struct {
+	Hostname string `json:"hostname"`
+	// default 443
+	Port int `json:"port,omitempty"`
+	// the name sent in the TLS handshake and verified against the certificate (default the hostname)
+	ServerName string `json:"server_name,omitempty"`
+	// seconds to wait for the handshake (1-30, default 5)
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// TLSResult describes the TLS connection and certificate chain presented by a server
+type TLSResult struct {
+	Hostname   string `json:"hostname"`
+	Port       int    `json:"port"`
+	ServerName string `json:"server_name"`
+	Address    string `json:"address,omitempty"`
+	// negotiated protocol version, e.g. "TLS 1.3"
+	Version     string `json:"version,omitempty"`
+	CipherSuite string `json:"cipher_suite,omitempty"`
+	// negotiated application protocol, e.g. "h2"
+	ALPN string `json:"alpn,omitempty"`
+	// whether the chain is trusted and valid for the server name
+	Verified    bool          `json:"verified"`
+	VerifyError string        `json:"verify_error,omitempty"`
+	Chain       []Certificate `json:"chain,omitempty"`
+	// time taken to connect and complete the handshake in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Certificate describes a certificate of a TLS chain, leaf first
+type Certificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	ExpiresInDays      int       `json:"expires_in_days"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+}
+
+// HTTPProbe holds the parameters of an HTTP request
+type HTTPProbe struct {
+	URL string `json:"url"`
+	// GET or HEAD (default GET)
+	Method string `json:"method,omitempty"`
+	// maximum number of redirects to follow (0-10, default 5)
+	MaxRedirects *int `json:"max_redirects,omitempty"`
+	// seconds to wait for the whole probe (1-30, default 5)
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// HTTPResult describes the response to an HTTP probe
+type HTTPResult struct {
+	URL string `json:"url"`
+	// the URL of the last response, after following redirects
+	FinalURL   string      `json:"final_url"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Proto      string      `json:"proto,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Redirects  []Redirect  `json:"redirects,omitempty"`
+	// number of body bytes read, up to 1 MiB
+	BodyBytes int64 `json:"body_bytes"`
+	// timing of the last request
+	Timing HTTPTiming `json:"timing"`
+	// time taken by all requests in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Redirect is a response that redirected the probe
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// HTTPTiming breaks down the time taken by a request in milliseconds. Phases that didn't
+// happen, such as the TLS handshake of a plain HTTP request, are zero.
+type HTTPTiming struct {
+	DNS       float64 `json:"dns_ms"`
+	Connect   float64 `json:"connect_ms"`
+	TLS       float64 `json:"tls_ms"`
+	FirstByte float64 `json:"first_byte_ms"`
+	Total     float64 `json:"total_ms"`
+}
+
+// Download is a file of the downloads catalogue
+type Download struct {
+	ID string `json:"id"`
+	// the name the file was uploaded with, offered to clients when it is downloaded
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	// hex encoded SHA-256 of the contents
+	SHA256   string    `json:"sha256"`
+	Uploaded time.Time `json:"uploaded"`
+}
+
+// HistoryEntry is a command run by the site API
+type HistoryEntry struct {
+	// sequence number, increasing with every command run
+	ID      int64     `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	// the remote address of the client that requested the command
+	Client    string `json:"client"`
+	RequestID string `json:"request_id,omitempty"`
+	// -1 if the command could not be started or was killed
+	ExitCode int `json:"exit_code"`
+	// the signal that killed the command, if any
+	Signal   string `json:"signal,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+	// run time in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Output   string  `json:"output"`
+	// whether output beyond the capture limit was discarded
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// HistoryFilter selects command history entries. Zero values match every entry.
+type HistoryFilter struct {
+	Command string
+	// matches entries with an argument containing the text, such as a hostname
+	Arg       string
+	Client    string
+	RequestID string
+	ExitCode  *int
+	Since     time.Time
+	Until     time.Time
+}
+
+// historyPage is a page of the command history
+type historyPage struct {
+	// number of entries matching the filter
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// JobRequest submits a site diagnostic to run in the background
+type JobRequest struct {
+	// one of "ping", "dns", "port", "tls" or "http"
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// JobStatus is the state of a job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// Finished reports whether a job with the status will not change any more.
+func (s JobStatus) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// Job is a site diagnostic run in the background
+type Job struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Status JobStatus       `json:"status"`
+	Params json.RawMessage `json:"params,omitempty"`
+	// percentage of the work done
+	Progress   int             `json:"progress"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedOn  time.Time       `json:"created_on"`
+	StartedOn  *time.Time      `json:"started_on,omitempty"`
+	FinishedOn *time.Time      `json:"finished_on,omitempty"`
+}
+
+// MonitorRequest registers a site diagnostic to run on an interval
+type MonitorRequest struct {
+	Name string `json:"name"`
+	// one of "ping", "dns", "port", "tls" or "http"
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+	// seconds between checks (5-86400, default 60)
+	Interval int `json:"interval,omitempty"`
+}
+
+// MonitorState is the outcome of the latest check of a monitor
+type MonitorState string
+
+const (
+	MonitorUnknown MonitorState = "unknown"
+	MonitorUp      MonitorState = "up"
+	MonitorDown    MonitorState = "down"
+)
+
+// Monitor checks a host or URL on an interval with one of the site diagnostics
+type Monitor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// the diagnostic used: "ping", "dns", "port", "tls" or "http"
+	Type string `json:"type"`
+	// the diagnostic parameters, as for a job of the same type
+	Params json.RawMessage `json:"params"`
+	// seconds between checks
+	Interval       int          `json:"interval"`
+	State          MonitorState `json:"state"`
+	CreatedOn      time.Time    `json:"created_on"`
+	LastCheckedOn  *time.Time   `json:"last_checked_on,omitempty"`
+	StateChangedOn *time.Time   `json:"state_changed_on,omitempty"`
+}
+
+// MonitorHistory summarizes the checks of a monitor over a period
+type MonitorHistory struct {
+	MonitorID string    `json:"monitor_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	// number of checks in the period
+	Checks int `json:"checks"`
+	Up     int `json:"up"`
+	// percentage of checks that were up, or 100 if there were no checks
+	Uptime float64 `json:"uptime_percent"`
+	// latency of the checks that were up, omitted if there were none
+	Latency *LatencyStats `json:"latency,omitempty"`
+	// periods during which the monitor was down, oldest first
+	Incidents []Incident `json:"incidents"`
+	// the checks in the period, oldest first, when requested
+	Entries []Check `json:"entries,omitempty"`
+}
+
+// LatencyStats holds latency percentiles in milliseconds
+type LatencyStats struct {
+	Min float64 `json:"min_ms"`
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P95 float64 `json:"p95_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// Incident is a period during which a monitor was down
+type Incident struct {
+	Start time.Time `json:"start"`
+	// the time of the first check that was up again, omitted while the incident is ongoing
+	End *time.Time `json:"end,omitempty"`
+	// seconds from the start to the end, or to the end of the period if ongoing
+	Duration float64 `json:"duration_seconds"`
+	// number of checks that were down
+	Checks int `json:"checks"`
+	// the error reported by the first check that was down
+	Error string `json:"error,omitempty"`
+}
+
+// Check is the outcome of one check of a monitor
+type Check struct {
+	Time time.Time `json:"time"`
+	Up   bool      `json:"up"`
+	// latency measured by the check in milliseconds
+	Latency float64         `json:"latency_ms,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Note is a note with the fields of the server's note.Note. It is sent and received in the representation
+// of the sync protocol rather than the frozen version 1 one, which leaves out the version and update time
+// that conditional updates and syncs need.
+type Note struct {
+	NoteID      string    `json:"noteid"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedOn   time.Time `json:"createdon"`
+	UpdatedOn   time.Time `json:"updatedon"`
+	// the position of the latest change to the note in the change sequence. Pass it as the base
+	// version of a change to the note
+	Version uint64 `json:"version"`
+}
+
+// Changes is a page of the change feed
+type Changes struct {
+	// the notes created or updated since the token, oldest change first
+	Upserts []Note `json:"upserts"`
+	// the notes deleted since the token, oldest first
+	Tombstones []Tombstone `json:"tombstones"`
+	// pass as since to get the changes that follow
+	Token string `json:"token"`
+	// more changes follow: request them with the token straight away
+	More bool `json:"more"`
+	// the token was issued for another change sequence. Discard the local notes and apply the changes
+	// from scratch
+	Reset bool `json:"reset"`
+}
+
+// Tombstone records the deletion of a note
+type Tombstone struct {
+	NoteID string `json:"noteid"`
+	// the position of the deletion in the change sequence
+	Version   uint64    `json:"version"`
+	DeletedOn time.Time `json:"deletedon"`
+}
+
+// SyncChange is a change made by an offline client to the version of a note it last saw
+type SyncChange struct {
+	// the note changed. Creates a note when the note is not known and BaseVersion is 0,
+	// with a new ID when empty
+	NoteID string `json:"noteid,omitempty"`
+	// the version of the note the change was made to, 0 for a new note
+	BaseVersion uint64 `json:"base_version"`
+	// delete rather than update the note
+	Deleted     bool   `json:"deleted,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Outcomes of a client change
+const (
+	SyncApplied = "applied"
+	// the note was changed since the base version of the client change
+	SyncConflict = "conflict"
+	// the change is invalid, such as a title used by another note
+	SyncRejected = "rejected"
+	// the change could not be applied and can be retried
+	SyncFailed = "failed"
+)
+
+// SyncResult is the outcome of a client change
+type SyncResult struct {
+	NoteID string `json:"noteid,omitempty"`
+	// one of the Sync outcomes
+	Status string `json:"status"`
+	// the note after the change was applied, or as it is when the change conflicts with it.
+	// Missing when the note is deleted
+	Note  *Note  `json:"note,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// noteV2 is the version 2 representation of a note, which the client uses for single notes
+type noteV2 struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Version     uint64    `json:"version"`
+}
+
+// noteInputV2 is the body of a version 2 create or update
+type noteInputV2 struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     uint64 `json:"version,omitempty"`
+}
+
+type syncRequest struct {
+	Changes []SyncChange `json:"changes"`
+}
+
+type syncResponse struct {
+	Results []SyncResult `json:"results"`
+}
+
+// fromV2 converts a note of version 2 of the notes API.
+func fromV2(n noteV2) Note {
+	return Note{
+		NoteID:      n.ID,
+		Title:       n.Title,
+		Description: n.Description,
+		CreatedOn:   n.CreatedAt,
+		UpdatedOn:   n.UpdatedAt,
+		Version:     n.Version,
+	}
+}
+
+// ListNotes returns the notes whose title or description contains the keywords, or every note.
+func (c *Client) ListNotes(ctx context.Context, keywords string) ([]Note, error) {
+	query := url.Values{}
+	if keywords != "" {
+		query.Set("keywords", keywords)
+	}
+	var list []noteV2
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/notes", query: query}, &list); err != nil {
+		return nil, err
+	}
+	notes := make([]Note, len(list))
+	for i, n := range list {
+		notes[i] = fromV2(n)
+	}
+	return notes, nil
+}
+
+// GetNote returns a note, or an error matching ErrNotFound.
+func (c *Client) GetNote(ctx context.Context, id string) (Note, error) {
+	var n noteV2
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v2/notes/" + url.PathEscape(id)}, &n); err != nil {
+		return Note{}, err
+	}
+	return fromV2(n), nil
+}
+
+// CreateNote creates a note from its title and description, returning it. An error matching ErrConflict is
+// returned when another note has the title.
+func (c *Client) CreateNote(ctx context.Context, n Note) (Note, error) {
+	in := noteInputV2{Title: n.Title, Description: n.Description}
+	var created noteV2
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v2/notes", body: in}, &created); err != nil {
+		return Note{}, err
+	}
+	return fromV2(created), nil
+}
+
+// UpdateNote replaces the title and description of a note, returning it. When the note has a version the
+// update is made only if the note has not changed since, and an error matching ErrConflict is returned otherwise.
+func (c *Client) UpdateNote(ctx context.Context, n Note) (Note, error) {
+	in := noteInputV2{Title: n.Title, Description: n.Description, Version: n.Version}
+	var updated noteV2
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: "/api/v2/notes/" + url.PathEscape(n.NoteID), body: in}, &updated); err != nil {
+		return Note{}, err
+	}
+	return fromV2(updated), nil
+}
+
+// DeleteNote deletes a note.
+func (c *Client) DeleteNote(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/v2/notes/" + url.PathEscape(id)}, nil)
+	return err
+}
+
+// NoteChanges pages through the note changes since a sync token, or through every note when the token is
+// empty, with up to limit changes a page or the default of the API when limit is 0. The iteration stops
+// after the first error. Keep the token of the last page to get the changes that follow next time, and
+// discard the local notes when a page is a reset.
+func (c *Client) NoteChanges(ctx context.Context, since string, limit int) iter.Seq2[Changes, error] {
+	return func(yield func(Changes, error) bool) {
+		for {
+			query := url.Values{}
+			if since != "" {
+				query.Set("since", since)
+			}
+			if limit > 0 {
+				query.Set("limit", strconv.Itoa(limit))
+			}
+			var page Changes
+			if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/notes/changes", query: query}, &page); err != nil {
+				yield(Changes{}, err)
+				return
+			}
+			if !yield(page, nil) || !page.More {
+				return
+			}
+			since = page.Token
+		}
+	}
+}
+
+// SyncNotes applies the changes made by an offline client, returning the outcome of each change in order.
+func (c *Client) SyncNotes(ctx context.Context, changes ...SyncChange) ([]SyncResult, error) {
+	var res syncResponse
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/notes/sync", body: syncRequest{Changes: changes}}, &res); err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ping pings a site.
+func (c *Client) Ping(ctx context.Context, s Site) (PingResult, error) {
+	var result PingResult
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/site/ping", body: s}, &result)
+	return result, err
+}
+
+// LookupDNS looks up the DNS records of a hostname.
+func (c *Client) LookupDNS(ctx context.Context, lookup DNSLookup) (DNSResult, error) {
+	query := url.Values{"hostname": {lookup.Hostname}}
+	if len(lookup.Types) > 0 {
+		query.Set("type", strings.Join(lookup.Types, ","))
+	}
+	setString(query, "resolver", lookup.Resolver)
+	setInt(query, "timeout", lookup.Timeout)
+	var result DNSResult
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/dns", query: query}, &result)
+	return result, err
+}
+
+// CheckPort checks whether a TCP port accepts connections.
+func (c *Client) CheckPort(ctx context.Context, check PortCheck) (PortResult, error) {
+	query := url.Values{"hostname": {check.Hostname}, "port": {strconv.Itoa(check.Port)}}
+	setInt(query, "timeout", check.Timeout)
+	var result PortResult
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/port", query: query}, &result)
+	return result, err
+}
+
+// CheckTLS describes the TLS configuration and certificate chain of a server.
+func (c *Client) CheckTLS(ctx context.Context, check TLSCheck) (TLSResult, error) {
+	query := url.Values{"hostname": {check.Hostname}}
+	setInt(query, "port", check.Port)
+	setString(query, "server_name", check.ServerName)
+	setInt(query, "timeout", check.Timeout)
+	var result TLSResult
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/tls", query: query}, &result)
+	return result, err
+}
+
+// ProbeHTTP sends a request to a URL and reports the response, redirects and timing.
+func (c *Client) ProbeHTTP(ctx context.Context, probe HTTPProbe) (HTTPResult, error) {
+	query := url.Values{"url": {probe.URL}}
+	setString(query, "method", probe.Method)
+	if probe.MaxRedirects != nil {
+		query.Set("max_redirects", strconv.Itoa(*probe.MaxRedirects))
+	}
+	setInt(query, "timeout", probe.Timeout)
+	var result HTTPResult
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/http", query: query}, &result)
+	return result, err
+}
+
+// ListDownloads returns the files of the downloads catalogue.
+func (c *Client) ListDownloads(ctx context.Context) ([]Download, error) {
+	var downloads []Download
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/downloads"}, &downloads)
+	return downloads, err
+}
+
+// GetDownload returns the metadata of a file in the downloads catalogue.
+func (c *Client) GetDownload(ctx context.Context, id string) (Download, error) {
+	var download Download
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/downloads/" + url.PathEscape(id)}, &download)
+	return download, err
+}
+
+// DownloadFile writes the contents of a file of the downloads catalogue to w. When the server signs
+// download links, files are only served through them and an error matching ErrUnauthorized is returned.
+func (c *Client) DownloadFile(ctx context.Context, id string, w io.Writer) error {
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/download/" + url.PathEscape(id)}, w)
+	return err
+}
+
+// UploadFile adds a file to the downloads catalogue, returning its metadata. The file is read into memory
+// so that the upload can be retried.
+func (c *Client) UploadFile(ctx context.Context, name string, r io.Reader) (Download, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return Download{}, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return Download{}, err
+	}
+	if err := mw.Close(); err != nil {
+		return Download{}, err
+	}
+	var download Download
+	_, err = c.do(ctx, request{method: http.MethodPost, path: "/api/v1/site/downloads", raw: body.Bytes(), contentType: mw.FormDataContentType()}, &download)
+	return download, err
+}
+
+// SubmitJob runs a site diagnostic in the background, returning the queued job. An error matching
+// ErrUnavailable is returned when the job queue stays full.
+func (c *Client) SubmitJob(ctx context.Context, req JobRequest) (Job, error) {
+	var j Job
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/site/jobs", body: req}, &j)
+	return j, err
+}
+
+// GetJob returns the state of a job.
+func (c *Client) GetJob(ctx context.Context, id string) (Job, error) {
+	var j Job
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/jobs/" + url.PathEscape(id)}, &j)
+	return j, err
+}
+
+// CancelJob cancels a queued or running job, returning its state. An error matching ErrConflict is returned
+// when the job has finished.
+func (c *Client) CancelJob(ctx context.Context, id string) (Job, error) {
+	var j Job
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/v1/site/jobs/" + url.PathEscape(id)}, &j)
+	return j, err
+}
+
+// CreateMonitor registers a site diagnostic to run on an interval.
+func (c *Client) CreateMonitor(ctx context.Context, req MonitorRequest) (Monitor, error) {
+	var m Monitor
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/site/monitors", body: req}, &m)
+	return m, err
+}
+
+// ListMonitors returns the monitors with their current state.
+func (c *Client) ListMonitors(ctx context.Context) ([]Monitor, error) {
+	var monitors []Monitor
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/monitors"}, &monitors)
+	return monitors, err
+}
+
+// GetMonitor returns a monitor with its current state.
+func (c *Client) GetMonitor(ctx context.Context, id string) (Monitor, error) {
+	var m Monitor
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/monitors/" + url.PathEscape(id)}, &m)
+	return m, err
+}
+
+// DeleteMonitor stops checking a monitor and deletes its history.
+func (c *Client) DeleteMonitor(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/v1/site/monitors/" + url.PathEscape(id)}, nil)
+	return err
+}
+
+// GetMonitorHistory summarizes the uptime of a monitor from one time to another. Zero times stand for the
+// defaults of the API: the 24 hours up to now.
+func (c *Client) GetMonitorHistory(ctx context.Context, id string, from, to time.Time, entries bool) (MonitorHistory, error) {
+	query := url.Values{}
+	setTime(query, "from", from)
+	setTime(query, "to", to)
+	if entries {
+		query.Set("entries", "true")
+	}
+	var h MonitorHistory
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/monitors/" + url.PathEscape(id) + "/history", query: query}, &h)
+	return h, err
+}
+
+// History pages through the command history selected by the filter, newest first, with up to limit entries
+// a page or the default of the API when limit is 0. The Arg of the filter is sent as the hostname. The
+// iteration stops after the first error. Commands run while iterating shift the pages, so set Until to
+// avoid seeing an entry twice.
+func (c *Client) History(ctx context.Context, f HistoryFilter, limit int) iter.Seq2[HistoryEntry, error] {
+	return func(yield func(HistoryEntry, error) bool) {
+		query := url.Values{}
+		setString(query, "command", f.Command)
+		setString(query, "hostname", f.Arg)
+		setString(query, "client", f.Client)
+		setString(query, "request_id", f.RequestID)
+		if f.ExitCode != nil {
+			query.Set("exit_code", strconv.Itoa(*f.ExitCode))
+		}
+		setTime(query, "since", f.Since)
+		setTime(query, "until", f.Until)
+		setInt(query, "limit", limit)
+		for offset := 0; ; {
+			query.Set("offset", strconv.Itoa(offset))
+			var page historyPage
+			if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/site/history", query: query}, &page); err != nil {
+				yield(HistoryEntry{}, err)
+				return
+			}
+			for _, e := range page.Entries {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			offset += len(page.Entries)
+			if len(page.Entries) == 0 || offset >= page.Total {
+				return
+			}
+		}
+	}
+}
+
+func setString(query url.Values, name, value string) {
+	if value != "" {
+		query.Set(name, value)
+	}
+}
+
+func setInt(query url.Values, name string, value int) {
+	if value != 0 {
+		query.Set(name, strconv.Itoa(value))
+	}
+}
+
+func setTime(query url.Values, name string, value time.Time) {
+	if !value.IsZero() {
+		query.Set(name, value.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,177 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels holds the root log level together with the per-logger overrides used by named loggers.
+// All levels are atomic and can be changed while the application is running.
+type Levels struct {
+	root zap.AtomicLevel
+
+	mu    sync.RWMutex
+	named map[string]zap.AtomicLevel
+	// saved holds the root level to restore when debug logging is toggled off.
+	saved zapcore.Level
+}
+
+// NewLevels creates a level set with the given root level and no overrides.
+func NewLevels(root zapcore.Level) *Levels {
+	return &Levels{
+		root:  zap.NewAtomicLevelAt(root),
+		named: make(map[string]zap.AtomicLevel),
+		saved: root,
+	}
+}
+
+// Level returns the effective level of the named logger. The empty name denotes the root logger.
+//
+// Names are dot-separated as produced by Logger.Named, and a logger without an override of its own
+// inherits the level of its closest configured parent, falling back to the root level.
+func (l *Levels) Level(name string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for name != "" {
+		if lvl, ok := l.named[name]; ok {
+			return lvl.Level()
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return l.root.Level()
+}
+
+// SetLevel changes the level of the named logger, or the root level when name is empty.
+func (l *Levels) SetLevel(name string, level zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if name == "" {
+		// toggling debug logging off goes back to the latest level other than debug
+		if level != zapcore.DebugLevel {
+			l.saved = level
+		} else if current := l.root.Level(); current != zapcore.DebugLevel {
+			l.saved = current
+		}
+		l.root.SetLevel(level)
+		return
+	}
+	if lvl, ok := l.named[name]; ok {
+		lvl.SetLevel(level)
+		return
+	}
+	l.named[name] = zap.NewAtomicLevelAt(level)
+}
+
+// ResetLevel removes the override for the named logger so that it inherits its parent's level again.
+func (l *Levels) ResetLevel(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.named, name)
+}
+
+// Overrides returns the configured per-logger levels keyed by logger name.
+func (l *Levels) Overrides() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	levels := make(map[string]string, len(l.named))
+	for name, lvl := range l.named {
+		levels[name] = lvl.Level().String()
+	}
+	return levels
+}
+
+// ToggleDebug switches the root level to debug, or back to the previous level if it is already debug.
+func (l *Levels) ToggleDebug() zapcore.Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if current := l.root.Level(); current != zapcore.DebugLevel {
+		l.saved = current
+		l.root.SetLevel(zapcore.DebugLevel)
+	} else {
+		l.root.SetLevel(l.saved)
+	}
+	return l.root.Level()
+}
+
+// enabler returns a level enabler that follows the effective level of the named logger.
+func (l *Levels) enabler(name string) zapcore.LevelEnabler {
+	if name == "" {
+		return l.root
+	}
+	return zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return l.Level(name).Enabled(level)
+	})
+}
+
+type levelsPayload struct {
+	Logger string            `json:"logger,omitempty"`
+	Level  string            `json:"level"`
+	Named  map[string]string `json:"loggers,omitempty"`
+}
+
+// ServeHTTP reports the current levels on GET and changes a level on PUT.
+//
+// A PUT body of {"level":"debug"} changes the root level, {"logger":"note","level":"debug"} overrides the
+// level of the "note" logger and {"logger":"note","level":""} removes that override.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req levelsPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Logger != "" && req.Level == "" {
+			l.ResetLevel(req.Logger)
+			break
+		}
+		level, err := zapcore.ParseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		l.SetLevel(req.Logger, level)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levelsPayload{
+		Level: l.root.Level().String(),
+		Named: l.Overrides(),
+	})
+}
+
+// levelCore filters entries using a dynamic level enabler. The wrapped core is expected to accept
+// every level so that named loggers can be made more verbose than the root logger.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.enabler.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
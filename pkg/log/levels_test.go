@@ -0,0 +1,92 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newLeveledForTest(root zapcore.Level) (Logger, *Levels, *observer.ObservedLogs) {
+	levels := NewLevels(root)
+	core, recorded := observer.New(zapcore.DebugLevel)
+	zl := zap.New(&levelCore{Core: core, enabler: levels.enabler("")})
+	return &logger{SugaredLogger: zl.Sugar(), levels: levels}, levels, recorded
+}
+
+func TestNewWithConfig(t *testing.T) {
+	l, levels, err := NewWithConfig(Config{Level: "warn", Encoding: "console", Packages: map[string]string{"note": "debug"}})
+	require.NoError(t, err)
+	assert.NotNil(t, l)
+	assert.Equal(t, zapcore.WarnLevel, levels.Level(""))
+	assert.Equal(t, zapcore.DebugLevel, levels.Level("note"))
+
+	_, _, err = NewWithConfig(Config{Level: "loud"})
+	assert.Error(t, err)
+	_, _, err = NewWithConfig(Config{Encoding: "xml"})
+	assert.Error(t, err)
+}
+
+func TestLevels_Named(t *testing.T) {
+	l, levels, entries := newLeveledForTest(zapcore.InfoLevel)
+	noteLogger := l.Named("note")
+	sqlLogger := noteLogger.Named("sql")
+
+	l.Debug("root debug")
+	noteLogger.Debug("note debug")
+	assert.Equal(t, 0, entries.Len())
+
+	levels.SetLevel("note", zapcore.DebugLevel)
+	noteLogger.Debug("note debug")
+	sqlLogger.With(nil, "id", "1").Debug("sql debug")
+	l.Debug("root debug")
+	assert.Equal(t, 2, entries.Len())
+	assert.Equal(t, "note.sql", entries.All()[1].LoggerName)
+
+	levels.ResetLevel("note")
+	levels.SetLevel("", zapcore.ErrorLevel)
+	noteLogger.Warn("note warn")
+	l.Warn("root warn")
+	assert.Equal(t, 2, entries.Len())
+}
+
+func TestLevels_ToggleDebug(t *testing.T) {
+	levels := NewLevels(zapcore.WarnLevel)
+	assert.Equal(t, zapcore.DebugLevel, levels.ToggleDebug())
+	assert.Equal(t, zapcore.WarnLevel, levels.ToggleDebug())
+
+	// a level set between toggles is the one toggled back to
+	levels.ToggleDebug()
+	levels.SetLevel("", zapcore.ErrorLevel)
+	assert.Equal(t, zapcore.DebugLevel, levels.ToggleDebug())
+	assert.Equal(t, zapcore.ErrorLevel, levels.ToggleDebug())
+	levels.SetLevel("", zapcore.DebugLevel)
+	assert.Equal(t, zapcore.ErrorLevel, levels.ToggleDebug())
+}
+
+func TestLevels_ServeHTTP(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+
+	res := httptest.NewRecorder()
+	levels.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"logger":"site","level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"level":"info","loggers":{"site":"debug"}}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	levels.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"error"}`)))
+	assert.Equal(t, zapcore.ErrorLevel, levels.Level(""))
+
+	res = httptest.NewRecorder()
+	levels.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"logger":"site"}`)))
+	assert.JSONEq(t, `{"level":"error"}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	levels.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"loud"}`)))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
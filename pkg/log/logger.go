@@ -3,35 +3,86 @@ package log
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
-	"net/http"
 )
 
 // Logger is a logger that supports log levels, context and structured logging.
 type Logger interface {
 	// With returns a logger based off the root logger and decorates it with the given context and arguments.
 	With(ctx context.Context, args ...interface{}) Logger
+	// Named returns a child logger whose name is appended to the logger name. If the logger was created
+	// by NewWithConfig, the child's level can be configured independently of its parent.
+	Named(name string) Logger
 
 	// Debug uses fmt.Sprint to construct and log a message at DEBUG level
 	Debug(args ...interface{})
 	// Info uses fmt.Sprint to construct and log a message at INFO level
 	Info(args ...interface{})
+	// Warn uses fmt.Sprint to construct and log a message at WARN level
+	Warn(args ...interface{})
 	// Error uses fmt.Sprint to construct and log a message at ERROR level
 	Error(args ...interface{})
+	// Fatal uses fmt.Sprint to construct and log a message at FATAL level, then calls os.Exit(1)
+	Fatal(args ...interface{})
 
 	// Debugf uses fmt.Sprintf to construct and log a message at DEBUG level
 	Debugf(format string, args ...interface{})
 	// Infof uses fmt.Sprintf to construct and log a message at INFO level
 	Infof(format string, args ...interface{})
+	// Warnf uses fmt.Sprintf to construct and log a message at WARN level
+	Warnf(format string, args ...interface{})
 	// Errorf uses fmt.Sprintf to construct and log a message at ERROR level
 	Errorf(format string, args ...interface{})
+	// Fatalf uses fmt.Sprintf to construct and log a message at FATAL level, then calls os.Exit(1)
+	Fatalf(format string, args ...interface{})
+
+	// Sync flushes any buffered log entries.
+	Sync() error
+	// Close flushes any buffered log entries and closes the log outputs, which loggers derived from the
+	// same root share. Applications should call it before exiting and stop logging afterwards.
+	Close() error
 }
 
 type logger struct {
 	*zap.SugaredLogger
+	// levels is nil unless the logger was created by NewWithConfig.
+	levels *Levels
+	// close flushes and closes the outputs opened by NewWithConfig
+	close func() error
+}
+
+// Config represents the logger configuration.
+type Config struct {
+	// the minimum enabled level: debug, info, warn, error or fatal. Defaults to info
+	Level string `yaml:"level"`
+	// the log encoding: json or console. Defaults to json
+	Encoding string `yaml:"encoding"`
+	// levels of named loggers that differ from the root level, e.g. {"note": "debug"}
+	Packages map[string]string `yaml:"packages"`
+	// sampling of repeated messages. Defaults to the first 100 then every 100th each second
+	Sampling *SamplingConfig `yaml:"sampling"`
+	// masking of secrets and personal data in log output
+	Redact RedactConfig `yaml:"redact"`
+	// the log destinations; every entry is written to all of them. Defaults to stderr
+	Outputs []OutputConfig `yaml:"outputs"`
+	// the number of recent entries kept in memory for the admin API. 0 disables it
+	RingSize int `yaml:"ring_size"`
+}
+
+// SamplingConfig limits the number of identical messages logged per second. Messages are identical
+// when they have the same level and text. Setting Initial to zero disables sampling.
+type SamplingConfig struct {
+	// the number of identical messages logged each second before sampling starts
+	Initial int `yaml:"initial"`
+	// after Initial messages, only every Thereafter-th message is logged for the rest of the second
+	Thereafter int `yaml:"thereafter"`
 }
 
 type contextKey int
@@ -41,20 +92,116 @@ const (
 	correlationIDKey
 )
 
-// New creates a new logger using the default configuration.
+// Option customizes a logger created by NewWithConfig or NewForTest.
+type Option func(*options)
+
+type options struct {
+	redactor *Redactor
+	ring     *Ring
+}
+
+// WithRedactor sets the redactor applied to log output. It lets the caller register secrets, such as
+// those loaded from the application configuration, after the logger has been created.
+func WithRedactor(r *Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
+// New creates a new logger using the default configuration. Its output is redacted by a default Redactor.
 func New() Logger {
-	l, _ := zap.NewProduction()
+	redactor := NewRedactor()
+	l, _ := zap.NewProduction(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return NewRedactingCore(c, redactor)
+	}))
 	return NewWithZap(l)
 }
 
+// NewWithConfig creates a new logger using the given configuration. The returned levels control the
+// root logger and any loggers derived from it via Named, and can be changed while the logger is in use.
+//
+// Log output is always redacted, using the redactor given by WithRedactor or else one created from cfg.Redact.
+func NewWithConfig(cfg Config, opts ...Option) (Logger, *Levels, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.redactor == nil {
+		o.redactor = NewRedactor(cfg.Redact.Fields...)
+	}
+
+	root := zapcore.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		if root, err = zapcore.ParseLevel(cfg.Level); err != nil {
+			return nil, nil, err
+		}
+	}
+	levels := NewLevels(root)
+	for name, text := range cfg.Packages {
+		level, err := zapcore.ParseLevel(text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("logger %q: %w", name, err)
+		}
+		levels.SetLevel(name, level)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	var encoder zapcore.Encoder
+	switch cfg.Encoding {
+	case "", "json":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, nil, fmt.Errorf("unknown log encoding %q", cfg.Encoding)
+	}
+
+	// the core accepts every level; filtering is done by levelCore so that each named logger can have its own level
+	core, closeOutputs, err := newOutputCore(encoder, cfg.Outputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.ring != nil {
+		core = zapcore.NewTee(core, &ringCore{ring: o.ring})
+	}
+	core = NewRedactingCore(core, o.redactor)
+	sampling := cfg.Sampling
+	if sampling == nil {
+		sampling = &SamplingConfig{Initial: 100, Thereafter: 100}
+	}
+	if sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, sampling.Initial, sampling.Thereafter)
+	}
+
+	l := zap.New(&levelCore{Core: core, enabler: levels.enabler("")}, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	return &logger{SugaredLogger: l.Sugar(), levels: levels, close: closeOutputs}, levels, nil
+}
+
 // NewWithZap creates a new logger using the preconfigured zap logger.
 func NewWithZap(l *zap.Logger) Logger {
-	return &logger{l.Sugar()}
+	return &logger{SugaredLogger: l.Sugar()}
 }
 
 // NewForTest returns a new logger and the corresponding observed logs which can be used in unit tests to verify log entries.
-func NewForTest() (Logger, *observer.ObservedLogs) {
+//
+// The observed entries are redacted only if a redactor is given with WithRedactor, and are also added
+// to the ring given by WithRing.
+func NewForTest(opts ...Option) (Logger, *observer.ObservedLogs) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var core zapcore.Core
 	core, recorded := observer.New(zapcore.InfoLevel)
+	if o.ring != nil {
+		core = zapcore.NewTee(core, &ringCore{ring: o.ring})
+	}
+	if o.redactor != nil {
+		core = NewRedactingCore(core, o.redactor)
+	}
 	return NewWithZap(zap.New(core)), recorded
 }
 
@@ -75,11 +222,39 @@ func (l *logger) With(ctx context.Context, args ...interface{}) Logger {
 		}
 	}
 	if len(args) > 0 {
-		return &logger{l.SugaredLogger.With(args...)}
+		return &logger{SugaredLogger: l.SugaredLogger.With(args...), levels: l.levels, close: l.close}
 	}
 	return l
 }
 
+// Named returns a child logger whose name is appended to the logger name.
+//
+// The child's level follows the level configured for its full name (see Levels), so that individual
+// packages can log more or less verbosely than the rest of the application.
+func (l *logger) Named(name string) Logger {
+	zl := l.SugaredLogger.Desugar().Named(name)
+	if l.levels != nil {
+		enabler := l.levels.enabler(zl.Name())
+		zl = zl.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			if lc, ok := c.(*levelCore); ok {
+				c = lc.Core
+			}
+			return &levelCore{Core: c, enabler: enabler}
+		}))
+	}
+	return &logger{SugaredLogger: zl.Sugar(), levels: l.levels, close: l.close}
+}
+
+// Close flushes the buffered log entries and closes the outputs opened by NewWithConfig.
+func (l *logger) Close() error {
+	// the error of syncing stderr, which cannot be synced on some platforms, is not a failure to close
+	l.SugaredLogger.Sync()
+	if l.close == nil {
+		return nil
+	}
+	return l.close()
+}
+
 // WithRequest returns a context which knows the request ID and correlation ID in the given request.
 func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	id := getRequestID(req)
@@ -93,6 +268,12 @@ func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	return ctx
 }
 
+// RequestID returns the request ID recorded in the context by WithRequest, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
 // getCorrelationID extracts the correlation ID from the HTTP request
 func getCorrelationID(req *http.Request) string {
 	return req.Header.Get("X-Correlation-ID")
@@ -26,6 +26,8 @@ func TestWithRequest(t *testing.T) {
 	ctx := WithRequest(context.Background(), req)
 	assert.Equal(t, "abc", ctx.Value(requestIDKey).(string))
 	assert.Equal(t, "123", ctx.Value(correlationIDKey).(string))
+	assert.Equal(t, "abc", RequestID(ctx))
+	assert.Equal(t, "", RequestID(context.Background()))
 
 	req = buildRequest("", "123")
 	ctx = WithRequest(context.Background(), req)
@@ -0,0 +1,178 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultBufferSizeKB     = 256
+	defaultFlushIntervalSec = 30
+	defaultDialTimeout      = 5 * time.Second
+)
+
+// OutputConfig represents a log destination.
+type OutputConfig struct {
+	// the output type: stderr, stdout, file, syslog, udp or tcp. Defaults to stderr
+	Type string `yaml:"type"`
+	// the log file path for file outputs
+	Path string `yaml:"path"`
+	// the collector address (host:port) for udp and tcp outputs, or the syslog daemon address. Syslog defaults to the local daemon
+	Address string `yaml:"address"`
+	// the network used to reach a remote syslog daemon: udp or tcp
+	Network string `yaml:"network"`
+	// the syslog tag. Defaults to the program name
+	Tag string `yaml:"tag"`
+	// the size in megabytes at which a log file is rotated. 0 disables rotation
+	MaxSize int `yaml:"max_size"`
+	// the number of days to keep rotated log files. 0 keeps them regardless of age
+	MaxAge int `yaml:"max_age"`
+	// the number of rotated log files to keep. 0 keeps all of them
+	MaxBackups int `yaml:"max_backups"`
+	// buffer writes in memory and flush them in the background
+	Async bool `yaml:"async"`
+	// the size of the async buffer in kilobytes. Defaults to 256KB
+	BufferSize int `yaml:"buffer_size"`
+	// the maximum time in seconds that buffered entries wait before being flushed. Defaults to 30 seconds
+	FlushInterval int `yaml:"flush_interval"`
+}
+
+// openOutput opens the write syncer for the given output configuration, returning the function that
+// flushes and closes it.
+func openOutput(cfg OutputConfig) (zapcore.WriteSyncer, func() error, error) {
+	var (
+		ws     zapcore.WriteSyncer
+		closer = func() error { return nil }
+	)
+	switch cfg.Type {
+	case "", "stderr":
+		ws = zapcore.Lock(os.Stderr)
+	case "stdout":
+		ws = zapcore.Lock(os.Stdout)
+	case "file":
+		if cfg.Path == "" {
+			return nil, nil, fmt.Errorf("file log output requires a path")
+		}
+		f, err := NewRotatingFile(cfg.Path, cfg.MaxSize, cfg.MaxAge, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		ws, closer = f, f.Close
+	case "syslog":
+		w, err := newSyslogWriter(cfg.Network, cfg.Address, cfg.Tag)
+		if err != nil {
+			return nil, nil, err
+		}
+		ws, closer = zapcore.AddSync(w), w.Close
+	case "udp", "tcp":
+		if cfg.Address == "" {
+			return nil, nil, fmt.Errorf("%s log output requires an address", cfg.Type)
+		}
+		w := &netWriter{network: cfg.Type, address: cfg.Address}
+		ws, closer = w, w.Close
+	default:
+		return nil, nil, fmt.Errorf("unknown log output type %q", cfg.Type)
+	}
+
+	if cfg.Async {
+		size, interval := cfg.BufferSize, cfg.FlushInterval
+		if size <= 0 {
+			size = defaultBufferSizeKB
+		}
+		if interval <= 0 {
+			interval = defaultFlushIntervalSec
+		}
+		buffered := &zapcore.BufferedWriteSyncer{
+			WS:            ws,
+			Size:          size * 1024,
+			FlushInterval: time.Duration(interval) * time.Second,
+		}
+		closeWriter := closer
+		// Stop writes out the buffered entries before the output is closed
+		ws, closer = buffered, func() error {
+			return errors.Join(buffered.Stop(), closeWriter())
+		}
+	}
+	return ws, closer, nil
+}
+
+// newOutputCore creates a core that writes every entry to each of the configured outputs, returning the
+// function that flushes and closes them. The outputs already opened are closed if one fails to open.
+func newOutputCore(encoder zapcore.Encoder, outputs []OutputConfig) (zapcore.Core, func() error, error) {
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Type: "stderr"}}
+	}
+	cores := make([]zapcore.Core, 0, len(outputs))
+	closers := make([]func() error, 0, len(outputs))
+	closeAll := func() error {
+		var errs []error
+		for _, closer := range closers {
+			errs = append(errs, closer())
+		}
+		return errors.Join(errs...)
+	}
+	for _, output := range outputs {
+		ws, closer, err := openOutput(output)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		cores = append(cores, zapcore.NewCore(encoder.Clone(), ws, zapcore.DebugLevel))
+		closers = append(closers, closer)
+	}
+	var once sync.Once
+	var err error
+	return zapcore.NewTee(cores...), func() error {
+		once.Do(func() { err = closeAll() })
+		return err
+	}, nil
+}
+
+// netWriter sends log entries to a UDP or TCP collector, reconnecting after a failed write.
+type netWriter struct {
+	network string
+	address string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.network, w.address, defaultDialTimeout)
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+	n, err := w.conn.Write(p)
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	return n, err
+}
+
+func (w *netWriter) Sync() error {
+	return nil
+}
+
+// Close closes the connection to the collector. A later write reconnects.
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	f, err := NewRotatingFile(path, 0, 0, 2)
+	require.NoError(t, err)
+	defer f.Close()
+	f.maxSize = 10
+
+	for i := 0; i < 4; i++ {
+		_, err := f.Write([]byte("0123456789"))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond) // backups are named after the time of rotation
+	}
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 2)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+}
+
+func TestRotatingFile_SameMillisecond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	f, err := NewRotatingFile(path, 0, 0, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := f.Write([]byte{byte('a' + i)})
+		require.NoError(t, err)
+		require.NoError(t, f.Rotate())
+	}
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3, "no backup overwrites another")
+	data, err := os.ReadFile(backups[0].path)
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(data), "the newest backup comes first")
+}
+
+func TestNewWithConfig_Outputs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "server.log")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	logger, _, err := NewWithConfig(Config{Outputs: []OutputConfig{
+		{Type: "file", Path: path, Async: true},
+		{Type: "tcp", Address: ln.Addr().String()},
+	}})
+	require.NoError(t, err)
+	logger.Info("hello sinks")
+
+	select {
+	case line := <-received:
+		assert.Contains(t, line, "hello sinks")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no log entry received by the tcp collector")
+	}
+
+	// the file output is buffered until Sync
+	data, _ := os.ReadFile(path)
+	assert.Empty(t, data)
+	require.NoError(t, logger.Sync())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(data), "hello sinks"))
+
+	// closing writes out what is still buffered
+	logger.Info("goodbye sinks")
+	require.NoError(t, logger.Close())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "goodbye sinks")
+}
+
+func TestNewWithConfig_InvalidOutput(t *testing.T) {
+	_, _, err := NewWithConfig(Config{Outputs: []OutputConfig{{Type: "pigeon"}}})
+	assert.Error(t, err)
+	_, _, err = NewWithConfig(Config{Outputs: []OutputConfig{{Type: "file"}}})
+	assert.Error(t, err)
+}
+
+func TestNewOutputCore_ClosesOpenedOutputs(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("the open files cannot be listed:", err)
+	}
+	path := filepath.Join(t.TempDir(), "server.log")
+	_, _, err = newOutputCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), []OutputConfig{
+		{Type: "file", Path: path},
+		{Type: "pigeon"},
+	})
+	require.Error(t, err)
+	fds, err = os.ReadDir("/proc/self/fd")
+	require.NoError(t, err)
+	for _, fd := range fds {
+		target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		assert.NotEqual(t, path, target, "the file output opened before the failure is closed")
+	}
+}
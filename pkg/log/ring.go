@@ -0,0 +1,276 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ringSubscriberBuffer is the number of entries queued for a subscriber before new entries are dropped.
+	ringSubscriberBuffer = 64
+	// ringHeartbeatInterval is how often a comment is sent to idle log streams to keep connections open.
+	ringHeartbeatInterval = 15 * time.Second
+)
+
+// Entry is a log entry kept by a Ring.
+type Entry struct {
+	Seq     uint64                 `json:"seq"`
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Caller  string                 `json:"caller,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// RingFilter selects entries from a Ring. Zero values match every entry.
+type RingFilter struct {
+	// the minimum level of the entries
+	Level zapcore.Level
+	// the request ID recorded in the entries' request_id field
+	RequestID string
+	// the earliest time of the entries
+	Since time.Time
+	// only entries with a greater sequence number
+	After uint64
+}
+
+// Match reports whether the entry is selected by the filter.
+func (f RingFilter) Match(e Entry) bool {
+	if level, err := zapcore.ParseLevel(e.Level); err == nil && level < f.Level {
+		return false
+	}
+	if f.RequestID != "" && e.Fields["request_id"] != f.RequestID {
+		return false
+	}
+	return e.Seq > f.After && !e.Time.Before(f.Since)
+}
+
+// Ring keeps the most recent log entries in memory so that they can be queried or streamed over HTTP.
+// Add it to a logger with WithRing.
+type Ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	seq     uint64
+	subs    map[chan Entry]struct{}
+}
+
+// NewRing creates a ring that keeps the last size entries.
+func NewRing(size int) *Ring {
+	if size < 1 {
+		size = 1
+	}
+	return &Ring{
+		entries: make([]Entry, size),
+		subs:    make(map[chan Entry]struct{}),
+	}
+}
+
+// WithRing adds the ring to the logger outputs. Entries are added after redaction.
+func WithRing(r *Ring) Option {
+	return func(o *options) {
+		o.ring = r
+	}
+}
+
+func (r *Ring) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	e.Seq = r.seq
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	for ch := range r.subs {
+		select {
+		case ch <- e:
+		default: // never block logging on a slow subscriber
+		}
+	}
+}
+
+// Entries returns the entries selected by the filter, oldest first.
+func (r *Ring) Entries(f RingFilter) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entriesLocked(f)
+}
+
+func (r *Ring) entriesLocked(f RingFilter) []Entry {
+	var ordered []Entry
+	if r.full {
+		ordered = append(ordered, r.entries[r.next:]...)
+	}
+	ordered = append(ordered, r.entries[:r.next]...)
+
+	selected := make([]Entry, 0, len(ordered))
+	for _, e := range ordered {
+		if f.Match(e) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// Subscribe returns the entries selected by the filter together with a channel that receives every entry
+// added afterwards. Entries are dropped if the subscriber falls behind. Call cancel to unsubscribe.
+func (r *Ring) Subscribe(f RingFilter) (backlog []Entry, entries <-chan Entry, cancel func()) {
+	ch := make(chan Entry, ringSubscriberBuffer)
+	r.mu.Lock()
+	backlog = r.entriesLocked(f)
+	r.subs[ch] = struct{}{}
+	r.mu.Unlock()
+	return backlog, ch, func() {
+		r.mu.Lock()
+		delete(r.subs, ch)
+		r.mu.Unlock()
+	}
+}
+
+// parseRingFilter reads a filter from the level, request_id and since query parameters.
+// The since parameter is either an RFC 3339 time or a duration relative to now, such as 5m.
+func parseRingFilter(req *http.Request) (RingFilter, error) {
+	var f RingFilter
+	q := req.URL.Query()
+	if level := q.Get("level"); level != "" {
+		l, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return f, err
+		}
+		f.Level = l
+	}
+	f.RequestID = q.Get("request_id")
+	if since := q.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			f.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			f.Since = t
+		} else {
+			return f, fmt.Errorf("invalid since %q: expecting an RFC 3339 time or a duration", since)
+		}
+	}
+	return f, nil
+}
+
+// ServeHTTP returns the entries selected by the level, request_id and since query parameters as JSON.
+func (r *Ring) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f, err := parseRingFilter(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.Entries(f))
+}
+
+// ServeStream streams the entries selected by the level, request_id and since query parameters as
+// Server-Sent Events, starting with the matching entries already in the ring. A reconnecting client
+// resumes after the entry given by its Last-Event-ID header.
+func (r *Ring) ServeStream(w http.ResponseWriter, req *http.Request) {
+	f, err := parseRingFilter(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if id := req.Header.Get("Last-Event-ID"); id != "" {
+		if f.After, err = strconv.ParseUint(id, 10, 64); err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	backlog, entries, cancel := r.Subscribe(f)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e Entry) {
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", e.Seq, data)
+	}
+	for _, e := range backlog {
+		send(e)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(ringHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case e := <-entries:
+			if f.Match(e) {
+				send(e)
+				flusher.Flush()
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// ringCore is a zap core that adds every entry it writes to a Ring.
+type ringCore struct {
+	ring   *Ring
+	fields []zapcore.Field
+}
+
+func (c *ringCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	return &ringCore{ring: c.ring, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	e := Entry{
+		Time:    ent.Time,
+		Level:   ent.Level.String(),
+		Logger:  ent.LoggerName,
+		Message: ent.Message,
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	if len(enc.Fields) > 0 {
+		e.Fields = enc.Fields
+	}
+	c.ring.add(e)
+	return nil
+}
+
+func (c *ringCore) Sync() error {
+	return nil
+}
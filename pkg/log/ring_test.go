@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRing_Entries(t *testing.T) {
+	ring := NewRing(3)
+	logger, _ := NewForTest(WithRing(ring), WithRedactor(NewRedactor()))
+
+	ctx := WithRequest(context.Background(), buildRequest("req-1", ""))
+	logger.Info("one")
+	logger.With(ctx).Error("two")
+	logger.Info("three")
+	logger.With(ctx).Warn("mail jane@example.com")
+
+	all := ring.Entries(RingFilter{})
+	require.Len(t, all, 3)
+	assert.Equal(t, "two", all[0].Message)
+	assert.Equal(t, uint64(4), all[2].Seq)
+	assert.Equal(t, "mail ***@***", all[2].Message)
+
+	byRequest := ring.Entries(RingFilter{RequestID: "req-1"})
+	assert.Len(t, byRequest, 2)
+	assert.Len(t, ring.Entries(RingFilter{Level: zapcore.ErrorLevel}), 1)
+	assert.Len(t, ring.Entries(RingFilter{Since: time.Now().Add(time.Minute)}), 0)
+}
+
+func TestRing_ServeHTTP(t *testing.T) {
+	ring := NewRing(10)
+	logger, _ := NewForTest(WithRing(ring))
+	logger.Info("info")
+	logger.Error("error")
+
+	res := httptest.NewRecorder()
+	ring.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin/logs?level=error&since=1m", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var entries []Entry
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "error", entries[0].Message)
+
+	res = httptest.NewRecorder()
+	ring.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin/logs?since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+func TestRing_ServeStream(t *testing.T) {
+	ring := NewRing(10)
+	logger, _ := NewForTest(WithRing(ring))
+	logger.Info("before")
+
+	server := httptest.NewServer(http.HandlerFunc(ring.ServeStream))
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	logger.Info("after")
+	reader := bufio.NewReader(res.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	assert.Equal(t, "id: 2", lines[0])
+	assert.Equal(t, "event: log", lines[1])
+	assert.Contains(t, lines[2], `"message":"after"`)
+}
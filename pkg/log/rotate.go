@@ -0,0 +1,195 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp inserted into the names of rotated log files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an io.Writer that writes to a log file and rotates it once it reaches a maximum size.
+//
+// Rotated files are renamed to <name>-<timestamp><ext> in the same directory, or <name>-<timestamp>-<n><ext>
+// when the file is rotated more than once within the same millisecond. Backups beyond the maximum
+// count, or older than the maximum age, are removed after each rotation.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens (or creates) the log file at path for appending. A zero maxSizeMB disables
+// rotation, while a zero maxAgeDays or maxBackups keeps rotated files regardless of age or count.
+func NewRotatingFile(path string, maxSizeMB, maxAgeDays, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		maxBackups: maxBackups,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write appends p to the log file, rotating the file first if p would take it over the maximum size.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync commits the current contents of the log file to stable storage.
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the log file. Subsequent writes fail.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Rotate closes the current log file, renames it to a backup and starts a new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	ext := filepath.Ext(f.path)
+	stamp := fmt.Sprintf("%s-%s", strings.TrimSuffix(f.path, ext), time.Now().Format(backupTimeFormat))
+	backup := stamp + ext
+	// a counter keeps backups made within the same millisecond apart
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			break
+		}
+		backup = fmt.Sprintf("%s-%d%s", stamp, n, ext)
+	}
+	if err := os.Rename(f.path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	return f.prune()
+}
+
+// prune removes the backups that exceed the maximum count or age.
+func (f *RotatingFile) prune() error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	for i, b := range backups {
+		expired := f.maxAge > 0 && time.Since(b.time) > f.maxAge
+		if expired || (f.maxBackups > 0 && i >= f.maxBackups) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+	// orders the backups made within the same millisecond
+	n int
+}
+
+// backups returns the rotated files of this log, newest first.
+func (f *RotatingFile) backups() ([]backupFile, error) {
+	ext := filepath.Ext(f.path)
+	prefix := filepath.Base(strings.TrimSuffix(f.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp, n := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext), 0
+		if len(stamp) > len(backupTimeFormat) && stamp[len(backupTimeFormat)] == '-' {
+			if n, err = strconv.Atoi(stamp[len(backupTimeFormat)+1:]); err != nil {
+				continue
+			}
+			stamp = stamp[:len(backupTimeFormat)]
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(filepath.Dir(f.path), name), time: t, n: n})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].n > backups[j].n
+	})
+	return backups, nil
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ToggleDebugOnSignal toggles debug logging (see Levels.ToggleDebug) every time the process receives
+// SIGUSR1, logging the new root level with the given logger. Call the returned function to stop.
+func ToggleDebugOnSignal(levels *Levels, logger Logger) (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGUSR1)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				logger.Warnf("received SIGUSR1, log level is now %s", levels.ToggleDebug())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
@@ -0,0 +1,6 @@
+package log
+
+// ToggleDebugOnSignal is a no-op on Windows, which has no SIGUSR1. Use the admin endpoint instead.
+func ToggleDebugOnSignal(levels *Levels, logger Logger) (stop func()) {
+	return func() {}
+}
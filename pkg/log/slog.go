@@ -0,0 +1,213 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelFatal is the slog level used for Fatal messages written through a logger returned by FromSlog.
+const LevelFatal = slog.LevelError + 4
+
+// slogHandler is a slog.Handler that writes records to a Logger.
+type slogHandler struct {
+	logger Logger
+	// prefix is prepended to attribute keys, built from the groups opened with WithGroup.
+	prefix string
+}
+
+// NewSlogHandler returns a slog.Handler that writes records to the given logger, so that code using
+// log/slog shares the logger's outputs, level and fields.
+//
+// Records logged with a context (e.g. slog.InfoContext) carry the request ID and correlation ID
+// recorded in the context by WithRequest. Attributes in groups are flattened into dot-separated keys.
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if l, ok := h.logger.(*logger); ok {
+		return l.Desugar().Core().Enabled(zapLevel(level))
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, rec slog.Record) error {
+	args := make([]interface{}, 0, 2*rec.NumAttrs())
+	rec.Attrs(func(a slog.Attr) bool {
+		args = appendAttr(args, h.prefix, a)
+		return true
+	})
+	l := h.logger.With(ctx, args...)
+
+	// log through zap directly when possible to report the caller of the slog function
+	if zl, ok := l.(*logger); ok {
+		if ce := zl.Desugar().Check(zapLevel(rec.Level), rec.Message); ce != nil {
+			if !rec.Time.IsZero() {
+				ce.Time = rec.Time
+			}
+			if rec.PC != 0 {
+				frame, _ := runtime.CallersFrames([]uintptr{rec.PC}).Next()
+				ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+			}
+			ce.Write()
+		}
+		return nil
+	}
+
+	switch zapLevel(rec.Level) {
+	case zapcore.DebugLevel:
+		l.Debug(rec.Message)
+	case zapcore.InfoLevel:
+		l.Info(rec.Message)
+	case zapcore.WarnLevel:
+		l.Warn(rec.Message)
+	default:
+		l.Error(rec.Message)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	args := make([]interface{}, 0, 2*len(attrs))
+	for _, a := range attrs {
+		args = appendAttr(args, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger.With(nil, args...), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// appendAttr appends the attribute as key/value pairs, flattening groups into dot-separated keys.
+func appendAttr(args []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return args
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			args = appendAttr(args, prefix, ga)
+		}
+		return args
+	}
+	return append(args, prefix+a.Key, a.Value.Any())
+}
+
+// zapLevel converts a slog level to the closest zap level. Levels above error map to error,
+// since slog callers don't expect the program to exit.
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+// slogLogger adapts a *slog.Logger to the Logger interface.
+type slogLogger struct {
+	l    *slog.Logger
+	name string
+}
+
+// FromSlog returns a Logger that writes to the given slog logger, so that a *slog.Logger can be used
+// wherever a Logger is expected. Fatal messages are logged at LevelFatal before the program exits.
+func FromSlog(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+// With returns a logger that adds the given arguments, and the request ID and correlation ID recorded
+// in the context by WithRequest, to every message.
+func (s *slogLogger) With(ctx context.Context, args ...interface{}) Logger {
+	if ctx != nil {
+		if id, ok := ctx.Value(requestIDKey).(string); ok {
+			args = append(args, "request_id", id)
+		}
+		if id, ok := ctx.Value(correlationIDKey).(string); ok {
+			args = append(args, "correlation_id", id)
+		}
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return &slogLogger{l: s.l.With(args...), name: s.name}
+}
+
+// Named returns a logger that records the dot-separated logger name in the "logger" attribute.
+func (s *slogLogger) Named(name string) Logger {
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+	return &slogLogger{l: s.l, name: name}
+}
+
+// log writes a record whose source is the caller of the Logger method.
+func (s *slogLogger) log(level slog.Level, msg string) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip runtime.Callers, log and the Logger method
+	rec := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if s.name != "" {
+		rec.AddAttrs(slog.String("logger", s.name))
+	}
+	s.l.Handler().Handle(ctx, rec)
+}
+
+func (s *slogLogger) Debug(args ...interface{}) { s.log(slog.LevelDebug, fmt.Sprint(args...)) }
+func (s *slogLogger) Info(args ...interface{})  { s.log(slog.LevelInfo, fmt.Sprint(args...)) }
+func (s *slogLogger) Warn(args ...interface{})  { s.log(slog.LevelWarn, fmt.Sprint(args...)) }
+func (s *slogLogger) Error(args ...interface{}) { s.log(slog.LevelError, fmt.Sprint(args...)) }
+
+func (s *slogLogger) Fatal(args ...interface{}) {
+	s.log(LevelFatal, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (s *slogLogger) Infof(format string, args ...interface{}) {
+	s.log(slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (s *slogLogger) Warnf(format string, args ...interface{}) {
+	s.log(slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (s *slogLogger) Errorf(format string, args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+func (s *slogLogger) Fatalf(format string, args ...interface{}) {
+	s.log(LevelFatal, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// Sync does nothing: slog handlers write records synchronously.
+func (s *slogLogger) Sync() error {
+	return nil
+}
+
+func (s *slogLogger) Close() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewSlogHandler(t *testing.T) {
+	logger, entries := NewForTest()
+	sl := slog.New(NewSlogHandler(logger.With(nil, "version", "1.0.0")))
+	ctx := WithRequest(context.Background(), buildRequest("abc", "123"))
+
+	sl.Debug("dropped")
+	sl.With("component", "helper").WithGroup("http").InfoContext(ctx, "handled", "status", 200, slog.Group("timing", "ms", 12))
+	sl.Warn("careful")
+	sl.Log(ctx, slog.LevelError+8, "very bad")
+
+	all := entries.All()
+	require.Len(t, all, 3)
+	assert.Equal(t, "handled", all[0].Message)
+	assert.Equal(t, zapcore.InfoLevel, all[0].Level)
+	assert.True(t, all[0].Caller.Defined)
+	assert.Contains(t, all[0].Caller.File, "slog_test.go")
+	fields := all[0].ContextMap()
+	assert.Equal(t, "1.0.0", fields["version"])
+	assert.Equal(t, "helper", fields["component"])
+	assert.Equal(t, int64(200), fields["http.status"])
+	assert.Equal(t, int64(12), fields["http.timing.ms"])
+	assert.Equal(t, "abc", fields["request_id"])
+	assert.Equal(t, "123", fields["correlation_id"])
+	assert.Equal(t, zapcore.WarnLevel, all[1].Level)
+	assert.Equal(t, zapcore.ErrorLevel, all[2].Level)
+}
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := FromSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true})))
+	ctx := WithRequest(context.Background(), buildRequest("abc", ""))
+
+	l.Debug("dropped")
+	l.Named("note").Named("sql").With(ctx, "id", 7).Infof("found %d notes", 2)
+
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "INFO", rec["level"])
+	assert.Equal(t, "found 2 notes", rec["msg"])
+	assert.Equal(t, "note.sql", rec["logger"])
+	assert.Equal(t, "abc", rec["request_id"])
+	assert.Equal(t, float64(7), rec["id"])
+	assert.Contains(t, rec["source"].(map[string]interface{})["file"], "slog_test.go")
+	assert.NoError(t, l.Sync())
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package log
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// newSyslogWriter connects to the syslog daemon at the given address, or to the local daemon if address is empty.
+func newSyslogWriter(network, address, tag string) (io.WriteCloser, error) {
+	if address != "" && network == "" {
+		network = "udp"
+	}
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+}
@@ -0,0 +1,11 @@
+package log
+
+import (
+	"errors"
+	"io"
+)
+
+// newSyslogWriter always fails because syslog is not available on Windows.
+func newSyslogWriter(network, address, tag string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog log output is not supported on windows")
+}